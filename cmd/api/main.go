@@ -2,12 +2,17 @@ package main
 
 import (
 	"banksystem/internal/config"
+	"banksystem/internal/crypto"
 	"banksystem/internal/handlers"
+	"banksystem/internal/iso8583"
+	"banksystem/internal/models"
 	"banksystem/internal/repositories"
 	"banksystem/internal/services"
+	"banksystem/internal/storage"
 	"database/sql"
 	"log"
 	"net/http"
+	"time"
 
 	_ "github.com/lib/pq"
 	"github.com/sirupsen/logrus"
@@ -34,20 +39,100 @@ func main() {
 	transactionRepo := repositories.NewTransactionRepository(db)
 	creditRepo := repositories.NewCreditRepository(db)
 	creditPaymentRepo := repositories.NewCreditPaymentRepository(db)
+	keyRateRepo := repositories.NewKeyRateRepository(db)
 	cardRepo := repositories.NewCardRepository(db)
+	budgetRepo := repositories.NewBudgetRepository(db)
+	attachmentRepo := repositories.NewAttachmentRepository(db)
+	auditRepo := repositories.NewAuditRepository(db)
+
+	// Инициализация хранилища вложений
+	attachmentStore, err := storage.NewLocalStore(cfg.AttachmentsDir)
+	if err != nil {
+		log.Fatalf("Failed to initialize attachment storage: %v", err)
+	}
+
+	// Загрузка PGP-ключей для шифрования данных карт
+	cardKeys, err := crypto.LoadKeyManager(cfg.KeySource())
+	if err != nil {
+		log.Fatalf("Failed to load PGP keys: %v", err)
+	}
+
+	cardHMACKeys, err := crypto.ParseHMACKeys(cfg.CardHMACKeys)
+	if err != nil {
+		log.Fatalf("Failed to load card HMAC keys: %v", err)
+	}
+
+	cardPANGenerator, err := crypto.ParseBINRanges(cfg.CardBINRanges)
+	if err != nil {
+		log.Fatalf("Failed to parse card BIN ranges: %v", err)
+	}
+
+	cardPANHasher, err := crypto.NewPANHasher(cfg.CardPANHashKey)
+	if err != nil {
+		log.Fatalf("Failed to initialize card number hasher: %v", err)
+	}
 
 	// Инициализация SMTP сервиса
-	smtpService := services.NewSMTPService(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword)
+	smtpService := services.NewSMTPService(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom)
 
 	// Инициализация JWT сервиса
 	jwtService := services.NewJWTService(cfg.JWTSecret)
 
 	// Инициализация сервисов
 	authService := services.NewAuthService(db, userRepo, jwtService)
-	accountService := services.NewAccountService(db, accountRepo, transactionRepo, userRepo, smtpService)
-	cardService := services.NewCardService(cardRepo, nil)
-	creditService := services.NewCreditService(db, creditRepo, accountRepo, creditPaymentRepo, transactionRepo)
-	creditPaymentService := services.NewCreditPaymentService(db, creditPaymentRepo, creditRepo, accountRepo)
+	budgetService := services.NewBudgetService(budgetRepo, accountRepo, userRepo, smtpService)
+	accountService := services.NewAccountService(db, accountRepo, transactionRepo, userRepo, smtpService, budgetService)
+	cardService := services.NewCardService(
+		db,
+		cardRepo,
+		accountRepo,
+		transactionRepo,
+		auditRepo,
+		userRepo,
+		budgetService,
+		accountService,
+		smtpService,
+		cardKeys,
+		cardHMACKeys,
+		cardPANGenerator,
+		cardPANHasher,
+		models.CardLimits{
+			PerPayment:         cfg.CardPaymentLimit,
+			Daily:              cfg.CardDailyLimit,
+			PINThreshold:       cfg.CardPINThreshold,
+			HoldTTL:            time.Duration(cfg.CardHoldDays) * 24 * time.Hour,
+			OTPThreshold:       cfg.CardOTPThreshold,
+			OTPTTL:             time.Duration(cfg.CardOTPMinutes) * time.Minute,
+			TransferFeePercent: cfg.CardTransferFee,
+			TransferFeeMin:     cfg.CardTransferFeeMin,
+		},
+		int(cfg.CardRenewalDays),
+	)
+	centralBankService := services.NewCentralBankService(
+		keyRateRepo,
+		cfg.CBRBaseURL,
+		time.Duration(cfg.KeyRateTTLMinutes)*time.Minute,
+		time.Duration(cfg.KeyRateMaxAgeHours)*time.Hour,
+	)
+	creditService := services.NewCreditService(
+		db,
+		creditRepo,
+		accountRepo,
+		creditPaymentRepo,
+		transactionRepo,
+		userRepo,
+		smtpService,
+		centralBankService,
+		models.CreditPolicy{
+			Margin:        cfg.CreditMargin,
+			MaxAmount:     cfg.CreditMaxAmount,
+			MaxTermMonths: int(cfg.CreditMaxTerm),
+		},
+	)
+	creditPaymentService := services.NewCreditPaymentService(db, creditPaymentRepo, creditRepo, accountRepo, transactionRepo, userRepo, smtpService)
+	analyticsService := services.NewAnalyticsService(creditRepo, creditPaymentRepo, transactionRepo)
+	transactionService := services.NewTransactionService(db, transactionRepo, attachmentRepo, accountRepo, attachmentStore, cfg.MaxAttachmentSize)
+	forecastService := services.NewForecastService(accountRepo, creditPaymentRepo, transactionRepo, cfg.SavingsInterestRate)
 
 	// Инициализация обработчиков
 	authHandler := handlers.NewAuthHandler(authService)
@@ -55,9 +140,14 @@ func main() {
 	cardHandler := handlers.NewCardHandler(cardService)
 	creditHandler := handlers.NewCreditHandler(creditService)
 	creditPaymentHandler := handlers.NewCreditPaymentHandler(creditPaymentService)
+	analyticsHandler := handlers.NewAnalyticsHandler(analyticsService, forecastService)
+	budgetHandler := handlers.NewBudgetHandler(budgetService)
+	transactionHandler := handlers.NewTransactionHandler(transactionService)
+	keyRateHandler := handlers.NewKeyRateHandler(centralBankService)
 
 	// Инициализация middleware
 	authMiddleware := handlers.NewAuthMiddleware(jwtService, logger)
+	adminMiddleware := handlers.NewAdminMiddleware(authService, logger)
 
 	// Настройка маршрутизации
 	mux := http.NewServeMux()
@@ -65,6 +155,7 @@ func main() {
 	// Публичные маршруты
 	mux.HandleFunc("/api/register", authHandler.Register)
 	mux.HandleFunc("/api/login", authHandler.Login)
+	mux.HandleFunc("/api/key-rate", keyRateHandler.GetKeyRateHistory)
 
 	// Защищенные маршруты
 	protectedMux := http.NewServeMux()
@@ -74,20 +165,82 @@ func main() {
 	protectedMux.HandleFunc("/api/accounts/deposit", accountHandler.Deposit)
 	protectedMux.HandleFunc("/api/accounts/withdraw", accountHandler.Withdraw)
 	protectedMux.HandleFunc("/api/accounts/transfer", accountHandler.Transfer)
+	protectedMux.HandleFunc("/api/accounts/predict", analyticsHandler.PredictBalance)
+
+	protectedMux.HandleFunc("/api/transactions/list", transactionHandler.GetHistory)
+	protectedMux.HandleFunc("/api/transactions/search", transactionHandler.Search)
+	protectedMux.HandleFunc("/api/transactions/note", transactionHandler.SetNote)
+	protectedMux.HandleFunc("/api/transactions/tags", transactionHandler.SetTags)
+	protectedMux.HandleFunc("/api/transactions/attachments/upload", transactionHandler.UploadAttachment)
+	protectedMux.HandleFunc("/api/transactions/attachments/list", transactionHandler.GetAttachments)
+	protectedMux.HandleFunc("/api/transactions/attachments/get", transactionHandler.DownloadAttachment)
+	protectedMux.HandleFunc("/api/transactions/attachments/delete", transactionHandler.DeleteAttachment)
 
 	protectedMux.HandleFunc("/api/cards/create", cardHandler.CreateCard)
 	protectedMux.HandleFunc("/api/cards/list", cardHandler.GetUserCards)
 	protectedMux.HandleFunc("/api/cards/get", cardHandler.GetCard)
-
-	protectedMux.HandleFunc("/api/credits/create", creditHandler.CreateCredit)
+	protectedMux.HandleFunc("/api/cards/reveal", cardHandler.RevealCard)
+	protectedMux.HandleFunc("/api/cards/block", cardHandler.BlockCard)
+	protectedMux.HandleFunc("/api/cards/unblock", cardHandler.UnblockCard)
+	protectedMux.HandleFunc("/api/cards/reissue", cardHandler.ReissueCard)
+	protectedMux.HandleFunc("/api/cards/pin", cardHandler.SetPIN)
+	protectedMux.HandleFunc("/api/cards/controls", cardHandler.GetControls)
+	protectedMux.HandleFunc("/api/cards/controls/update", cardHandler.UpdateControls)
+	protectedMux.HandleFunc("/api/cards/pay", cardHandler.Pay)
+	protectedMux.HandleFunc("/api/cards/merchant/pay", cardHandler.MerchantPay)
+	protectedMux.HandleFunc("/api/cards/pay/confirm", cardHandler.ConfirmPayment)
+	protectedMux.HandleFunc("/api/cards/transfer/recipient", cardHandler.FindRecipient)
+	protectedMux.HandleFunc("/api/cards/transfer", cardHandler.TransferToCard)
+	protectedMux.HandleFunc("/api/cards/mandates", cardHandler.GetMandates)
+	protectedMux.HandleFunc("/api/cards/mandates/create", cardHandler.CreateMandate)
+	protectedMux.HandleFunc("/api/cards/mandates/confirm", cardHandler.ConfirmMandate)
+	protectedMux.HandleFunc("/api/cards/mandates/charge", cardHandler.ChargeMandate)
+	protectedMux.HandleFunc("/api/cards/mandates/pause", cardHandler.PauseMandate)
+	protectedMux.HandleFunc("/api/cards/mandates/resume", cardHandler.ResumeMandate)
+	protectedMux.HandleFunc("/api/cards/mandates/revoke", cardHandler.RevokeMandate)
+
+	// Административные маршруты
+	protectedMux.Handle("/api/admin/cards/block", adminMiddleware.Middleware(http.HandlerFunc(cardHandler.AdminBlockCard)))
+	protectedMux.Handle("/api/admin/cards/unblock", adminMiddleware.Middleware(http.HandlerFunc(cardHandler.AdminUnblockCard)))
+	protectedMux.Handle("/api/admin/credits/applications", adminMiddleware.Middleware(http.HandlerFunc(creditHandler.AdminGetApplications)))
+	protectedMux.Handle("/api/admin/credits/applications/approve", adminMiddleware.Middleware(http.HandlerFunc(creditHandler.ApproveApplication)))
+	protectedMux.Handle("/api/admin/credits/applications/reject", adminMiddleware.Middleware(http.HandlerFunc(creditHandler.RejectApplication)))
+
+	protectedMux.HandleFunc("/api/credits/applications", creditHandler.GetApplications)
+	protectedMux.HandleFunc("/api/credits/applications/get", creditHandler.GetApplication)
+	protectedMux.HandleFunc("/api/credits/applications/create", creditHandler.SubmitApplication)
 	protectedMux.HandleFunc("/api/credits/list", creditHandler.GetUserCredits)
 	protectedMux.HandleFunc("/api/credits/get", creditHandler.GetCredit)
 	protectedMux.HandleFunc("/api/credits/schedule", creditHandler.GetPaymentSchedule)
+	protectedMux.HandleFunc("/api/credits/prepay", creditHandler.Prepay)
 
 	protectedMux.HandleFunc("/api/payments/create", creditPaymentHandler.CreatePayment)
 	protectedMux.HandleFunc("/api/payments/process", creditPaymentHandler.ProcessPayment)
 	protectedMux.HandleFunc("/api/payments/list", creditPaymentHandler.GetPaymentsByCreditID)
 	protectedMux.HandleFunc("/api/payments/pending", creditPaymentHandler.GetPendingPayments)
+	protectedMux.HandleFunc("/api/payments/penalties", creditPaymentHandler.GetPenalties)
+
+	protectedMux.HandleFunc("/api/analytics/credits", analyticsHandler.GetCreditLoad)
+
+	protectedMux.HandleFunc("/api/budgets/create", budgetHandler.CreateBudget)
+	protectedMux.HandleFunc("/api/budgets/list", budgetHandler.GetBudgets)
+	protectedMux.HandleFunc("/api/budgets/delete", budgetHandler.DeleteBudget)
+
+	// Запуск шедулера фоновых задач
+	scheduler := services.NewScheduler(creditPaymentService, budgetService, cardService, centralBankService)
+	scheduler.Start()
+	defer scheduler.Stop()
+
+	// Запуск приема сообщений ISO 8583 от терминалов
+	if cfg.ISO8583Addr != "" {
+		isoServer := iso8583.NewServer(cardService, logger)
+		go func() {
+			if err := isoServer.ListenAndServe(cfg.ISO8583Addr); err != nil {
+				logger.Fatalf("Failed to start ISO 8583 listener: %v", err)
+			}
+		}()
+		defer isoServer.Close()
+	}
 
 	// Применяем middleware к защищенным маршрутам
 	mux.Handle("/api/", authMiddleware.Middleware(protectedMux))
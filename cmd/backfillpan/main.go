@@ -0,0 +1,98 @@
+package main
+
+import (
+	"banksystem/internal/config"
+	"banksystem/internal/crypto"
+	"banksystem/internal/models"
+	"banksystem/internal/repositories"
+	"banksystem/internal/services"
+	"context"
+	"database/sql"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	_ "github.com/lib/pq"
+)
+
+// Однократно вычисляет ключевой хеш номера (pan_hash) для карт, выпущенных до его появления.
+// Без хеша карта не находится по реквизитам при оплате, поэтому команду нужно выполнить
+// после применения миграции 12_card_pan_hash.sql и до приема платежей:
+//
+//	CARD_PAN_HASH_KEY=... go run ./cmd/backfillpan -batch 100
+//
+// Закрытая связка PGP должна содержать ключи, которыми зашифрованы старые карты.
+// После прерывания команду достаточно запустить повторно: обрабатываются только карты без pan_hash.
+func main() {
+	batchSize := flag.Int("batch", 100, "Количество карт в одной пачке")
+	flag.Parse()
+
+	cfg := config.LoadConfig()
+
+	db, err := sql.Open("postgres", cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	keys, err := crypto.LoadKeyManager(cfg.KeySource())
+	if err != nil {
+		log.Fatalf("Failed to load PGP keys: %v", err)
+	}
+
+	hmacKeys, err := crypto.ParseHMACKeys(cfg.CardHMACKeys)
+	if err != nil {
+		log.Fatalf("Failed to load card HMAC keys: %v", err)
+	}
+
+	panHasher, err := crypto.NewPANHasher(cfg.CardPANHashKey)
+	if err != nil {
+		log.Fatalf("Failed to initialize card number hasher: %v", err)
+	}
+
+	cardService := services.NewCardService(
+		db,
+		repositories.NewCardRepository(db),
+		repositories.NewAccountRepository(db),
+		repositories.NewTransactionRepository(db),
+		repositories.NewAuditRepository(db),
+		repositories.NewUserRepository(db),
+		nil,
+		nil,
+		nil,
+		keys,
+		hmacKeys,
+		nil,
+		panHasher,
+		models.CardLimits{},
+		0,
+	)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var lastID int64
+	total := 0
+	for {
+		if ctx.Err() != nil {
+			log.Printf("Interrupted after %d cards, run again to continue", total)
+			return
+		}
+
+		filled, nextID, err := cardService.BackfillPANHashes(context.Background(), lastID, *batchSize)
+		if err != nil {
+			log.Fatalf("Backfill failed after %d cards: %v", total, err)
+		}
+		if nextID == lastID {
+			break
+		}
+
+		total += filled
+		lastID = nextID
+		log.Printf("Filled PAN hash for %d cards (last id %d)", total, lastID)
+	}
+
+	log.Printf("Backfill completed: %d cards", total)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"banksystem/internal/cbrstub"
+	"flag"
+	"log"
+	"net/http"
+)
+
+// Заглушка сервисов ЦБ для разработки без доступа к cbr.ru: SOAP-метод KeyRate (DailyInfo.asmx)
+// и курсы валют (XML_daily.asp). Сервис направляется на заглушку через CBR_BASE_URL.
+//
+//	go run ./cmd/cbrstub -addr localhost:8090 -rates rates.json -key-rate-failures timeout,500,ok
+//	CBR_BASE_URL=http://localhost:8090 go run ./cmd/api
+//
+// Без -rates используется встроенный набор ставок (internal/cbrstub/rates.json).
+// Сценарий сбоев — режимы через запятую, которые получают очередные запросы:
+//
+//	ok         обычный ответ
+//	timeout    ответ задерживается на -timeout-delay
+//	500        ответ 500 с SOAP Fault
+//	malformed  обрезанный XML
+//
+// Сценарий можно задать и у запущенной заглушки:
+//
+//	curl -X POST 'http://localhost:8090/stub/failures?endpoint=key_rate&script=500,malformed'
+//	curl -X DELETE http://localhost:8090/stub/failures
+func main() {
+	addr := flag.String("addr", "localhost:8090", "Адрес HTTP-сервера заглушки")
+	ratesPath := flag.String("rates", "", "JSON-файл со ставками в формате internal/cbrstub/rates.json")
+	keyRateFailures := flag.String("key-rate-failures", "", "Сценарий сбоев KeyRate, например timeout,500,ok")
+	dailyFailures := flag.String("daily-failures", "", "Сценарий сбоев XML_daily.asp")
+	timeoutDelay := flag.Duration("timeout-delay", 0, "Задержка ответа в режиме timeout (по умолчанию 1 минута)")
+	flag.Parse()
+
+	rates := cbrstub.DefaultRates()
+	if *ratesPath != "" {
+		var err error
+		rates, err = cbrstub.LoadRates(*ratesPath)
+		if err != nil {
+			log.Fatalf("Failed to load rates: %v", err)
+		}
+	}
+
+	stub := cbrstub.NewServer(rates)
+	if *timeoutDelay > 0 {
+		stub.TimeoutDelay = *timeoutDelay
+	}
+
+	for endpoint, script := range map[string]string{
+		cbrstub.EndpointKeyRate: *keyRateFailures,
+		cbrstub.EndpointDaily:   *dailyFailures,
+	} {
+		failures, err := cbrstub.ParseFailures(script)
+		if err != nil {
+			log.Fatalf("Invalid %s failures: %v", endpoint, err)
+		}
+		stub.Script(endpoint, failures...)
+	}
+
+	log.Printf("CBR stub listening on http://%s", *addr)
+	if err := http.ListenAndServe(*addr, stub); err != nil {
+		log.Fatalf("Failed to start CBR stub: %v", err)
+	}
+}
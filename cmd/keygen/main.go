@@ -1,8 +1,7 @@
 package main
 
 import (
-	"crypto/rand"
-	"encoding/pem"
+	"crypto"
 	"flag"
 	"fmt"
 	"golang.org/x/crypto/openpgp"
@@ -10,21 +9,28 @@ import (
 	"golang.org/x/crypto/openpgp/packet"
 	"io"
 	"os"
-	"time"
+	"path/filepath"
 )
 
+// Генерирует пару связок ключей в формате, который читает crypto.LoadKeyManager:
+// <out>/<prefix>.pub.asc — публичная связка (PGP PUBLIC KEY BLOCK),
+// <out>/<prefix>.sec.asc — закрытая связка (PGP PRIVATE KEY BLOCK или, если задана
+// парольная фраза, PGP MESSAGE, зашифрованное ею симметрично).
 func main() {
 	name := flag.String("name", "Bank System", "Имя владельца ключа")
 	email := flag.String("email", "bank@example.com", "Email владельца ключа")
-	output := flag.String("output", "key.pem", "Путь для сохранения ключа")
+	outDir := flag.String("out", "keys", "Каталог для сохранения связок ключей")
+	prefix := flag.String("prefix", "bank", "Префикс имен файлов")
+	bits := flag.Int("bits", 4096, "Длина RSA-ключа")
+	passphrase := flag.String("passphrase", os.Getenv("PGP_PASSPHRASE"), "Парольная фраза для закрытой связки (по умолчанию PGP_PASSPHRASE)")
 	flag.Parse()
 
 	// Создаем конфигурацию для генерации ключа
 	config := &packet.Config{
-		DefaultHash:            packet.SHA256,
+		DefaultHash:            crypto.SHA256,
 		DefaultCipher:          packet.CipherAES256,
 		DefaultCompressionAlgo: packet.CompressionZLIB,
-		RSABits:                4096,
+		RSABits:                *bits,
 	}
 
 	// Создаем сущность
@@ -34,7 +40,8 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Генерируем подпись
+	// Переподписываем идентичности: NewEntity добавляет предпочтительные алгоритмы
+	// уже после подписи, и без этого они не попадают в сериализованный ключ
 	for _, id := range entity.Identities {
 		err := id.SelfSignature.SignUserId(id.UserId.Id, entity.PrimaryKey, entity.PrivateKey, config)
 		if err != nil {
@@ -43,25 +50,64 @@ func main() {
 		}
 	}
 
-	// Сохраняем ключ в файл
-	file, err := os.Create(*output)
-	if err != nil {
-		fmt.Printf("Ошибка создания файла: %v\n", err)
+	if err := os.MkdirAll(*outDir, 0700); err != nil {
+		fmt.Printf("Ошибка создания каталога: %v\n", err)
 		os.Exit(1)
 	}
-	defer file.Close()
 
-	// Кодируем ключ в PEM формат
-	block := &pem.Block{
-		Type:  "PGP PRIVATE KEY",
-		Bytes: entity.PrivateKey.SerializePrivateKey(nil, config),
+	publicPath := filepath.Join(*outDir, *prefix+".pub.asc")
+	err = writeArmored(publicPath, 0644, openpgp.PublicKeyType, func(w io.Writer) error {
+		return entity.Serialize(w)
+	})
+	if err != nil {
+		fmt.Printf("Ошибка сохранения публичного ключа: %v\n", err)
+		os.Exit(1)
 	}
 
-	err = pem.Encode(file, block)
+	privatePath := filepath.Join(*outDir, *prefix+".sec.asc")
+	if *passphrase == "" {
+		err = writeArmored(privatePath, 0600, openpgp.PrivateKeyType, func(w io.Writer) error {
+			return entity.SerializePrivate(w, config)
+		})
+	} else {
+		err = writeArmored(privatePath, 0600, "PGP MESSAGE", func(w io.Writer) error {
+			encrypted, err := openpgp.SymmetricallyEncrypt(w, []byte(*passphrase), nil, config)
+			if err != nil {
+				return err
+			}
+			if err := entity.SerializePrivate(encrypted, config); err != nil {
+				return err
+			}
+			return encrypted.Close()
+		})
+	}
 	if err != nil {
-		fmt.Printf("Ошибка кодирования ключа: %v\n", err)
+		fmt.Printf("Ошибка сохранения закрытого ключа: %v\n", err)
 		os.Exit(1)
 	}
 
-	fmt.Printf("Ключ успешно создан и сохранен в %s\n", *output)
-} 
\ No newline at end of file
+	fmt.Printf("Ключ %016X создан\n", entity.PrimaryKey.KeyId)
+	fmt.Printf("Публичная связка: %s\n", publicPath)
+	fmt.Printf("Закрытая связка: %s\n", privatePath)
+}
+
+func writeArmored(path string, perm os.FileMode, blockType string, write func(w io.Writer) error) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	armorWriter, err := armor.Encode(file, blockType, nil)
+	if err != nil {
+		return err
+	}
+	if err := write(armorWriter); err != nil {
+		return err
+	}
+	if err := armorWriter.Close(); err != nil {
+		return err
+	}
+
+	return file.Close()
+}
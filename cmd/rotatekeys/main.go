@@ -0,0 +1,97 @@
+package main
+
+import (
+	"banksystem/internal/config"
+	"banksystem/internal/crypto"
+	"banksystem/internal/models"
+	"banksystem/internal/repositories"
+	"banksystem/internal/services"
+	"context"
+	"database/sql"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	_ "github.com/lib/pq"
+)
+
+// Перешифровывает данные всех карт активным ключом (первым в публичной связке).
+// Закрытая связка должна содержать и новый, и старые ключи, например:
+//
+//	PGP_PUBLIC_KEYRING_PATH=keys/new.pub.asc \
+//	PGP_PRIVATE_KEYRING_PATH=keys/new.sec.asc,keys/bank.sec.asc \
+//	go run ./cmd/rotatekeys -batch 100
+//
+// Карты обрабатываются пачками, каждая в своей транзакции. После прерывания команду
+// достаточно запустить повторно: уже перешифрованные карты отбираются по key_id.
+func main() {
+	batchSize := flag.Int("batch", 100, "Количество карт в одной транзакции")
+	flag.Parse()
+
+	cfg := config.LoadConfig()
+
+	db, err := sql.Open("postgres", cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	keys, err := crypto.LoadKeyManager(cfg.KeySource())
+	if err != nil {
+		log.Fatalf("Failed to load PGP keys: %v", err)
+	}
+
+	hmacKeys, err := crypto.ParseHMACKeys(cfg.CardHMACKeys)
+	if err != nil {
+		log.Fatalf("Failed to load card HMAC keys: %v", err)
+	}
+
+	cardService := services.NewCardService(
+		db,
+		repositories.NewCardRepository(db),
+		repositories.NewAccountRepository(db),
+		repositories.NewTransactionRepository(db),
+		repositories.NewAuditRepository(db),
+		repositories.NewUserRepository(db),
+		nil,
+		nil,
+		nil,
+		keys,
+		hmacKeys,
+		nil,
+		nil,
+		models.CardLimits{},
+		0,
+	)
+
+	// Прерывание обрабатываем между пачками, чтобы не оставлять открытых транзакций
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("Rotating card data to key %s", keys.ActiveKeyID())
+
+	var lastID int64
+	total := 0
+	for {
+		if ctx.Err() != nil {
+			log.Printf("Interrupted after %d cards, run again to continue", total)
+			return
+		}
+
+		processed, nextID, err := cardService.ReencryptBatch(context.Background(), lastID, *batchSize)
+		if err != nil {
+			log.Fatalf("Rotation failed after %d cards: %v", total, err)
+		}
+		if nextID == lastID {
+			break
+		}
+
+		total += processed
+		lastID = nextID
+		log.Printf("Re-encrypted %d cards (last id %d)", total, lastID)
+	}
+
+	log.Printf("Rotation completed: %d cards re-encrypted", total)
+}
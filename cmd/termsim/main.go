@@ -0,0 +1,244 @@
+package main
+
+import (
+	"banksystem/internal/iso8583"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// Симулятор платежного терминала: отправляет сообщения ISO 8583 на слушатель сервиса
+// (ISO8583_ADDR) и печатает ответы. Терминал должен быть зарегистрирован в таблице terminals,
+// сообщения подписываются его ключом MAC (поле 64).
+//
+//	go run ./cmd/termsim -mac-key $TERM_MAC_KEY -pan 2200123412341234 -expiry 05/29 -cvv 123 -amount 1500 -scenario auth-capture
+//
+// Сценарии:
+//
+//	auth              блокировка средств (0100)
+//	purchase          покупка без предварительной блокировки (0200)
+//	capture           списание по ранее выполненной блокировке с тем же -rrn (0200)
+//	reverse           отмена операции с -rrn на сумму -amount (0400)
+//	auth-capture      блокировка и списание
+//	auth-reverse      блокировка и ее отмена
+//	purchase-reverse  покупка и возврат средств
+func main() {
+	addr := flag.String("addr", "localhost:8583", "Адрес слушателя ISO 8583")
+	terminalID := flag.String("terminal", "TERM0001", "Идентификатор терминала (поле 41)")
+	macKeyHex := flag.String("mac-key", "", "Ключ MAC терминала в hex (поле 64)")
+	merchantID := flag.String("merchant-id", "MERCHANT000001", "Идентификатор продавца (поле 42)")
+	merchantName := flag.String("merchant", "Test Shop", "Название продавца (поле 43)")
+	city := flag.String("city", "Moscow", "Город продавца (поле 43)")
+	country := flag.String("country", "RU", "Страна продавца (поле 43)")
+	mcc := flag.String("mcc", "5411", "MCC продавца (поле 18)")
+	pan := flag.String("pan", "", "Номер карты")
+	expiry := flag.String("expiry", "", "Срок действия MM/YY")
+	cvv := flag.String("cvv", "", "CVV2 (поле 48)")
+	pin := flag.String("pin", "", "PIN-код (поле 52, ISO 9564 format 0)")
+	amount := flag.Float64("amount", 0, "Сумма в рублях")
+	captureAmount := flag.Float64("capture-amount", 0, "Сумма списания по блокировке (по умолчанию равна -amount)")
+	channel := flag.String("channel", "chip", "Канал: ecom, chip или contactless")
+	rrn := flag.String("rrn", "", "RRN операции (по умолчанию генерируется)")
+	scenario := flag.String("scenario", "purchase", "Сценарий: auth, purchase, capture, reverse, auth-capture, auth-reverse, purchase-reverse")
+	timeout := flag.Duration("timeout", 30*time.Second, "Таймаут ожидания ответа")
+	flag.Parse()
+
+	if *rrn == "" {
+		*rrn = time.Now().Format("060102150405")
+	}
+	if *captureAmount == 0 {
+		*captureAmount = *amount
+	}
+
+	macKey, err := hex.DecodeString(*macKeyHex)
+	if err != nil || len(macKey) < iso8583.MinMACKeyLength {
+		log.Fatalf("-mac-key must be a hex key of at least %d bytes", iso8583.MinMACKeyLength)
+	}
+
+	entryMode, ok := entryModes[*channel]
+	if !ok {
+		log.Fatalf("Unknown channel %q", *channel)
+	}
+
+	conn, err := net.DialTimeout("tcp", *addr, *timeout)
+	if err != nil {
+		log.Fatalf("Failed to connect to %s: %v", *addr, err)
+	}
+	defer conn.Close()
+
+	sim := &terminal{
+		conn:    conn,
+		timeout: *timeout,
+		macKey:  macKey,
+		stan:    int(time.Now().UnixNano()/int64(time.Millisecond)) % 1000000,
+		card: cardData{
+			pan:    strings.ReplaceAll(*pan, " ", ""),
+			expiry: *expiry,
+			cvv:    *cvv,
+			pin:    *pin,
+		},
+		terminalID:   *terminalID,
+		merchantID:   *merchantID,
+		cardAcceptor: iso8583.FormatCardAcceptor(*merchantName, *city, *country),
+		mcc:          *mcc,
+		entryMode:    entryMode,
+		rrn:          *rrn,
+		lastAmount:   *amount,
+	}
+
+	var steps []func() (*iso8583.Message, error)
+	switch *scenario {
+	case "auth":
+		steps = append(steps, func() (*iso8583.Message, error) { return sim.payment(iso8583.MTIAuthorizationRequest, *amount) })
+	case "purchase", "capture":
+		steps = append(steps, func() (*iso8583.Message, error) { return sim.payment(iso8583.MTIFinancialRequest, *captureAmount) })
+	case "reverse":
+		steps = append(steps, sim.reversal)
+	case "auth-capture":
+		steps = append(steps,
+			func() (*iso8583.Message, error) { return sim.payment(iso8583.MTIAuthorizationRequest, *amount) },
+			func() (*iso8583.Message, error) { return sim.payment(iso8583.MTIFinancialRequest, *captureAmount) },
+		)
+	case "auth-reverse":
+		steps = append(steps,
+			func() (*iso8583.Message, error) { return sim.payment(iso8583.MTIAuthorizationRequest, *amount) },
+			sim.reversal,
+		)
+	case "purchase-reverse":
+		steps = append(steps,
+			func() (*iso8583.Message, error) { return sim.payment(iso8583.MTIFinancialRequest, *amount) },
+			sim.reversal,
+		)
+	default:
+		log.Fatalf("Unknown scenario %q", *scenario)
+	}
+
+	for _, step := range steps {
+		response, err := step()
+		if err != nil {
+			log.Fatalf("Exchange failed: %v", err)
+		}
+		if code := response.Get(iso8583.FieldResponseCode); code != iso8583.ResponseApproved {
+			fmt.Printf("Declined with response code %s\n", code)
+			os.Exit(1)
+		}
+	}
+}
+
+// entryModes значения поля 22 для каналов оплаты
+var entryModes = map[string]string{
+	"ecom":        "812",
+	"chip":        "051",
+	"contactless": "071",
+}
+
+type cardData struct {
+	pan    string
+	expiry string // MM/YY
+	cvv    string
+	pin    string
+}
+
+type terminal struct {
+	conn         net.Conn
+	timeout      time.Duration
+	macKey       []byte
+	stan         int
+	card         cardData
+	terminalID   string
+	merchantID   string
+	cardAcceptor string
+	mcc          string
+	entryMode    string
+	rrn          string
+
+	// Данные последней операции для поля 90 отмены
+	lastMTI      string
+	lastSTAN     string
+	lastDateTime string
+	lastAmount   float64
+}
+
+func (t *terminal) payment(mti string, amount float64) (*iso8583.Message, error) {
+	expiry := strings.Split(t.card.expiry, "/")
+	if len(expiry) != 2 {
+		return nil, fmt.Errorf("expiry must be MM/YY")
+	}
+
+	request := t.newRequest(mti, "000000")
+	request.Set(iso8583.FieldPAN, t.card.pan)
+	request.Set(iso8583.FieldAmount, iso8583.FormatAmount(amount))
+	request.Set(iso8583.FieldExpiryDate, expiry[1]+expiry[0])
+	request.Set(iso8583.FieldMCC, t.mcc)
+	request.Set(iso8583.FieldPOSEntryMode, t.entryMode)
+	request.Set(iso8583.FieldCardAcceptor, t.cardAcceptor)
+	if t.card.cvv != "" {
+		request.Set(iso8583.FieldAdditionalData, t.card.cvv)
+	}
+	if t.card.pin != "" {
+		block, err := iso8583.EncodePINBlock(t.card.pin, t.card.pan)
+		if err != nil {
+			return nil, err
+		}
+		request.Set(iso8583.FieldPINBlock, block)
+	}
+
+	t.lastMTI = mti
+	t.lastSTAN = request.Get(iso8583.FieldSTAN)
+	t.lastDateTime = request.Get(iso8583.FieldTransmissionDateTime)
+	t.lastAmount = amount
+
+	return t.exchange(request, fmt.Sprintf("amount=%.2f", amount))
+}
+
+func (t *terminal) reversal() (*iso8583.Message, error) {
+	request := t.newRequest(iso8583.MTIReversalRequest, "000000")
+	request.Set(iso8583.FieldAmount, iso8583.FormatAmount(t.lastAmount))
+	if t.lastMTI != "" {
+		request.Set(iso8583.FieldOriginalData, t.lastMTI+t.lastSTAN+t.lastDateTime+strings.Repeat("0", 22))
+	}
+	return t.exchange(request, fmt.Sprintf("amount=%.2f", t.lastAmount))
+}
+
+func (t *terminal) newRequest(mti, processingCode string) *iso8583.Message {
+	t.stan = t.stan%999999 + 1
+	now := time.Now()
+
+	request := iso8583.NewMessage(mti)
+	request.Set(iso8583.FieldProcessingCode, processingCode)
+	request.Set(iso8583.FieldTransmissionDateTime, now.UTC().Format("0102150405"))
+	request.Set(iso8583.FieldSTAN, fmt.Sprintf("%06d", t.stan))
+	request.Set(iso8583.FieldLocalTime, now.Format("150405"))
+	request.Set(iso8583.FieldLocalDate, now.Format("0102"))
+	request.Set(iso8583.FieldRRN, t.rrn)
+	request.Set(iso8583.FieldTerminalID, t.terminalID)
+	request.Set(iso8583.FieldMerchantID, t.merchantID)
+	request.Set(iso8583.FieldCurrency, "643")
+	return request
+}
+
+func (t *terminal) exchange(request *iso8583.Message, details string) (*iso8583.Message, error) {
+	fmt.Printf("-> %s stan=%s rrn=%s %s\n", request.MTI, request.Get(iso8583.FieldSTAN), request.Get(iso8583.FieldRRN), details)
+
+	if err := request.Sign(t.macKey); err != nil {
+		return nil, err
+	}
+	t.conn.SetDeadline(time.Now().Add(t.timeout))
+	if err := iso8583.WriteMessage(t.conn, request); err != nil {
+		return nil, err
+	}
+
+	response, err := iso8583.ReadMessage(t.conn)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("<- %s stan=%s response=%s auth=%s\n", response.MTI, response.Get(iso8583.FieldSTAN),
+		response.Get(iso8583.FieldResponseCode), response.Get(iso8583.FieldAuthCode))
+	return response, nil
+}
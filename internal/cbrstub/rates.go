@@ -0,0 +1,93 @@
+package cbrstub
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+//go:embed rates.json
+var defaultRates []byte
+
+// KeyRateChange решение ЦБ по ключевой ставке: ставка Rate действует с даты Date (YYYY-MM-DD)
+type KeyRateChange struct {
+	Date string  `json:"date"`
+	Rate float64 `json:"rate"`
+}
+
+// Currency курс валюты для XML_daily.asp: Value рублей за Nominal единиц
+type Currency struct {
+	ID       string  `json:"id"`
+	NumCode  string  `json:"num_code"`
+	CharCode string  `json:"char_code"`
+	Nominal  int     `json:"nominal"`
+	Name     string  `json:"name"`
+	Value    float64 `json:"value"`
+}
+
+// Rates данные, которые отдает заглушка
+type Rates struct {
+	KeyRate    []KeyRateChange `json:"key_rate"`
+	Currencies []Currency      `json:"currencies"`
+
+	changes []keyRateChange
+}
+
+type keyRateChange struct {
+	date time.Time
+	rate float64
+}
+
+// DefaultRates возвращает встроенный набор ставок
+func DefaultRates() *Rates {
+	rates, err := ParseRates(defaultRates)
+	if err != nil {
+		panic(fmt.Sprintf("invalid embedded rates: %v", err))
+	}
+	return rates
+}
+
+// LoadRates загружает ставки из JSON-файла в формате rates.json
+func LoadRates(path string) (*Rates, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseRates(data)
+}
+
+func ParseRates(data []byte) (*Rates, error) {
+	rates := &Rates{}
+	if err := json.Unmarshal(data, rates); err != nil {
+		return nil, err
+	}
+
+	for _, change := range rates.KeyRate {
+		date, err := time.Parse("2006-01-02", change.Date)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key rate date %q: %v", change.Date, err)
+		}
+		rates.changes = append(rates.changes, keyRateChange{date: date, rate: change.Rate})
+	}
+	sort.Slice(rates.changes, func(i, j int) bool {
+		return rates.changes[i].date.Before(rates.changes[j].date)
+	})
+
+	return rates, nil
+}
+
+// keyRateOn возвращает ставку, действующую на дату date
+func (r *Rates) keyRateOn(date time.Time) (float64, bool) {
+	var rate float64
+	found := false
+	for _, change := range r.changes {
+		if change.date.After(date) {
+			break
+		}
+		rate, found = change.rate, true
+	}
+	return rate, found
+}
@@ -0,0 +1,60 @@
+package cbrstub
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const keyRateResponseHead = `<?xml version="1.0" encoding="utf-8"?><soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:xsd="http://www.w3.org/2001/XMLSchema"><soap:Body><KeyRateResponse xmlns="http://web.cbr.ru/"><KeyRateResult><xs:schema id="KeyRate" xmlns="" xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:msdata="urn:schemas-microsoft-com:xml-msdata"><xs:element name="KeyRate" msdata:IsDataSet="true" msdata:UseCurrentLocale="true"><xs:complexType><xs:choice minOccurs="0" maxOccurs="unbounded"><xs:element name="KR"><xs:complexType><xs:sequence><xs:element name="DT" type="xs:dateTime" minOccurs="0" /><xs:element name="Rate" type="xs:decimal" minOccurs="0" /></xs:sequence></xs:complexType></xs:element></xs:choice></xs:complexType></xs:element></xs:schema>`
+
+const keyRateResponseTail = `</KeyRateResult></KeyRateResponse></soap:Body></soap:Envelope>`
+
+const diffgramAttrs = `xmlns:msdata="urn:schemas-microsoft-com:xml-msdata" xmlns:diffgr="urn:schemas-microsoft-com:xml-diffgram-v1"`
+
+// keyRateResponse формирует ответ KeyRate так же, как ЦБ: ставка за каждый рабочий день периода
+// в порядке убывания даты; без ставок за период diffgram пустой
+func keyRateResponse(rates *Rates, from, to time.Time) string {
+	var rows strings.Builder
+	row := 0
+	for date := to; !date.Before(from); date = date.AddDate(0, 0, -1) {
+		if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+			continue
+		}
+		rate, ok := rates.keyRateOn(date)
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(&rows, `<KR diffgr:id="KR%d" msdata:rowOrder="%d"><DT>%sT00:00:00+03:00</DT><Rate>%.2f</Rate></KR>`,
+			row+1, row, date.Format("2006-01-02"), rate)
+		row++
+	}
+
+	if row == 0 {
+		return keyRateResponseHead + `<diffgr:diffgram ` + diffgramAttrs + ` />` + keyRateResponseTail
+	}
+	return keyRateResponseHead + `<diffgr:diffgram ` + diffgramAttrs + `><KeyRate xmlns="">` + rows.String() +
+		`</KeyRate></diffgr:diffgram>` + keyRateResponseTail
+}
+
+// xmlDailyResponse формирует курсы валют в формате XML_daily.asp на дату date
+func xmlDailyResponse(rates *Rates, date time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<?xml version="1.0" encoding="utf-8"?><ValCurs Date="%s" name="Foreign Currency Market">`, date.Format("02.01.2006"))
+	for _, currency := range rates.Currencies {
+		nominal := currency.Nominal
+		if nominal <= 0 {
+			nominal = 1
+		}
+		fmt.Fprintf(&b, `<Valute ID="%s"><NumCode>%s</NumCode><CharCode>%s</CharCode><Nominal>%d</Nominal><Name>%s</Name><Value>%s</Value><VunitRate>%s</VunitRate></Valute>`,
+			escapeXML(currency.ID), escapeXML(currency.NumCode), escapeXML(currency.CharCode), nominal, escapeXML(currency.Name),
+			formatDecimal(currency.Value), formatDecimal(currency.Value/float64(nominal)))
+	}
+	b.WriteString(`</ValCurs>`)
+	return b.String()
+}
+
+// formatDecimal форматирует число с запятой, как в ответах ЦБ
+func formatDecimal(value float64) string {
+	return strings.Replace(fmt.Sprintf("%.4f", value), ".", ",", 1)
+}
@@ -0,0 +1,289 @@
+package cbrstub
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/beevik/etree"
+)
+
+// Пути сервисов ЦБ относительно базового адреса
+const (
+	DailyInfoPath = "/DailyInfoWebServ/DailyInfo.asmx"
+	XMLDailyPath  = "/scripts/XML_daily.asp"
+	// Управление сценарием сбоев для запущенного бинарника
+	FailuresPath = "/stub/failures"
+)
+
+// Эндпоинты, для которых задается сценарий сбоев
+const (
+	EndpointKeyRate = "key_rate"
+	EndpointDaily   = "daily"
+)
+
+// Failure режим ответа на очередной запрос
+type Failure string
+
+const (
+	// Обычный ответ; нужен, чтобы чередовать сбои с успешными ответами
+	FailureNone Failure = "ok"
+	// Ответ задерживается на TimeoutDelay или до отключения клиента
+	FailureTimeout Failure = "timeout"
+	// Ответ 500 с SOAP Fault
+	FailureServerError Failure = "500"
+	// Обрезанный XML
+	FailureMalformed Failure = "malformed"
+)
+
+// ParseFailure проверяет название режима сбоя
+func ParseFailure(value string) (Failure, error) {
+	switch failure := Failure(strings.TrimSpace(value)); failure {
+	case FailureNone, FailureTimeout, FailureServerError, FailureMalformed:
+		return failure, nil
+	default:
+		return "", fmt.Errorf("unknown failure %q", value)
+	}
+}
+
+// ParseFailures разбирает сценарий сбоев через запятую, например "timeout,500,ok"
+func ParseFailures(value string) ([]Failure, error) {
+	var failures []Failure
+	for _, item := range strings.Split(value, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		failure, err := ParseFailure(item)
+		if err != nil {
+			return nil, err
+		}
+		failures = append(failures, failure)
+	}
+	return failures, nil
+}
+
+// Server заглушка сервисов ЦБ: SOAP-метод KeyRate сервиса DailyInfo.asmx и курсы валют XML_daily.asp.
+// Для каждого эндпоинта можно задать сценарий сбоев: очередной запрос получает очередной режим из сценария,
+// после окончания сценария запросы обслуживаются обычно.
+type Server struct {
+	rates *Rates
+	// Задержка ответа в режиме FailureTimeout
+	TimeoutDelay time.Duration
+
+	mu       sync.Mutex
+	scripts  map[string][]Failure
+	requests map[string]int
+}
+
+func NewServer(rates *Rates) *Server {
+	return &Server{
+		rates:        rates,
+		TimeoutDelay: time.Minute,
+		scripts:      make(map[string][]Failure),
+		requests:     make(map[string]int),
+	}
+}
+
+// NewTestServer запускает заглушку на случайном локальном порту для тестов и отладки.
+// Адрес сервера (URL) передается в CentralBankService как базовый; сервер закрывается через Close.
+func NewTestServer(rates *Rates) (*Server, *httptest.Server) {
+	stub := NewServer(rates)
+	return stub, httptest.NewServer(stub)
+}
+
+// Script добавляет режимы failures в сценарий эндпоинта endpoint
+func (s *Server) Script(endpoint string, failures ...Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scripts[endpoint] = append(s.scripts[endpoint], failures...)
+}
+
+// Reset очищает сценарии сбоев и счетчики запросов
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scripts = make(map[string][]Failure)
+	s.requests = make(map[string]int)
+}
+
+// Requests возвращает число запросов к эндпоинту endpoint, включая неудачные
+func (s *Server) Requests(endpoint string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[endpoint]
+}
+
+func (s *Server) next(endpoint string) Failure {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests[endpoint]++
+	script := s.scripts[endpoint]
+	if len(script) == 0 {
+		return FailureNone
+	}
+	s.scripts[endpoint] = script[1:]
+	return script[0]
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case DailyInfoPath:
+		s.serveDailyInfo(w, r)
+	case XMLDailyPath:
+		s.serveXMLDaily(w, r)
+	case FailuresPath:
+		s.serveFailures(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveFailures задает сценарий сбоев: POST ?endpoint=key_rate&script=timeout,500,ok; DELETE очищает сценарии
+func (s *Server) serveFailures(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		endpoint := r.URL.Query().Get("endpoint")
+		if endpoint != EndpointKeyRate && endpoint != EndpointDaily {
+			http.Error(w, "Invalid endpoint", http.StatusBadRequest)
+			return
+		}
+		failures, err := ParseFailures(r.URL.Query().Get("script"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.Script(endpoint, failures...)
+		log.Printf("Scripted %s failures: %v", endpoint, failures)
+	case http.MethodDelete:
+		s.Reset()
+		log.Printf("Failure scripts reset")
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) serveDailyInfo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeSOAPFault(w, err.Error())
+		return
+	}
+
+	failure, ok := s.handleFailure(w, r, EndpointKeyRate)
+	if !ok {
+		return
+	}
+
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(body); err != nil {
+		writeSOAPFault(w, fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+	request := doc.FindElement("//Envelope/Body/KeyRate")
+	if request == nil {
+		writeSOAPFault(w, "only KeyRate operation is supported")
+		return
+	}
+
+	from, err := parseSOAPDate(request.FindElement("./fromDate"))
+	if err != nil {
+		writeSOAPFault(w, fmt.Sprintf("invalid fromDate: %v", err))
+		return
+	}
+	to, err := parseSOAPDate(request.FindElement("./ToDate"))
+	if err != nil {
+		writeSOAPFault(w, fmt.Sprintf("invalid ToDate: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/soap+xml; charset=utf-8")
+	writeResponse(w, keyRateResponse(s.rates, from, to), failure)
+}
+
+func (s *Server) serveXMLDaily(w http.ResponseWriter, r *http.Request) {
+	failure, ok := s.handleFailure(w, r, EndpointDaily)
+	if !ok {
+		return
+	}
+
+	date := time.Now()
+	if value := r.URL.Query().Get("date_req"); value != "" {
+		parsed, err := time.Parse("02/01/2006", value)
+		if err != nil {
+			http.Error(w, "Invalid date_req", http.StatusBadRequest)
+			return
+		}
+		date = parsed
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	writeResponse(w, xmlDailyResponse(s.rates, date), failure)
+}
+
+// handleFailure берет очередной режим из сценария эндпоинта и выполняет задержку или ответ 500.
+// Возвращает false, если ответ уже отправлен; FailureMalformed применяется при записи ответа.
+func (s *Server) handleFailure(w http.ResponseWriter, r *http.Request, endpoint string) (Failure, bool) {
+	failure := s.next(endpoint)
+	switch failure {
+	case FailureTimeout:
+		log.Printf("%s: delaying response by %s", endpoint, s.TimeoutDelay)
+		select {
+		case <-r.Context().Done():
+			return failure, false
+		case <-time.After(s.TimeoutDelay):
+		}
+	case FailureServerError:
+		log.Printf("%s: responding with 500", endpoint)
+		writeSOAPFault(w, "Server was unable to process request.")
+		return failure, false
+	case FailureMalformed:
+		log.Printf("%s: responding with malformed XML", endpoint)
+	}
+	return failure, true
+}
+
+func writeResponse(w http.ResponseWriter, response string, failure Failure) {
+	if failure == FailureMalformed {
+		response = response[:len(response)/2]
+	}
+	io.WriteString(w, response)
+}
+
+func writeSOAPFault(w http.ResponseWriter, reason string) {
+	w.Header().Set("Content-Type", "application/soap+xml; charset=utf-8")
+	w.WriteHeader(http.StatusInternalServerError)
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="utf-8"?><soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:xsd="http://www.w3.org/2001/XMLSchema"><soap:Body><soap:Fault><soap:Code><soap:Value>soap:Receiver</soap:Value></soap:Code><soap:Reason><soap:Text xml:lang="ru">%s</soap:Text></soap:Reason><soap:Detail /></soap:Fault></soap:Body></soap:Envelope>`, escapeXML(reason))
+}
+
+// parseSOAPDate разбирает значение xs:dateTime; время суток отбрасывается
+func parseSOAPDate(element *etree.Element) (time.Time, error) {
+	if element == nil {
+		return time.Time{}, fmt.Errorf("missing")
+	}
+	value := strings.TrimSpace(element.Text())
+	if len(value) > len("2006-01-02") {
+		value = value[:len("2006-01-02")]
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+func escapeXML(value string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(value))
+	return b.String()
+}
@@ -0,0 +1,86 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// HMACKeyring хранит версии ключей HMAC для контроля целостности данных карт.
+// Новые HMAC вычисляются ключом последней версии, проверка принимает любую известную версию,
+// чтобы карты, подписанные до ротации, оставались валидными до переподписи.
+type HMACKeyring struct {
+	keys   map[int][]byte
+	active int
+}
+
+// ParseHMACKeys разбирает список ключей вида "1:old-key,2:new-key"
+func ParseHMACKeys(spec string) (*HMACKeyring, error) {
+	keyring := &HMACKeyring{keys: make(map[int][]byte)}
+
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		versionStr, key, ok := strings.Cut(item, ":")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("неверный формат ключа HMAC %q, ожидается версия:ключ", item)
+		}
+		version, err := strconv.Atoi(versionStr)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("неверная версия ключа HMAC %q", versionStr)
+		}
+		if _, exists := keyring.keys[version]; exists {
+			return nil, fmt.Errorf("версия ключа HMAC %d указана дважды", version)
+		}
+
+		keyring.keys[version] = []byte(key)
+		if version > keyring.active {
+			keyring.active = version
+		}
+	}
+
+	if len(keyring.keys) == 0 {
+		return nil, errors.New("не задан ни один ключ HMAC")
+	}
+
+	return keyring, nil
+}
+
+// ActiveVersion возвращает версию ключа, которой подписываются новые данные
+func (k *HMACKeyring) ActiveVersion() int {
+	return k.active
+}
+
+// Compute вычисляет HMAC активным ключом и возвращает его вместе с версией ключа
+func (k *HMACKeyring) Compute(data string) (string, int) {
+	mac, _ := k.ComputeVersion(data, k.active)
+	return mac, k.active
+}
+
+// ComputeVersion вычисляет HMAC ключом указанной версии
+func (k *HMACKeyring) ComputeVersion(data string, version int) (string, error) {
+	key, ok := k.keys[version]
+	if !ok {
+		return "", fmt.Errorf("неизвестная версия ключа HMAC: %d", version)
+	}
+
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// Verify проверяет HMAC ключом версии, которой он был вычислен
+func (k *HMACKeyring) Verify(data, mac string, version int) bool {
+	expected, err := k.ComputeVersion(data, version)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal([]byte(expected), []byte(mac))
+}
@@ -0,0 +1,206 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+// KeySource описывает, откуда загружать связки ключей: содержимое в armored-виде
+// (например, из переменной окружения) имеет приоритет над путем к файлу.
+// PrivateKeyringPath может содержать несколько путей через запятую: во время ротации
+// к новой закрытой связке добавляется старая, чтобы читать еще не перешифрованные карты.
+type KeySource struct {
+	PublicKeyring      string
+	PublicKeyringPath  string
+	PrivateKeyring     string
+	PrivateKeyringPath string
+	Passphrase         string
+}
+
+// KeyManager хранит ключи банка для шифрования данных карт.
+// Шифрование выполняется активным ключом (первым в публичной связке),
+// расшифровка — любым ключом закрытой связки, что позволяет читать данные во время ротации.
+type KeyManager struct {
+	active  *openpgp.Entity
+	keyring openpgp.EntityList
+}
+
+// LoadKeyManager загружает связки ключей и расшифровывает закрытые ключи парольной фразой
+func LoadKeyManager(source KeySource) (*KeyManager, error) {
+	passphrase := []byte(source.Passphrase)
+
+	var private openpgp.EntityList
+	if source.PrivateKeyring != "" {
+		keyring, err := readKeyring(source.PrivateKeyring, "", passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка загрузки закрытых ключей: %v", err)
+		}
+		private = keyring
+	} else {
+		for _, path := range strings.Split(source.PrivateKeyringPath, ",") {
+			keyring, err := readKeyring("", strings.TrimSpace(path), passphrase)
+			if err != nil {
+				return nil, fmt.Errorf("ошибка загрузки закрытых ключей из %s: %v", path, err)
+			}
+			private = append(private, keyring...)
+		}
+	}
+	if len(private) == 0 {
+		return nil, errors.New("закрытая связка ключей пуста")
+	}
+
+	for _, entity := range private {
+		if err := decryptEntity(entity, passphrase); err != nil {
+			return nil, fmt.Errorf("ключ %s: %v", KeyID(entity), err)
+		}
+	}
+
+	// Без публичной связки шифруем первым ключом закрытой
+	public := private
+	if source.PublicKeyring != "" || source.PublicKeyringPath != "" {
+		var err error
+		public, err = readKeyring(source.PublicKeyring, source.PublicKeyringPath, passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка загрузки публичных ключей: %v", err)
+		}
+		if len(public) == 0 {
+			return nil, errors.New("публичная связка ключей пуста")
+		}
+	}
+
+	active := public[0]
+	if len(private.KeysById(active.PrimaryKey.KeyId)) == 0 {
+		return nil, fmt.Errorf("для активного ключа %s нет закрытого ключа", KeyID(active))
+	}
+
+	return &KeyManager{active: active, keyring: private}, nil
+}
+
+// ActiveKeyID возвращает идентификатор ключа, которым шифруются новые данные
+func (m *KeyManager) ActiveKeyID() string {
+	return KeyID(m.active)
+}
+
+// EncryptCardData шифрует номер карты и CVV активным ключом.
+// Возвращает armored PGP-сообщение и идентификатор использованного ключа.
+func (m *KeyManager) EncryptCardData(cardNumber, cvv string) (string, string, error) {
+	buf := new(bytes.Buffer)
+
+	armorWriter, err := armor.Encode(buf, "PGP MESSAGE", nil)
+	if err != nil {
+		return "", "", fmt.Errorf("ошибка кодирования: %v", err)
+	}
+
+	encryptedWriter, err := openpgp.Encrypt(armorWriter, []*openpgp.Entity{m.active}, nil, nil, nil)
+	if err != nil {
+		return "", "", fmt.Errorf("ошибка шифрования: %v", err)
+	}
+
+	if _, err := encryptedWriter.Write([]byte(cardNumber + "|" + cvv)); err != nil {
+		return "", "", fmt.Errorf("ошибка записи данных: %v", err)
+	}
+
+	if err := encryptedWriter.Close(); err != nil {
+		return "", "", fmt.Errorf("ошибка шифрования: %v", err)
+	}
+	if err := armorWriter.Close(); err != nil {
+		return "", "", fmt.Errorf("ошибка кодирования: %v", err)
+	}
+
+	return buf.String(), m.ActiveKeyID(), nil
+}
+
+// DecryptCardData расшифровывает данные карты любым ключом закрытой связки
+func (m *KeyManager) DecryptCardData(encryptedData string) (string, string, error) {
+	block, err := armor.Decode(strings.NewReader(encryptedData))
+	if err != nil {
+		return "", "", fmt.Errorf("ошибка декодирования: %v", err)
+	}
+
+	md, err := openpgp.ReadMessage(block.Body, m.keyring, nil, nil)
+	if err != nil {
+		return "", "", fmt.Errorf("ошибка чтения сообщения: %v", err)
+	}
+
+	plaintext, err := io.ReadAll(md.UnverifiedBody)
+	if err != nil {
+		return "", "", fmt.Errorf("ошибка чтения данных: %v", err)
+	}
+
+	parts := strings.Split(string(plaintext), "|")
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("неверный формат данных")
+	}
+
+	return parts[0], parts[1], nil
+}
+
+// KeyID возвращает идентификатор ключа в шестнадцатеричном виде, как его показывает gpg
+func KeyID(entity *openpgp.Entity) string {
+	return fmt.Sprintf("%016X", entity.PrimaryKey.KeyId)
+}
+
+// readKeyring читает armored-связку ключей из строки или файла.
+// Связка, зашифрованная симметрично парольной фразой (см. cmd/keygen), расшифровывается.
+func readKeyring(content, path string, passphrase []byte) (openpgp.EntityList, error) {
+	if content == "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		content = string(data)
+	}
+
+	block, err := armor.Decode(strings.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка декодирования: %v", err)
+	}
+
+	if block.Type != "PGP MESSAGE" {
+		return openpgp.ReadKeyRing(block.Body)
+	}
+
+	prompted := false
+	md, err := openpgp.ReadMessage(block.Body, nil, func(keys []openpgp.Key, symmetric bool) ([]byte, error) {
+		if !symmetric || prompted || len(passphrase) == 0 {
+			return nil, errors.New("неверная парольная фраза")
+		}
+		prompted = true
+		return passphrase, nil
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка расшифровки связки: %v", err)
+	}
+
+	return openpgp.ReadKeyRing(md.UnverifiedBody)
+}
+
+// decryptEntity расшифровывает защищенные парольной фразой закрытые ключи сущности
+func decryptEntity(entity *openpgp.Entity, passphrase []byte) error {
+	keys := []*packet.PrivateKey{entity.PrivateKey}
+	for _, subkey := range entity.Subkeys {
+		keys = append(keys, subkey.PrivateKey)
+	}
+
+	for _, key := range keys {
+		if key == nil || !key.Encrypted {
+			continue
+		}
+		if len(passphrase) == 0 {
+			return errors.New("закрытый ключ защищен парольной фразой, но она не задана")
+		}
+		if err := key.Decrypt(passphrase); err != nil {
+			return fmt.Errorf("неверная парольная фраза: %v", err)
+		}
+	}
+
+	return nil
+}
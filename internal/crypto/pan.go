@@ -0,0 +1,178 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// PANLength длина выпускаемых номеров карт
+const PANLength = 16
+
+// BINRange диапазон префиксов номеров карт эмитента одинаковой длины, например 2200–2204 для «Мир»
+type BINRange struct {
+	Low  uint64
+	High uint64
+	// Число цифр префикса
+	Digits int
+}
+
+// PANGenerator выпускает номера карт из заданных диапазонов BIN
+type PANGenerator struct {
+	ranges []BINRange
+}
+
+// ParseBINRanges разбирает список диапазонов вида "2200-2204,220070" (одиночный префикс — диапазон из одного BIN)
+func ParseBINRanges(spec string) (*PANGenerator, error) {
+	generator := &PANGenerator{}
+
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		lowStr, highStr, found := strings.Cut(item, "-")
+		if !found {
+			highStr = lowStr
+		}
+		lowStr, highStr = strings.TrimSpace(lowStr), strings.TrimSpace(highStr)
+
+		if len(lowStr) != len(highStr) || len(lowStr) == 0 || len(lowStr) >= PANLength-1 {
+			return nil, fmt.Errorf("неверный диапазон BIN %q", item)
+		}
+		low, err := strconv.ParseUint(lowStr, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("неверный диапазон BIN %q", item)
+		}
+		high, err := strconv.ParseUint(highStr, 10, 64)
+		if err != nil || high < low || lowStr[0] == '0' {
+			return nil, fmt.Errorf("неверный диапазон BIN %q", item)
+		}
+
+		generator.ranges = append(generator.ranges, BINRange{Low: low, High: high, Digits: len(lowStr)})
+	}
+
+	if len(generator.ranges) == 0 {
+		return nil, errors.New("не задан ни один диапазон BIN")
+	}
+
+	return generator, nil
+}
+
+// Generate возвращает случайный номер карты: BIN из диапазона, случайные цифры и контрольная цифра по алгоритму Луна
+func (g *PANGenerator) Generate() (string, error) {
+	index, err := randomUint(uint64(len(g.ranges)))
+	if err != nil {
+		return "", err
+	}
+	binRange := g.ranges[index]
+
+	offset, err := randomUint(binRange.High - binRange.Low + 1)
+	if err != nil {
+		return "", err
+	}
+
+	var pan strings.Builder
+	pan.WriteString(fmt.Sprintf("%0*d", binRange.Digits, binRange.Low+offset))
+	for pan.Len() < PANLength-1 {
+		digit, err := randomUint(10)
+		if err != nil {
+			return "", err
+		}
+		pan.WriteByte(byte('0' + digit))
+	}
+
+	payload := pan.String()
+	return payload + strconv.Itoa(LuhnCheckDigit(payload)), nil
+}
+
+// GenerateCVV возвращает случайный трехзначный CVV
+func GenerateCVV() (string, error) {
+	return RandomDigits(3)
+}
+
+// RandomDigits возвращает строку из n случайных цифр
+func RandomDigits(n int) (string, error) {
+	digits := make([]byte, n)
+	for i := range digits {
+		digit, err := randomUint(10)
+		if err != nil {
+			return "", err
+		}
+		digits[i] = byte('0' + digit)
+	}
+	return string(digits), nil
+}
+
+// LuhnCheckDigit вычисляет контрольную цифру для номера без нее
+func LuhnCheckDigit(payload string) int {
+	sum := 0
+	for i := len(payload) - 1; i >= 0; i-- {
+		digit := int(payload[i] - '0')
+		// Удваивается каждая вторая цифра, начиная с ближайшей к контрольной
+		if (len(payload)-1-i)%2 == 0 {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+	}
+	return (10 - sum%10) % 10
+}
+
+// LuhnValid проверяет контрольную цифру номера карты
+func LuhnValid(number string) bool {
+	if len(number) < 2 {
+		return false
+	}
+	for _, c := range number {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	last := len(number) - 1
+	return int(number[last]-'0') == LuhnCheckDigit(number[:last])
+}
+
+// PANHasher вычисляет ключевой хеш номера карты для поиска и контроля уникальности
+// без расшифровки. Ключ нельзя менять: по нему посчитаны хеши всех выпущенных карт.
+type PANHasher struct {
+	key []byte
+}
+
+// MinPANHashKeyLength минимальная длина ключа хеширования в байтах. Номеров в диапазоне BIN немного,
+// поэтому по хешу с известным или коротким ключом номер карты подбирается перебором.
+const MinPANHashKeyLength = 32
+
+func NewPANHasher(key string) (*PANHasher, error) {
+	if key == "" {
+		return nil, errors.New("не задан ключ хеширования номеров карт")
+	}
+	if len(key) < MinPANHashKeyLength {
+		return nil, fmt.Errorf("ключ хеширования номеров карт короче %d байт", MinPANHashKeyLength)
+	}
+	return &PANHasher{key: []byte(key)}, nil
+}
+
+// Hash возвращает HMAC-SHA256 номера карты в hex
+func (h *PANHasher) Hash(pan string) string {
+	mac := hmac.New(sha256.New, h.key)
+	mac.Write([]byte(strings.ReplaceAll(pan, " ", "")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func randomUint(max uint64) (uint64, error) {
+	n, err := rand.Int(rand.Reader, new(big.Int).SetUint64(max))
+	if err != nil {
+		return 0, fmt.Errorf("ошибка генерации случайного числа: %v", err)
+	}
+	return n.Uint64(), nil
+}
@@ -93,6 +93,7 @@ func (h *AccountHandler) Withdraw(w http.ResponseWriter, r *http.Request) {
 	var request struct {
 		AccountID int64   `json:"account_id"`
 		Amount    float64 `json:"amount"`
+		Category  string  `json:"category,omitempty"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
@@ -105,7 +106,12 @@ func (h *AccountHandler) Withdraw(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.service.Withdraw(r.Context(), request.AccountID, request.Amount)
+	if request.Category != "" && !models.ValidateCategory(request.Category) {
+		http.Error(w, "Invalid category", http.StatusBadRequest)
+		return
+	}
+
+	err := h.service.Withdraw(r.Context(), request.AccountID, request.Amount, request.Category)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -119,6 +125,7 @@ func (h *AccountHandler) Transfer(w http.ResponseWriter, r *http.Request) {
 		FromAccountID int64   `json:"from_account_id"`
 		ToAccountID   int64   `json:"to_account_id"`
 		Amount        float64 `json:"amount"`
+		Category      string  `json:"category,omitempty"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
@@ -131,7 +138,12 @@ func (h *AccountHandler) Transfer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.service.Transfer(r.Context(), request.FromAccountID, request.ToAccountID, request.Amount)
+	if request.Category != "" && !models.ValidateCategory(request.Category) {
+		http.Error(w, "Invalid category", http.StatusBadRequest)
+		return
+	}
+
+	err := h.service.Transfer(r.Context(), request.FromAccountID, request.ToAccountID, request.Amount, request.Category)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
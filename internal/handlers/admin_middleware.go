@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"banksystem/internal/services"
+	"net/http"
+)
+
+// AdminMiddleware пропускает только пользователей с ролью администратора.
+// Должен подключаться после AuthMiddleware, который кладет user_id в контекст.
+type AdminMiddleware struct {
+	authService *services.AuthService
+	logger      Logger
+}
+
+func NewAdminMiddleware(authService *services.AuthService, logger Logger) *AdminMiddleware {
+	return &AdminMiddleware{
+		authService: authService,
+		logger:      logger,
+	}
+}
+
+func (m *AdminMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Context().Value("user_id").(int64)
+
+		isAdmin, err := m.authService.IsAdmin(r.Context(), userID)
+		if err != nil {
+			m.logger.Printf("Failed to check role of user %d: %v", userID, err)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		if !isAdmin {
+			m.logger.Printf("User %d is not an administrator", userID)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
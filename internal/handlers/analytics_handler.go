@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"banksystem/internal/models"
+	"banksystem/internal/services"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+type AnalyticsHandler struct {
+	service         *services.AnalyticsService
+	forecastService *services.ForecastService
+}
+
+func NewAnalyticsHandler(service *services.AnalyticsService, forecastService *services.ForecastService) *AnalyticsHandler {
+	return &AnalyticsHandler{
+		service:         service,
+		forecastService: forecastService,
+	}
+}
+
+func (h *AnalyticsHandler) GetCreditLoad(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int64)
+
+	report, err := h.service.GetCreditLoad(r.Context(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(report)
+}
+
+func (h *AnalyticsHandler) PredictBalance(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	accountID, err := strconv.ParseInt(query.Get("account_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid account ID", http.StatusBadRequest)
+		return
+	}
+
+	days, err := strconv.Atoi(query.Get("days"))
+	if err != nil || days <= 0 || days > models.MaxForecastDays {
+		http.Error(w, "Days must be between 1 and 365", http.StatusBadRequest)
+		return
+	}
+
+	var threshold float64
+	if value := query.Get("threshold"); value != "" {
+		threshold, err = strconv.ParseFloat(value, 64)
+		if err != nil {
+			http.Error(w, "Invalid threshold", http.StatusBadRequest)
+			return
+		}
+	}
+
+	includeRecurring := query.Get("include_recurring") == "true"
+
+	userID := r.Context().Value("user_id").(int64)
+	forecast, err := h.forecastService.PredictBalance(r.Context(), userID, accountID, days, threshold, includeRecurring)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrAccountNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, models.ErrAccessDenied):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	json.NewEncoder(w).Encode(forecast)
+}
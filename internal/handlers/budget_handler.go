@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"banksystem/internal/models"
+	"banksystem/internal/services"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+type BudgetHandler struct {
+	service *services.BudgetService
+}
+
+func NewBudgetHandler(service *services.BudgetService) *BudgetHandler {
+	return &BudgetHandler{service: service}
+}
+
+func (h *BudgetHandler) CreateBudget(w http.ResponseWriter, r *http.Request) {
+	var req models.BudgetCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value("user_id").(int64)
+	budget, err := h.service.CreateBudget(r.Context(), userID, &req)
+	if err != nil {
+		writeBudgetError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(budget.ToResponse())
+}
+
+func (h *BudgetHandler) GetBudgets(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int64)
+
+	budgets, err := h.service.GetUserBudgets(r.Context(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]models.BudgetProgressResponse, 0, len(budgets))
+	for _, budget := range budgets {
+		response = append(response, budget.ToResponse())
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *BudgetHandler) DeleteBudget(w http.ResponseWriter, r *http.Request) {
+	budgetID, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid budget ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value("user_id").(int64)
+	if err := h.service.DeleteBudget(r.Context(), userID, budgetID); err != nil {
+		writeBudgetError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func writeBudgetError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrBudgetNotFound), errors.Is(err, models.ErrAccountNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, models.ErrAccessDenied):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, models.ErrInvalidCategory), errors.Is(err, models.ErrInvalidAmount):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -1,8 +1,13 @@
 package handlers
 
 import (
+	"banksystem/internal/models"
 	"banksystem/internal/services"
+	"context"
 	"encoding/json"
+	"errors"
+	"io"
+	"net"
 	"net/http"
 	"strconv"
 )
@@ -16,30 +21,29 @@ func NewCardHandler(service *services.CardService) *CardHandler {
 }
 
 func (h *CardHandler) CreateCard(w http.ResponseWriter, r *http.Request) {
-	var request struct {
-		AccountID int64 `json:"account_id"`
+	var request models.CardIssueRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+	if err := request.Validate(); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	card, err := h.service.CreateCard(request.AccountID)
+	userID := r.Context().Value("user_id").(int64)
+	card, err := h.service.CreateCard(r.Context(), userID, &request)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeCardError(w, err)
 		return
 	}
 
-	json.NewEncoder(w).Encode(card)
+	json.NewEncoder(w).Encode(card.ToResponse())
 }
 
 func (h *CardHandler) GetUserCards(w http.ResponseWriter, r *http.Request) {
-	userID, err := strconv.ParseInt(r.URL.Query().Get("user_id"), 10, 64)
-	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
-		return
-	}
+	userID := r.Context().Value("user_id").(int64)
 
 	cards, err := h.service.GetUserCards(userID)
 	if err != nil {
@@ -47,7 +51,12 @@ func (h *CardHandler) GetUserCards(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	json.NewEncoder(w).Encode(cards)
+	response := make([]models.CardResponse, 0, len(cards))
+	for _, card := range cards {
+		response = append(response, card.ToResponse())
+	}
+
+	json.NewEncoder(w).Encode(response)
 }
 
 func (h *CardHandler) GetCard(w http.ResponseWriter, r *http.Request) {
@@ -57,16 +66,604 @@ func (h *CardHandler) GetCard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	card, err := h.service.GetCard(cardID)
+	userID := r.Context().Value("user_id").(int64)
+	card, err := h.service.GetCard(r.Context(), userID, cardID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeCardError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(card.ToResponse())
+}
+
+// RevealCard показ полных реквизитов карты владельцу: POST /api/cards/reveal?card_id=1
+func (h *CardHandler) RevealCard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	cardID, err := strconv.ParseInt(r.URL.Query().Get("card_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid card ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value("user_id").(int64)
+	details, err := h.service.RevealCard(r.Context(), userID, cardID, clientIP(r))
+	if err != nil {
+		writeCardError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(details)
+}
+
+// BlockCard блокировка карты владельцем: POST /api/cards/block?card_id=1
+func (h *CardHandler) BlockCard(w http.ResponseWriter, r *http.Request) {
+	h.handleBlock(w, r, h.service.BlockCard)
+}
+
+// UnblockCard снятие блокировки владельцем: POST /api/cards/unblock?card_id=1
+func (h *CardHandler) UnblockCard(w http.ResponseWriter, r *http.Request) {
+	h.handleUnblock(w, r, h.service.UnblockCard)
+}
+
+// AdminBlockCard блокировка карты банком: POST /api/admin/cards/block?card_id=1
+func (h *CardHandler) AdminBlockCard(w http.ResponseWriter, r *http.Request) {
+	h.handleBlock(w, r, h.service.AdminBlockCard)
+}
+
+// AdminUnblockCard снятие блокировки банком: POST /api/admin/cards/unblock?card_id=1
+func (h *CardHandler) AdminUnblockCard(w http.ResponseWriter, r *http.Request) {
+	h.handleUnblock(w, r, h.service.AdminUnblockCard)
+}
+
+// SetPIN установка или смена PIN-кода: POST /api/cards/pin?card_id=1
+func (h *CardHandler) SetPIN(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	cardID, err := strconv.ParseInt(r.URL.Query().Get("card_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid card ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CardPINRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value("user_id").(int64)
+	card, err := h.service.SetPIN(r.Context(), userID, cardID, &req)
+	if err != nil {
+		writeCardError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(card.ToResponse())
+}
+
+// GetControls настройки карты: GET /api/cards/controls?card_id=1
+func (h *CardHandler) GetControls(w http.ResponseWriter, r *http.Request) {
+	cardID, err := strconv.ParseInt(r.URL.Query().Get("card_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid card ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value("user_id").(int64)
+	controls, err := h.service.GetControls(r.Context(), userID, cardID)
+	if err != nil {
+		writeCardError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(controls.ToResponse())
+}
+
+// UpdateControls изменение настроек карты: POST /api/cards/controls/update?card_id=1
+func (h *CardHandler) UpdateControls(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	cardID, err := strconv.ParseInt(r.URL.Query().Get("card_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid card ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CardControlsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	if card == nil {
-		http.Error(w, "Card not found", http.StatusNotFound)
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	json.NewEncoder(w).Encode(card)
-} 
\ No newline at end of file
+	userID := r.Context().Value("user_id").(int64)
+	controls, err := h.service.UpdateControls(r.Context(), userID, cardID, &req)
+	if err != nil {
+		writeCardError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(controls.ToResponse())
+}
+
+// ReissueCard перевыпуск карты: POST /api/cards/reissue?card_id=1
+func (h *CardHandler) ReissueCard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	cardID, err := strconv.ParseInt(r.URL.Query().Get("card_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid card ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value("user_id").(int64)
+	card, err := h.service.ReissueCard(r.Context(), userID, cardID)
+	if err != nil {
+		writeCardError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(card.ToResponse())
+}
+
+func (h *CardHandler) handleBlock(w http.ResponseWriter, r *http.Request, block func(ctx context.Context, actorID, cardID int64, req *models.CardBlockRequest) (*models.Card, error)) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	cardID, err := strconv.ParseInt(r.URL.Query().Get("card_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid card ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CardBlockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value("user_id").(int64)
+	card, err := block(r.Context(), userID, cardID, &req)
+	if err != nil {
+		writeCardError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(card.ToResponse())
+}
+
+func (h *CardHandler) handleUnblock(w http.ResponseWriter, r *http.Request, unblock func(ctx context.Context, actorID, cardID int64) (*models.Card, error)) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	cardID, err := strconv.ParseInt(r.URL.Query().Get("card_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid card ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value("user_id").(int64)
+	card, err := unblock(r.Context(), userID, cardID)
+	if err != nil {
+		writeCardError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(card.ToResponse())
+}
+
+// Pay оплата картой владельцем: POST /api/cards/pay?card_id=1
+func (h *CardHandler) Pay(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	cardID, err := strconv.ParseInt(r.URL.Query().Get("card_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid card ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CardPaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value("user_id").(int64)
+	transaction, confirmation, err := h.service.Pay(r.Context(), userID, cardID, &req)
+	if err != nil {
+		writeCardError(w, err)
+		return
+	}
+
+	writePaymentResult(w, transaction, confirmation)
+}
+
+// MerchantPay списание продавцом по реквизитам карты: POST /api/cards/merchant/pay
+func (h *CardHandler) MerchantPay(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req models.MerchantPaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value("user_id").(int64)
+	transaction, confirmation, err := h.service.PayByCardData(r.Context(), userID, &req)
+	if err != nil {
+		writeCardError(w, err)
+		return
+	}
+
+	writePaymentResult(w, transaction, confirmation)
+}
+
+// ConfirmPayment подтверждение онлайн-платежа кодом из письма: POST /api/cards/pay/confirm?confirmation_id=1
+func (h *CardHandler) ConfirmPayment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	confirmationID, err := strconv.ParseInt(r.URL.Query().Get("confirmation_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid confirmation ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CardPaymentConfirmRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value("user_id").(int64)
+	transaction, err := h.service.ConfirmPayment(r.Context(), userID, confirmationID, &req)
+	if err != nil {
+		writeCardError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(transaction.ToResponse())
+}
+
+// FindRecipient получатель перевода по номеру карты: POST /api/cards/transfer/recipient
+func (h *CardHandler) FindRecipient(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req models.CardRecipientRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	recipient, err := h.service.FindRecipient(r.Context(), req.CardNumber)
+	if err != nil {
+		writeCardError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(recipient)
+}
+
+// TransferToCard перевод на карту банка по номеру: POST /api/cards/transfer?card_id=1
+func (h *CardHandler) TransferToCard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	cardID, err := strconv.ParseInt(r.URL.Query().Get("card_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid card ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CardTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value("user_id").(int64)
+	transfer, err := h.service.TransferToCard(r.Context(), userID, cardID, &req)
+	if err != nil {
+		writeCardError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(transfer.ToResponse())
+}
+
+// CreateMandate оформление продавцом регулярных списаний по реквизитам карты: POST /api/cards/mandates/create
+func (h *CardHandler) CreateMandate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req models.CardMandateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value("user_id").(int64)
+	mandate, err := h.service.CreateMandate(r.Context(), userID, &req)
+	if err != nil {
+		writeCardError(w, err)
+		return
+	}
+
+	// Разрешение, ожидающее кода владельца карты, возвращается с кодом 202
+	if mandate.Status == models.MandateStatusPending {
+		w.WriteHeader(http.StatusAccepted)
+	} else {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(mandate.ToResponse())
+}
+
+// ConfirmMandate подтверждение разрешения кодом владельца карты: POST /api/cards/mandates/confirm?mandate_id=1
+func (h *CardHandler) ConfirmMandate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	mandateID, err := strconv.ParseInt(r.URL.Query().Get("mandate_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid mandate ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CardPaymentConfirmRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value("user_id").(int64)
+	mandate, err := h.service.ConfirmMandate(r.Context(), userID, mandateID, &req)
+	if err != nil {
+		writeCardError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(mandate.ToResponse())
+}
+
+// ChargeMandate списание продавцом по разрешению: POST /api/cards/mandates/charge?mandate_id=1
+func (h *CardHandler) ChargeMandate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	mandateID, err := strconv.ParseInt(r.URL.Query().Get("mandate_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid mandate ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.MandateChargeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value("user_id").(int64)
+	transaction, err := h.service.ChargeMandate(r.Context(), userID, mandateID, &req)
+	if err != nil {
+		writeCardError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(transaction.ToResponse())
+}
+
+// GetMandates регулярные списания по карте: GET /api/cards/mandates?card_id=1
+func (h *CardHandler) GetMandates(w http.ResponseWriter, r *http.Request) {
+	cardID, err := strconv.ParseInt(r.URL.Query().Get("card_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid card ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value("user_id").(int64)
+	mandates, err := h.service.GetMandates(r.Context(), userID, cardID)
+	if err != nil {
+		writeCardError(w, err)
+		return
+	}
+
+	response := make([]*models.CardMandateResponse, 0, len(mandates))
+	for _, mandate := range mandates {
+		response = append(response, mandate.ToResponse())
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// PauseMandate приостановка регулярных списаний: POST /api/cards/mandates/pause?mandate_id=1
+func (h *CardHandler) PauseMandate(w http.ResponseWriter, r *http.Request) {
+	h.handleMandateStatus(w, r, h.service.PauseMandate)
+}
+
+// ResumeMandate возобновление регулярных списаний: POST /api/cards/mandates/resume?mandate_id=1
+func (h *CardHandler) ResumeMandate(w http.ResponseWriter, r *http.Request) {
+	h.handleMandateStatus(w, r, h.service.ResumeMandate)
+}
+
+// RevokeMandate отзыв разрешения на регулярные списания: POST /api/cards/mandates/revoke?mandate_id=1
+func (h *CardHandler) RevokeMandate(w http.ResponseWriter, r *http.Request) {
+	h.handleMandateStatus(w, r, h.service.RevokeMandate)
+}
+
+func (h *CardHandler) handleMandateStatus(w http.ResponseWriter, r *http.Request, change func(ctx context.Context, userID, mandateID int64) (*models.CardMandate, error)) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	mandateID, err := strconv.ParseInt(r.URL.Query().Get("mandate_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid mandate ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value("user_id").(int64)
+	mandate, err := change(r.Context(), userID, mandateID)
+	if err != nil {
+		writeCardError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(mandate.ToResponse())
+}
+
+// writePaymentResult проведенный платеж возвращается с кодом 200, ожидающий подтверждения — с кодом 202
+func writePaymentResult(w http.ResponseWriter, transaction *models.Transaction, confirmation *models.CardPaymentConfirmation) {
+	if confirmation != nil {
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(confirmation.ToResponse())
+		return
+	}
+
+	json.NewEncoder(w).Encode(transaction.ToResponse())
+}
+
+func writeCardError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrCardNotFound), errors.Is(err, models.ErrAccountNotFound), errors.Is(err, models.ErrConfirmationNotFound),
+		errors.Is(err, models.ErrMandateNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, models.ErrAccessDenied), errors.Is(err, models.ErrCardBlockedByBank):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, models.ErrCardStatusChange), errors.Is(err, models.ErrCardAlreadyReissued), errors.Is(err, models.ErrVirtualCard),
+		errors.Is(err, models.ErrConfirmationNotPending), errors.Is(err, models.ErrMandateStatusChange):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, models.ErrInvalidCVV), errors.Is(err, models.ErrInvalidMerchant), errors.Is(err, models.ErrInvalidPIN), errors.Is(err, models.ErrInvalidOTP),
+		errors.Is(err, models.ErrInvalidAccountID):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, models.ErrCardNotActive),
+		errors.Is(err, models.ErrMerchantNotAllowed),
+		errors.Is(err, models.ErrEcomDisabled),
+		errors.Is(err, models.ErrContactlessDisabled),
+		errors.Is(err, models.ErrForeignDisabled),
+		errors.Is(err, models.ErrMCCBlocked),
+		errors.Is(err, models.ErrCardDailyLimit),
+		errors.Is(err, models.ErrCardMonthlyLimit),
+		errors.Is(err, models.ErrPINRequired),
+		errors.Is(err, models.ErrPINNotSet),
+		errors.Is(err, models.ErrWrongPIN),
+		errors.Is(err, models.ErrCardPINBlocked),
+		errors.Is(err, models.ErrCardCVVBlocked),
+		errors.Is(err, models.ErrOTPRequired),
+		errors.Is(err, models.ErrWrongOTP),
+		errors.Is(err, models.ErrOTPAttemptsExceeded),
+		errors.Is(err, models.ErrConfirmationExpired),
+		errors.Is(err, models.ErrMandatePaused),
+		errors.Is(err, models.ErrMandateRevoked),
+		errors.Is(err, models.ErrMandateAmountExceeded),
+		errors.Is(err, models.ErrMandateTooFrequent),
+		errors.Is(err, models.ErrMandateNotConfirmed),
+		errors.Is(err, models.ErrCardExpired),
+		errors.Is(err, models.ErrCardTampered),
+		errors.Is(err, models.ErrCardLimitExceeded),
+		errors.Is(err, models.ErrInsufficientFunds):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// clientIP возвращает адрес клиента без порта
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
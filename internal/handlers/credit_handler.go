@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"banksystem/internal/models"
 	"banksystem/internal/services"
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 )
@@ -15,27 +18,133 @@ func NewCreditHandler(service *services.CreditService) *CreditHandler {
 	return &CreditHandler{service: service}
 }
 
-func (h *CreditHandler) CreateCredit(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		AccountID int64   `json:"account_id"`
-		Amount    float64 `json:"amount"`
-		Term      int     `json:"term"` // срок в месяцах
-		Rate      float64 `json:"rate"` // годовая процентная ставка
+// SubmitApplication подача заявки на кредит: POST /api/credits/applications/create
+func (h *CreditHandler) SubmitApplication(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
 
+	var req models.CreditApplicationRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	userID := r.Context().Value("user_id").(int64)
-	credit, err := h.service.CreateCredit(r.Context(), userID, req.AccountID, req.Amount, req.Term, req.Rate)
+	application, err := h.service.SubmitApplication(r.Context(), userID, &req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeCreditError(w, err)
 		return
 	}
 
-	json.NewEncoder(w).Encode(credit)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(application.ToResponse())
+}
+
+// GetApplications заявки пользователя: GET /api/credits/applications
+func (h *CreditHandler) GetApplications(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int64)
+	applications, err := h.service.GetApplications(r.Context(), userID)
+	if err != nil {
+		writeCreditError(w, err)
+		return
+	}
+
+	response := make([]*models.CreditApplicationResponse, 0, len(applications))
+	for _, application := range applications {
+		response = append(response, application.ToResponse())
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetApplication заявка пользователя: GET /api/credits/applications/get?id=1
+func (h *CreditHandler) GetApplication(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid application ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value("user_id").(int64)
+	application, err := h.service.GetApplication(r.Context(), userID, id)
+	if err != nil {
+		writeCreditError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(application.ToResponse())
+}
+
+// AdminGetApplications заявки для рассмотрения: GET /api/admin/credits/applications?status=under_review
+func (h *CreditHandler) AdminGetApplications(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = models.ApplicationStatusUnderReview
+	}
+
+	applications, err := h.service.GetApplicationsByStatus(r.Context(), status)
+	if err != nil {
+		writeCreditError(w, err)
+		return
+	}
+
+	response := make([]*models.CreditApplicationAdminResponse, 0, len(applications))
+	for _, application := range applications {
+		response = append(response, application.ToAdminResponse())
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// ApproveApplication одобрение заявки и выдача кредита: POST /api/admin/credits/applications/approve?id=1
+func (h *CreditHandler) ApproveApplication(w http.ResponseWriter, r *http.Request) {
+	h.handleDecision(w, r, models.ApplicationStatusApproved, h.service.ApproveApplication)
+}
+
+// RejectApplication отказ по заявке: POST /api/admin/credits/applications/reject?id=1
+func (h *CreditHandler) RejectApplication(w http.ResponseWriter, r *http.Request) {
+	h.handleDecision(w, r, models.ApplicationStatusRejected, h.service.RejectApplication)
+}
+
+func (h *CreditHandler) handleDecision(w http.ResponseWriter, r *http.Request, status string,
+	decide func(ctx context.Context, adminID, applicationID int64, req *models.CreditDecisionRequest) (*models.CreditApplication, error)) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid application ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CreditDecisionRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if err := req.Validate(status); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	adminID := r.Context().Value("user_id").(int64)
+	application, err := decide(r.Context(), adminID, id, &req)
+	if err != nil {
+		writeCreditError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(application.ToAdminResponse())
 }
 
 func (h *CreditHandler) GetUserCredits(w http.ResponseWriter, r *http.Request) {
@@ -85,4 +194,55 @@ func (h *CreditHandler) GetPaymentSchedule(w http.ResponseWriter, r *http.Reques
 	}
 
 	json.NewEncoder(w).Encode(schedule)
-} 
\ No newline at end of file
+}
+
+func (h *CreditHandler) Prepay(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid credit ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CreditPrepayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value("user_id").(int64)
+	prepayment, err := h.service.Prepay(r.Context(), userID, id, &req)
+	if err != nil {
+		writeCreditError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(prepayment.ToResponse())
+}
+
+func writeCreditError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrCreditNotFound), errors.Is(err, models.ErrApplicationNotFound), errors.Is(err, models.ErrAccountNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, models.ErrAccessDenied):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, models.ErrCreditClosed), errors.Is(err, models.ErrCreditPaymentDue), errors.Is(err, models.ErrApplicationStatus):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, models.ErrInvalidTerm), errors.Is(err, models.ErrInvalidScheduleType):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, models.ErrCreditAmountLimit), errors.Is(err, models.ErrInsufficientFunds):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, models.ErrKeyRateUnavailable):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"banksystem/internal/models"
 	"banksystem/internal/services"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -51,7 +53,14 @@ func (h *CreditPaymentHandler) ProcessPayment(w http.ResponseWriter, r *http.Req
 
 	err = h.service.ProcessPayment(r.Context(), paymentID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		switch {
+		case errors.Is(err, models.ErrPaymentNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, models.ErrInsufficientFunds):
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
 		return
 	}
 
@@ -74,6 +83,33 @@ func (h *CreditPaymentHandler) GetPaymentsByCreditID(w http.ResponseWriter, r *h
 	json.NewEncoder(w).Encode(payments)
 }
 
+func (h *CreditPaymentHandler) GetPenalties(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int64)
+
+	creditID, err := strconv.ParseInt(r.URL.Query().Get("credit_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid credit ID", http.StatusBadRequest)
+		return
+	}
+
+	penalties, err := h.service.GetPenalties(r.Context(), userID, creditID)
+	if err != nil {
+		if errors.Is(err, models.ErrCreditNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]*models.CreditPenaltyResponse, 0, len(penalties))
+	for _, penalty := range penalties {
+		response = append(response, penalty.ToResponse())
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
 func (h *CreditPaymentHandler) GetPendingPayments(w http.ResponseWriter, r *http.Request) {
 	payments, err := h.service.GetPendingPayments(r.Context())
 	if err != nil {
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"banksystem/internal/models"
+	"banksystem/internal/services"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+type KeyRateHandler struct {
+	service *services.CentralBankService
+}
+
+func NewKeyRateHandler(service *services.CentralBankService) *KeyRateHandler {
+	return &KeyRateHandler{service: service}
+}
+
+// GetKeyRateHistory возвращает ключевую ставку ЦБ за период from..to (YYYY-MM-DD).
+// По умолчанию to — сегодня, from — за 30 дней до to.
+func (h *KeyRateHandler) GetKeyRateHistory(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	now := time.Now()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if value := query.Get("to"); value != "" {
+		date, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			http.Error(w, "Invalid to date", http.StatusBadRequest)
+			return
+		}
+		to = date
+	}
+
+	from := to.AddDate(0, 0, -30)
+	if value := query.Get("from"); value != "" {
+		date, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			http.Error(w, "Invalid from date", http.StatusBadRequest)
+			return
+		}
+		from = date
+	}
+
+	rates, err := h.service.GetKeyRateHistory(r.Context(), from, to)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrKeyRatePeriod):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, models.ErrKeyRateUnavailable):
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response := make([]*models.KeyRateResponse, 0, len(rates))
+	for _, rate := range rates {
+		response = append(response, rate.ToResponse())
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
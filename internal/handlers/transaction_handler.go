@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"banksystem/internal/models"
+	"banksystem/internal/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// Максимальный размер тела запроса на загрузку вложения (лимит на сам файл проверяет сервис)
+const maxUploadRequestSize = 20 << 20
+
+type TransactionHandler struct {
+	service *services.TransactionService
+}
+
+func NewTransactionHandler(service *services.TransactionService) *TransactionHandler {
+	return &TransactionHandler{service: service}
+}
+
+func (h *TransactionHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	accountID, err := strconv.ParseInt(r.URL.Query().Get("account_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid account ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value("user_id").(int64)
+	transactions, err := h.service.GetHistory(r.Context(), userID, accountID, r.URL.Query().Get("tag"))
+	if err != nil {
+		writeTransactionError(w, err)
+		return
+	}
+
+	response := make([]models.TransactionResponse, 0, len(transactions))
+	for _, t := range transactions {
+		response = append(response, t.ToResponse())
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *TransactionHandler) Search(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	text := query.Get("q")
+	if text == "" {
+		http.Error(w, "Query is required", http.StatusBadRequest)
+		return
+	}
+
+	page, _ := strconv.Atoi(query.Get("page"))
+	pageSize, _ := strconv.Atoi(query.Get("page_size"))
+
+	userID := r.Context().Value("user_id").(int64)
+	result, err := h.service.Search(r.Context(), userID, text, page, pageSize)
+	if err != nil {
+		writeTransactionError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *TransactionHandler) SetNote(w http.ResponseWriter, r *http.Request) {
+	var req models.TransactionNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value("user_id").(int64)
+	if err := h.service.SetNote(r.Context(), userID, &req); err != nil {
+		writeTransactionError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *TransactionHandler) SetTags(w http.ResponseWriter, r *http.Request) {
+	var req models.TransactionTagsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value("user_id").(int64)
+	if err := h.service.SetTags(r.Context(), userID, &req); err != nil {
+		writeTransactionError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *TransactionHandler) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	transactionID, err := strconv.ParseInt(r.URL.Query().Get("transaction_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadRequestSize)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "File is required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	userID := r.Context().Value("user_id").(int64)
+	attachment, err := h.service.AddAttachment(r.Context(), userID, transactionID, header.Filename, file)
+	if err != nil {
+		writeTransactionError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(attachment)
+}
+
+func (h *TransactionHandler) GetAttachments(w http.ResponseWriter, r *http.Request) {
+	transactionID, err := strconv.ParseInt(r.URL.Query().Get("transaction_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value("user_id").(int64)
+	attachments, err := h.service.GetAttachments(r.Context(), userID, transactionID)
+	if err != nil {
+		writeTransactionError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(attachments)
+}
+
+func (h *TransactionHandler) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	attachmentID, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid attachment ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value("user_id").(int64)
+	attachment, content, err := h.service.OpenAttachment(r.Context(), userID, attachmentID)
+	if err != nil {
+		writeTransactionError(w, err)
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", attachment.FileName))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	io.Copy(w, content)
+}
+
+func (h *TransactionHandler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	attachmentID, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid attachment ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value("user_id").(int64)
+	if err := h.service.DeleteAttachment(r.Context(), userID, attachmentID); err != nil {
+		writeTransactionError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func writeTransactionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrTransactionNotFound),
+		errors.Is(err, models.ErrAccountNotFound),
+		errors.Is(err, models.ErrAttachmentNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, models.ErrAccessDenied):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, models.ErrAttachmentTooLarge):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, models.ErrInvalidSearchQuery):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, models.ErrInvalidAttachmentType):
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
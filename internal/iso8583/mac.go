@@ -0,0 +1,49 @@
+package iso8583
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// MinMACKeyLength минимальная длина ключа MAC терминала в байтах
+const MinMACKeyLength = 16
+
+// ComputeMAC вычисляет MAC сообщения для поля 64: первые 8 байт HMAC-SHA256 от сообщения,
+// упакованного без поля 64, в hex
+func ComputeMAC(message *Message, key []byte) (string, error) {
+	unsigned := NewMessage(message.MTI)
+	for field, value := range message.Fields {
+		if field != FieldMAC {
+			unsigned.Fields[field] = value
+		}
+	}
+	data, err := unsigned.Pack()
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return strings.ToUpper(hex.EncodeToString(mac.Sum(nil)[:8])), nil
+}
+
+// Sign записывает MAC сообщения в поле 64
+func (m *Message) Sign(key []byte) error {
+	mac, err := ComputeMAC(m, key)
+	if err != nil {
+		return err
+	}
+	m.Set(FieldMAC, mac)
+	return nil
+}
+
+// VerifyMAC проверяет MAC из поля 64
+func (m *Message) VerifyMAC(key []byte) bool {
+	expected, err := ComputeMAC(m, key)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal([]byte(strings.ToUpper(m.Get(FieldMAC))), []byte(expected))
+}
@@ -0,0 +1,308 @@
+// Package iso8583 реализует подмножество ISO 8583:1987 в ASCII-кодировке, достаточное для
+// авторизации (0100/0110), финансовых сообщений (0200/0210) и отмен (0400/0410):
+// MTI из 4 цифр, битовые карты в hex, поля фиксированной длины и LLVAR/LLLVAR.
+package iso8583
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Типы сообщений
+const (
+	MTIAuthorizationRequest  = "0100"
+	MTIAuthorizationResponse = "0110"
+	MTIFinancialRequest      = "0200"
+	MTIFinancialResponse     = "0210"
+	MTIReversalRequest       = "0400"
+	MTIReversalResponse      = "0410"
+)
+
+// Номера используемых полей
+const (
+	FieldPAN                  = 2
+	FieldProcessingCode       = 3
+	FieldAmount               = 4
+	FieldTransmissionDateTime = 7
+	FieldSTAN                 = 11
+	FieldLocalTime            = 12
+	FieldLocalDate            = 13
+	FieldExpiryDate           = 14
+	FieldMCC                  = 18
+	FieldPOSEntryMode         = 22
+	FieldAcquirerID           = 32
+	FieldRRN                  = 37
+	FieldAuthCode             = 38
+	FieldResponseCode         = 39
+	FieldTerminalID           = 41
+	FieldMerchantID           = 42
+	FieldCardAcceptor         = 43
+	FieldAdditionalData       = 48
+	FieldCurrency             = 49
+	FieldPINBlock             = 52
+	FieldMAC                  = 64
+	FieldOriginalData         = 90
+)
+
+// Коды ответа (поле 39)
+const (
+	ResponseApproved           = "00"
+	ResponseDoNotHonor         = "05"
+	ResponseInvalidTransaction = "12"
+	ResponseInvalidAmount      = "13"
+	ResponseInvalidCard        = "14"
+	ResponseOriginalNotFound   = "25"
+	ResponseFormatError        = "30"
+	ResponseInsufficientFunds  = "51"
+	ResponseExpiredCard        = "54"
+	ResponseIncorrectPIN       = "55"
+	ResponseNotPermittedCard   = "57"
+	ResponseNotPermittedTerm   = "58"
+	ResponseExceedsLimit       = "61"
+	ResponseRestrictedCard     = "62"
+	ResponseSecurityViolation  = "63"
+	ResponseAuthRequired       = "65"
+	ResponsePINTriesExceeded   = "75"
+	ResponseDuplicate          = "94"
+	ResponseSystemMalfunction  = "96"
+)
+
+var ErrFormat = errors.New("iso8583: неверный формат сообщения")
+
+type fieldKind int
+
+const (
+	fixed fieldKind = iota
+	llvar
+	lllvar
+)
+
+type fieldSpec struct {
+	kind   fieldKind
+	length int // для фиксированных полей — длина, для переменных — максимальная длина
+	// Только цифры
+	numeric bool
+}
+
+var fieldSpecs = map[int]fieldSpec{
+	FieldPAN:                  {kind: llvar, length: 19, numeric: true},
+	FieldProcessingCode:       {kind: fixed, length: 6, numeric: true},
+	FieldAmount:               {kind: fixed, length: 12, numeric: true},
+	FieldTransmissionDateTime: {kind: fixed, length: 10, numeric: true},
+	FieldSTAN:                 {kind: fixed, length: 6, numeric: true},
+	FieldLocalTime:            {kind: fixed, length: 6, numeric: true},
+	FieldLocalDate:            {kind: fixed, length: 4, numeric: true},
+	FieldExpiryDate:           {kind: fixed, length: 4, numeric: true},
+	FieldMCC:                  {kind: fixed, length: 4, numeric: true},
+	FieldPOSEntryMode:         {kind: fixed, length: 3, numeric: true},
+	FieldAcquirerID:           {kind: llvar, length: 11, numeric: true},
+	FieldRRN:                  {kind: fixed, length: 12},
+	FieldAuthCode:             {kind: fixed, length: 6},
+	FieldResponseCode:         {kind: fixed, length: 2},
+	FieldTerminalID:           {kind: fixed, length: 8},
+	FieldMerchantID:           {kind: fixed, length: 15},
+	FieldCardAcceptor:         {kind: fixed, length: 40},
+	FieldAdditionalData:       {kind: lllvar, length: 999},
+	FieldCurrency:             {kind: fixed, length: 3, numeric: true},
+	FieldPINBlock:             {kind: fixed, length: 16},
+	FieldMAC:                  {kind: fixed, length: 16},
+	FieldOriginalData:         {kind: fixed, length: 42, numeric: true},
+}
+
+// Message сообщение ISO 8583: тип и значения полей в виде строк
+type Message struct {
+	MTI    string
+	Fields map[int]string
+}
+
+func NewMessage(mti string) *Message {
+	return &Message{MTI: mti, Fields: make(map[int]string)}
+}
+
+// Set задает значение поля. Значения фиксированных полей дополняются до нужной длины:
+// числовые — нулями слева, текстовые — пробелами справа.
+func (m *Message) Set(field int, value string) *Message {
+	if spec, ok := fieldSpecs[field]; ok && spec.kind == fixed && len(value) < spec.length {
+		if spec.numeric {
+			value = strings.Repeat("0", spec.length-len(value)) + value
+		} else {
+			value += strings.Repeat(" ", spec.length-len(value))
+		}
+	}
+	m.Fields[field] = value
+	return m
+}
+
+// Get возвращает значение поля без завершающих пробелов
+func (m *Message) Get(field int) string {
+	return strings.TrimRight(m.Fields[field], " ")
+}
+
+func (m *Message) Has(field int) bool {
+	_, ok := m.Fields[field]
+	return ok
+}
+
+// Response создает ответ на запрос: MTI + 10 и поля, которые возвращаются терминалу без изменений
+func (m *Message) Response() *Message {
+	mti, _ := strconv.Atoi(m.MTI)
+	response := NewMessage(fmt.Sprintf("%04d", mti+10))
+	for _, field := range []int{
+		FieldProcessingCode, FieldAmount, FieldTransmissionDateTime, FieldSTAN, FieldLocalTime, FieldLocalDate,
+		FieldAcquirerID, FieldRRN, FieldTerminalID, FieldMerchantID, FieldCurrency,
+	} {
+		if value, ok := m.Fields[field]; ok {
+			response.Fields[field] = value
+		}
+	}
+	return response
+}
+
+// Pack кодирует сообщение: MTI, первичная (и при необходимости вторичная) битовая карта в hex, поля по возрастанию номера
+func (m *Message) Pack() ([]byte, error) {
+	if len(m.MTI) != 4 || !isNumeric(m.MTI) {
+		return nil, fmt.Errorf("iso8583: неверный MTI %q", m.MTI)
+	}
+
+	fields := make([]int, 0, len(m.Fields))
+	for field := range m.Fields {
+		if field < 2 || field > 128 {
+			return nil, fmt.Errorf("iso8583: неверный номер поля %d", field)
+		}
+		fields = append(fields, field)
+	}
+	sort.Ints(fields)
+
+	bitmap := make([]byte, 8)
+	if len(fields) > 0 && fields[len(fields)-1] > 64 {
+		bitmap = make([]byte, 16)
+		bitmap[0] |= 0x80
+	}
+
+	var body strings.Builder
+	for _, field := range fields {
+		bitmap[(field-1)/8] |= 0x80 >> uint((field-1)%8)
+
+		encoded, err := encodeField(field, m.Fields[field])
+		if err != nil {
+			return nil, err
+		}
+		body.WriteString(encoded)
+	}
+
+	return []byte(m.MTI + strings.ToUpper(hex.EncodeToString(bitmap)) + body.String()), nil
+}
+
+// Unpack разбирает сообщение, закодированное Pack
+func Unpack(data []byte) (*Message, error) {
+	raw := string(data)
+	if len(raw) < 20 || !isNumeric(raw[:4]) {
+		return nil, ErrFormat
+	}
+
+	message := NewMessage(raw[:4])
+	bitmap, err := hex.DecodeString(raw[4:20])
+	if err != nil {
+		return nil, ErrFormat
+	}
+	pos := 20
+	if bitmap[0]&0x80 != 0 {
+		if len(raw) < 36 {
+			return nil, ErrFormat
+		}
+		secondary, err := hex.DecodeString(raw[20:36])
+		if err != nil {
+			return nil, ErrFormat
+		}
+		bitmap = append(bitmap, secondary...)
+		pos = 36
+	}
+
+	for field := 2; field <= len(bitmap)*8; field++ {
+		if bitmap[(field-1)/8]&(0x80>>uint((field-1)%8)) == 0 {
+			continue
+		}
+
+		spec, ok := fieldSpecs[field]
+		if !ok {
+			return nil, fmt.Errorf("iso8583: неподдерживаемое поле %d", field)
+		}
+
+		length := spec.length
+		if spec.kind != fixed {
+			prefix := 2
+			if spec.kind == lllvar {
+				prefix = 3
+			}
+			if pos+prefix > len(raw) {
+				return nil, ErrFormat
+			}
+			// Atoi принимает знак, поэтому префикс длины сначала проверяется на цифры
+			if !isNumeric(raw[pos : pos+prefix]) {
+				return nil, ErrFormat
+			}
+			length, err = strconv.Atoi(raw[pos : pos+prefix])
+			if err != nil || length < 0 || length > spec.length {
+				return nil, ErrFormat
+			}
+			pos += prefix
+		}
+
+		if pos+length > len(raw) {
+			return nil, ErrFormat
+		}
+		value := raw[pos : pos+length]
+		if spec.numeric && !isNumeric(value) {
+			return nil, fmt.Errorf("iso8583: поле %d должно быть числовым", field)
+		}
+		message.Fields[field] = value
+		pos += length
+	}
+
+	if pos != len(raw) {
+		return nil, ErrFormat
+	}
+
+	return message, nil
+}
+
+func encodeField(field int, value string) (string, error) {
+	spec, ok := fieldSpecs[field]
+	if !ok {
+		return "", fmt.Errorf("iso8583: неподдерживаемое поле %d", field)
+	}
+	if spec.numeric && !isNumeric(value) {
+		return "", fmt.Errorf("iso8583: поле %d должно быть числовым", field)
+	}
+
+	switch spec.kind {
+	case fixed:
+		if len(value) != spec.length {
+			return "", fmt.Errorf("iso8583: длина поля %d должна быть %d", field, spec.length)
+		}
+		return value, nil
+	case llvar:
+		if len(value) > spec.length {
+			return "", fmt.Errorf("iso8583: поле %d длиннее %d", field, spec.length)
+		}
+		return fmt.Sprintf("%02d%s", len(value), value), nil
+	default:
+		if len(value) > spec.length {
+			return "", fmt.Errorf("iso8583: поле %d длиннее %d", field, spec.length)
+		}
+		return fmt.Sprintf("%03d%s", len(value), value), nil
+	}
+}
+
+func isNumeric(value string) bool {
+	for _, c := range value {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
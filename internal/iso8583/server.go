@@ -0,0 +1,375 @@
+package iso8583
+
+import (
+	"banksystem/internal/models"
+	"banksystem/internal/services"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+const (
+	// idleTimeout соединение закрывается, если терминал молчит дольше
+	idleTimeout = 5 * time.Minute
+	// requestTimeout время на обработку одного сообщения
+	requestTimeout = 30 * time.Second
+	// currencyRUB код валюты ISO 4217, единственной принимаемой терминалами
+	currencyRUB = "643"
+)
+
+// Logger интерфейс для логирования
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// Server принимает сообщения ISO 8583 от терминалов по TCP и передает их в CardService:
+// 0100 — блокировка средств, 0200 — списание (по блокировке или покупка), 0400 — отмена.
+// Каждое сообщение передается в кадре с двухбайтовой длиной.
+type Server struct {
+	cards  *services.CardService
+	logger Logger
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+func NewServer(cards *services.CardService, logger Logger) *Server {
+	return &Server{
+		cards:  cards,
+		logger: logger,
+		conns:  make(map[net.Conn]struct{}),
+	}
+}
+
+// ListenAndServe слушает addr и обслуживает терминалы до вызова Close
+func (s *Server) ListenAndServe(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(listener)
+}
+
+// Serve обслуживает соединения, принятые listener
+func (s *Server) Serve(listener net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		listener.Close()
+		return net.ErrClosed
+	}
+	s.listener = listener
+	s.mu.Unlock()
+
+	s.logger.Printf("ISO 8583 listener started on %s", listener.Addr())
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go s.serveConn(conn)
+	}
+}
+
+// Close прекращает прием соединений, закрывает открытые и дожидается завершения обработки
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer func() {
+		// Паника при обработке сообщения закрывает только это соединение, а не весь сервер
+		if r := recover(); r != nil {
+			s.logger.Printf("ISO 8583 connection %s panicked: %v\n%s", conn.RemoteAddr(), r, debug.Stack())
+		}
+		conn.Close()
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		s.wg.Done()
+	}()
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(idleTimeout))
+		request, err := ReadMessage(conn)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				s.logger.Printf("ISO 8583 read from %s failed: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		response := s.Handle(ctx, request)
+		cancel()
+
+		if err := WriteMessage(conn, response); err != nil {
+			s.logger.Printf("ISO 8583 write to %s failed: %v", conn.RemoteAddr(), err)
+			return
+		}
+	}
+}
+
+// Handle проверяет MAC сообщения, обрабатывает его и возвращает ответ
+func (s *Server) Handle(ctx context.Context, request *Message) *Message {
+	response := request.Response()
+
+	var hold *models.CardHold
+	err := s.verifyMAC(ctx, request)
+	if err == nil {
+		hold, err = s.dispatch(ctx, request)
+	}
+
+	code := responseCode(err)
+	response.Set(FieldResponseCode, code)
+	if err == nil && hold != nil && request.MTI != MTIReversalRequest {
+		response.Set(FieldAuthCode, hold.AuthCode)
+	}
+
+	s.logger.Printf("ISO 8583 %s terminal=%s rrn=%s stan=%s pan=%s response=%s",
+		request.MTI, request.Get(FieldTerminalID), request.Get(FieldRRN), request.Get(FieldSTAN),
+		maskPAN(request.Get(FieldPAN)), code)
+	if code == ResponseSystemMalfunction {
+		s.logger.Printf("ISO 8583 %s processing failed: %v", request.MTI, err)
+	}
+
+	return response
+}
+
+// verifyMAC проверяет поле 64 ключом терминала из поля 41. Без этой проверки любой, кто знает
+// идентификатор терминала и RRN, мог бы отменить чужую операцию.
+func (s *Server) verifyMAC(ctx context.Context, request *Message) error {
+	terminalID := request.Get(FieldTerminalID)
+	if terminalID == "" || !request.Has(FieldMAC) {
+		return errInvalidMAC
+	}
+	key, err := s.cards.TerminalMACKey(ctx, terminalID)
+	if err != nil {
+		return err
+	}
+	if len(key) < MinMACKeyLength {
+		return fmt.Errorf("iso8583: ключ MAC терминала %s короче %d байт", terminalID, MinMACKeyLength)
+	}
+	if !request.VerifyMAC(key) {
+		return errInvalidMAC
+	}
+	return nil
+}
+
+// dispatch передает операцию в CardService по типу сообщения
+func (s *Server) dispatch(ctx context.Context, request *Message) (*models.CardHold, error) {
+	var hold *models.CardHold
+	var err error
+	switch request.MTI {
+	case MTIAuthorizationRequest, MTIFinancialRequest:
+		var req *models.TerminalPaymentRequest
+		req, err = parsePaymentRequest(request)
+		if err == nil && request.MTI == MTIAuthorizationRequest {
+			hold, err = s.cards.AuthorizeHold(ctx, req)
+		} else if err == nil {
+			hold, err = s.cards.Purchase(ctx, req)
+		}
+	case MTIReversalRequest:
+		var req *models.TerminalReversalRequest
+		req, err = parseReversalRequest(request)
+		if err == nil {
+			hold, err = s.cards.Reverse(ctx, req)
+		}
+	default:
+		err = errUnsupportedMTI
+	}
+	return hold, err
+}
+
+var (
+	errUnsupportedMTI = errors.New("iso8583: неподдерживаемый тип сообщения")
+	errInvalidMAC     = errors.New("iso8583: неверный MAC сообщения")
+)
+
+// parsePaymentRequest извлекает из 0100/0200 данные операции. CVV2 передается в поле 48,
+// PIN — блоком ISO 9564 format 0 в поле 52; ни то, ни другое не логируется.
+func parsePaymentRequest(request *Message) (*models.TerminalPaymentRequest, error) {
+	for _, field := range []int{FieldPAN, FieldAmount, FieldExpiryDate, FieldRRN, FieldTerminalID} {
+		if !request.Has(field) {
+			return nil, ErrFormat
+		}
+	}
+	if currency := request.Get(FieldCurrency); currency != "" && currency != currencyRUB {
+		return nil, models.ErrInvalidAmount
+	}
+
+	amount, err := ParseAmount(request.Get(FieldAmount))
+	if err != nil {
+		return nil, err
+	}
+
+	channel, err := entryModeChannel(request.Get(FieldPOSEntryMode))
+	if err != nil {
+		return nil, err
+	}
+
+	pan := request.Get(FieldPAN)
+	expiry := request.Get(FieldExpiryDate) // YYMM
+	merchantName, _, country := ParseCardAcceptor(request.Fields[FieldCardAcceptor])
+
+	req := &models.TerminalPaymentRequest{
+		TerminalID:   request.Get(FieldTerminalID),
+		RRN:          request.Get(FieldRRN),
+		STAN:         request.Get(FieldSTAN),
+		CardNumber:   pan,
+		ExpiryDate:   expiry[2:] + "/" + expiry[:2],
+		CVV:          request.Get(FieldAdditionalData),
+		Amount:       amount,
+		Channel:      channel,
+		MerchantName: merchantName,
+		MerchantMCC:  request.Get(FieldMCC),
+		Country:      country,
+	}
+
+	if block := request.Get(FieldPINBlock); block != "" {
+		req.PIN, err = DecodePINBlock(block, pan)
+		if err != nil {
+			return nil, models.ErrInvalidPIN
+		}
+	}
+
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// parseReversalRequest извлекает из 0400 исходную операцию: терминал и RRN, ее сумму (поле 4)
+// и STAN из данных исходного сообщения (поле 90: MTI, STAN, дата и время передачи)
+func parseReversalRequest(request *Message) (*models.TerminalReversalRequest, error) {
+	if !request.Has(FieldAmount) {
+		return nil, ErrFormat
+	}
+	amount, err := ParseAmount(request.Get(FieldAmount))
+	if err != nil {
+		return nil, err
+	}
+
+	req := &models.TerminalReversalRequest{
+		TerminalID: request.Get(FieldTerminalID),
+		RRN:        request.Get(FieldRRN),
+		Amount:     amount,
+	}
+	if original := request.Get(FieldOriginalData); original != "" {
+		req.OriginalSTAN = original[4:10]
+	}
+	return req, nil
+}
+
+// entryModeChannel определяет канал оплаты по первым двум цифрам поля 22.
+// Без поля 22 операция считается интернет-платежом, чтобы к ней применялись ограничения этого канала.
+func entryModeChannel(entryMode string) (string, error) {
+	if entryMode == "" {
+		return models.PaymentChannelEcom, nil
+	}
+	switch entryMode[:2] {
+	case "01", "81":
+		return models.PaymentChannelEcom, nil
+	case "02", "05", "90":
+		return models.PaymentChannelChip, nil
+	case "07", "91":
+		return models.PaymentChannelContactless, nil
+	default:
+		return "", ErrFormat
+	}
+}
+
+// responseCode переводит результат обработки в код ответа поля 39
+func responseCode(err error) string {
+	switch {
+	case err == nil:
+		return ResponseApproved
+	case errors.Is(err, models.ErrInvalidCardNumber), errors.Is(err, models.ErrCardNotFound):
+		return ResponseInvalidCard
+	case errors.Is(err, models.ErrInvalidExpiryDate), errors.Is(err, models.ErrCardExpired):
+		return ResponseExpiredCard
+	case errors.Is(err, models.ErrInvalidAmount), errors.Is(err, models.ErrHoldAmountExceeded):
+		return ResponseInvalidAmount
+	case errors.Is(err, models.ErrInsufficientFunds):
+		return ResponseInsufficientFunds
+	case errors.Is(err, models.ErrWrongPIN),
+		errors.Is(err, models.ErrInvalidPIN),
+		errors.Is(err, models.ErrPINRequired),
+		errors.Is(err, models.ErrPINNotSet):
+		return ResponseIncorrectPIN
+	case errors.Is(err, models.ErrCardPINBlocked):
+		return ResponsePINTriesExceeded
+	case errors.Is(err, models.ErrCardLimitExceeded),
+		errors.Is(err, models.ErrCardDailyLimit),
+		errors.Is(err, models.ErrCardMonthlyLimit):
+		return ResponseExceedsLimit
+	case errors.Is(err, models.ErrOTPRequired):
+		return ResponseAuthRequired
+	case errors.Is(err, models.ErrEcomDisabled),
+		errors.Is(err, models.ErrContactlessDisabled),
+		errors.Is(err, models.ErrForeignDisabled),
+		errors.Is(err, models.ErrMCCBlocked),
+		errors.Is(err, models.ErrMerchantNotAllowed):
+		return ResponseNotPermittedCard
+	case errors.Is(err, models.ErrCardNotActive), errors.Is(err, models.ErrCardTampered), errors.Is(err, models.ErrCardCVVBlocked):
+		return ResponseRestrictedCard
+	case errors.Is(err, models.ErrTerminalNotFound), errors.Is(err, models.ErrInvalidMerchant):
+		return ResponseNotPermittedTerm
+	case errors.Is(err, errInvalidMAC):
+		return ResponseSecurityViolation
+	case errors.Is(err, models.ErrHoldNotFound):
+		return ResponseOriginalNotFound
+	case errors.Is(err, models.ErrHoldNotActive), errors.Is(err, errUnsupportedMTI):
+		return ResponseInvalidTransaction
+	case errors.Is(err, models.ErrDuplicateTransaction):
+		return ResponseDuplicate
+	case errors.Is(err, models.ErrInvalidCVV), errors.Is(err, models.ErrAccountNotFound):
+		return ResponseDoNotHonor
+	case errors.Is(err, ErrFormat),
+		errors.Is(err, models.ErrInvalidPaymentChannel),
+		errors.Is(err, models.ErrInvalidTerminalRequest):
+		return ResponseFormatError
+	default:
+		return ResponseSystemMalfunction
+	}
+}
+
+func maskPAN(pan string) string {
+	if len(pan) < 4 {
+		return models.MaskCardNumber("")
+	}
+	return models.MaskCardNumber(pan[len(pan)-4:])
+}
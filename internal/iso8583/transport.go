@@ -0,0 +1,135 @@
+package iso8583
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// maxMessageSize ограничение длины сообщения в кадре
+const maxMessageSize = 8192
+
+// ReadMessage читает сообщение из потока: двухбайтовая длина (big-endian) и тело
+func ReadMessage(r io.Reader) (*Message, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+
+	length := binary.BigEndian.Uint16(header[:])
+	if length == 0 || length > maxMessageSize {
+		return nil, ErrFormat
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+
+	return Unpack(data)
+}
+
+// WriteMessage пишет сообщение в поток с двухбайтовым заголовком длины
+func WriteMessage(w io.Writer, message *Message) error {
+	data, err := message.Pack()
+	if err != nil {
+		return err
+	}
+	if len(data) > maxMessageSize {
+		return fmt.Errorf("iso8583: сообщение длиннее %d байт", maxMessageSize)
+	}
+
+	frame := make([]byte, 2+len(data))
+	binary.BigEndian.PutUint16(frame, uint16(len(data)))
+	copy(frame[2:], data)
+
+	_, err = w.Write(frame)
+	return err
+}
+
+// FormatAmount переводит сумму в рублях в минимальные единицы (копейки) для поля 4
+func FormatAmount(amount float64) string {
+	return fmt.Sprintf("%012d", int64(math.Round(amount*100)))
+}
+
+// ParseAmount переводит поле 4 из копеек в рубли
+func ParseAmount(value string) (float64, error) {
+	kopecks, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || kopecks <= 0 {
+		return 0, ErrFormat
+	}
+	return float64(kopecks) / 100, nil
+}
+
+// FormatCardAcceptor формирует поле 43: название (25), город (13), страна (2)
+func FormatCardAcceptor(name, city, country string) string {
+	return fmt.Sprintf("%-25.25s%-13.13s%-2.2s", name, city, country)
+}
+
+// ParseCardAcceptor разбирает поле 43 на название, город и страну
+func ParseCardAcceptor(value string) (name, city, country string) {
+	if len(value) != 40 {
+		return strings.TrimSpace(value), "", ""
+	}
+	return strings.TrimSpace(value[:25]), strings.TrimSpace(value[25:38]), strings.TrimSpace(value[38:])
+}
+
+// EncodePINBlock формирует PIN-блок формата ISO 9564-1 format 0 в hex.
+// Блок не шифруется: интерфейс предназначен для тестовой среды без HSM.
+func EncodePINBlock(pin, pan string) (string, error) {
+	if len(pin) < 4 || len(pin) > 12 || !isNumeric(pin) {
+		return "", errors.New("iso8583: неверный PIN")
+	}
+
+	pinField, err := hex.DecodeString(fmt.Sprintf("0%X%s%s", len(pin), pin, strings.Repeat("F", 14-len(pin))))
+	if err != nil {
+		return "", err
+	}
+	panField, err := panBlock(pan)
+	if err != nil {
+		return "", err
+	}
+
+	block := make([]byte, 8)
+	for i := range block {
+		block[i] = pinField[i] ^ panField[i]
+	}
+	return strings.ToUpper(hex.EncodeToString(block)), nil
+}
+
+// DecodePINBlock извлекает PIN из блока формата ISO 9564-1 format 0
+func DecodePINBlock(block, pan string) (string, error) {
+	data, err := hex.DecodeString(block)
+	if err != nil || len(data) != 8 {
+		return "", ErrFormat
+	}
+	panField, err := panBlock(pan)
+	if err != nil {
+		return "", err
+	}
+
+	for i := range data {
+		data[i] ^= panField[i]
+	}
+	clear := strings.ToUpper(hex.EncodeToString(data))
+
+	length := int(data[0] & 0x0F)
+	if clear[0] != '0' || length < 4 || length > 12 || !isNumeric(clear[2:2+length]) {
+		return "", ErrFormat
+	}
+	return clear[2 : 2+length], nil
+}
+
+// panBlock 12 правых цифр номера карты без контрольной, дополненные нулями слева
+func panBlock(pan string) ([]byte, error) {
+	if len(pan) < 13 || !isNumeric(pan) {
+		return nil, errors.New("iso8583: неверный номер карты")
+	}
+	digits := pan[len(pan)-13 : len(pan)-1]
+	return hex.DecodeString("0000" + digits)
+}
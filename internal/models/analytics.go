@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+type CreditLoadReport struct {
+	UserID               int64     `json:"user_id"`
+	ActiveCredits        int       `json:"active_credits"`
+	OutstandingPrincipal float64   `json:"outstanding_principal"`
+	MonthlyObligations   float64   `json:"monthly_obligations"`
+	Upcoming30Days       float64   `json:"upcoming_30_days"`
+	Upcoming90Days       float64   `json:"upcoming_90_days"`
+	Upcoming180Days      float64   `json:"upcoming_180_days"`
+	MonthlyIncome        float64   `json:"monthly_income"`
+	DebtToIncome         float64   `json:"debt_to_income"` // Доля дохода, уходящая на платежи (0..1+)
+	OverdueAmount        float64   `json:"overdue_amount"`
+	Penalties            float64   `json:"penalties"`
+	RiskBand             string    `json:"risk_band"`
+	GeneratedAt          time.Time `json:"generated_at"`
+}
+
+const (
+	RiskBandLow      = "LOW"
+	RiskBandMedium   = "MEDIUM"
+	RiskBandHigh     = "HIGH"
+	RiskBandCritical = "CRITICAL"
+)
+
+// MaxForecastDays максимальный горизонт прогноза баланса
+const MaxForecastDays = 365
+
+type ForecastPoint struct {
+	Date    time.Time `json:"date"`
+	Inflow  float64   `json:"inflow"`
+	Outflow float64   `json:"outflow"`
+	Balance float64   `json:"balance"`
+}
+
+type BalanceForecast struct {
+	AccountID           int64           `json:"account_id"`
+	Days                int             `json:"days"`
+	StartBalance        float64         `json:"start_balance"`
+	EndBalance          float64         `json:"end_balance"`
+	Threshold           float64         `json:"threshold"`
+	FirstNegativeDate   *time.Time      `json:"first_negative_date,omitempty"`
+	FirstBelowThreshold *time.Time      `json:"first_below_threshold,omitempty"`
+	Points              []ForecastPoint `json:"points"`
+}
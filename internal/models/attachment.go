@@ -0,0 +1,82 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	MaxNoteLength = 1000
+	MaxTags       = 10
+)
+
+var tagRegex = regexp.MustCompile(`^[\p{L}0-9_-]{1,32}$`)
+
+// AllowedAttachmentTypes типы файлов, которые можно прикрепить к операции
+var AllowedAttachmentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"application/pdf": true,
+}
+
+type TransactionAttachment struct {
+	ID            int64     `json:"id"`
+	TransactionID int64     `json:"transaction_id"`
+	FileName      string    `json:"file_name"`
+	ContentType   string    `json:"content_type"`
+	Size          int64     `json:"size"`
+	StorageKey    string    `json:"-"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type TransactionNoteRequest struct {
+	TransactionID int64  `json:"transaction_id" validate:"required"`
+	Note          string `json:"note"`
+}
+
+type TransactionTagsRequest struct {
+	TransactionID int64    `json:"transaction_id" validate:"required"`
+	Tags          []string `json:"tags"`
+}
+
+func (r *TransactionNoteRequest) Validate() error {
+	if r.TransactionID <= 0 {
+		return ErrInvalidID
+	}
+	if len([]rune(r.Note)) > MaxNoteLength {
+		return ErrInvalidNote
+	}
+	return nil
+}
+
+// Validate проверяет теги и приводит их к нижнему регистру без дубликатов
+func (r *TransactionTagsRequest) Validate() error {
+	if r.TransactionID <= 0 {
+		return ErrInvalidID
+	}
+
+	seen := make(map[string]bool)
+	tags := make([]string, 0, len(r.Tags))
+	for _, tag := range r.Tags {
+		tag = NormalizeTag(tag)
+		if !tagRegex.MatchString(tag) {
+			return ErrInvalidTag
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) > MaxTags {
+		return ErrTooManyTags
+	}
+
+	r.Tags = tags
+	return nil
+}
+
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
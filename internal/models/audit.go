@@ -0,0 +1,35 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Действия, записываемые в журнал аудита
+const (
+	AuditActionCardReveal   = "card.reveal"
+	AuditActionCardTampered = "card.integrity_violation"
+	AuditActionCardBlock    = "card.block"
+	AuditActionCardUnblock  = "card.unblock"
+	AuditActionCardReissue  = "card.reissue"
+	AuditActionCardRenew    = "card.renew"
+	AuditActionCardPINSet   = "card.pin_set"
+	AuditActionCardControls = "card.controls_update"
+	AuditActionCardMandate  = "card.mandate"
+)
+
+// Типы объектов журнала аудита
+const (
+	AuditEntityCard = "card"
+)
+
+type AuditRecord struct {
+	ID         int64          `json:"id"`
+	UserID     sql.NullInt64  `json:"user_id"`
+	Action     string         `json:"action"`
+	EntityType string         `json:"entity_type"`
+	EntityID   int64          `json:"entity_id"`
+	Details    sql.NullString `json:"details,omitempty"`
+	IPAddress  sql.NullString `json:"ip_address,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+type Budget struct {
+	ID               int64          `json:"id"`
+	UserID           int64          `json:"user_id"`
+	AccountID        sql.NullInt64  `json:"account_id"`
+	Category         sql.NullString `json:"category"`
+	MonthlyLimit     float64        `json:"monthly_limit"`
+	CarryOver        bool           `json:"carry_over"`
+	CarriedAmount    float64        `json:"carried_amount"`
+	Spent            float64        `json:"spent"`
+	LastAlertPercent int            `json:"last_alert_percent"`
+	PeriodStart      time.Time      `json:"period_start"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+}
+
+type BudgetCreateRequest struct {
+	AccountID    int64   `json:"account_id,omitempty"`
+	Category     string  `json:"category,omitempty"`
+	MonthlyLimit float64 `json:"monthly_limit" validate:"required,gt=0"`
+	CarryOver    bool    `json:"carry_over"`
+}
+
+type BudgetProgressResponse struct {
+	ID          int64     `json:"id"`
+	AccountID   int64     `json:"account_id,omitempty"`
+	Category    string    `json:"category,omitempty"`
+	Limit       float64   `json:"limit"` // Месячный лимит с учетом перенесенного остатка
+	Spent       float64   `json:"spent"`
+	Remaining   float64   `json:"remaining"`
+	Percent     float64   `json:"percent"`
+	CarryOver   bool      `json:"carry_over"`
+	PeriodStart time.Time `json:"period_start"`
+}
+
+// Категории расходов
+const (
+	CategoryGroceries     = "groceries"
+	CategoryRestaurants   = "restaurants"
+	CategoryTransport     = "transport"
+	CategoryUtilities     = "utilities"
+	CategoryHealth        = "health"
+	CategoryShopping      = "shopping"
+	CategoryEntertainment = "entertainment"
+	CategoryTravel        = "travel"
+	CategoryOther         = "other"
+)
+
+// BudgetAlertThresholds пороги использования бюджета (в процентах), при которых отправляется уведомление
+var BudgetAlertThresholds = []int{50, 80, 100}
+
+func (b *BudgetCreateRequest) Validate() error {
+	if b.AccountID < 0 {
+		return ErrInvalidAccountID
+	}
+	if b.Category != "" && !ValidateCategory(b.Category) {
+		return ErrInvalidCategory
+	}
+	if !ValidateAmount(b.MonthlyLimit) {
+		return ErrInvalidAmount
+	}
+	return nil
+}
+
+// Limit возвращает лимит текущего месяца с учетом перенесенного остатка
+func (b *Budget) Limit() float64 {
+	return b.MonthlyLimit + b.CarriedAmount
+}
+
+// Percent возвращает процент использования бюджета
+func (b *Budget) Percent() float64 {
+	limit := b.Limit()
+	if limit <= 0 {
+		return 100
+	}
+	return b.Spent / limit * 100
+}
+
+func (b *Budget) ToResponse() BudgetProgressResponse {
+	remaining := b.Limit() - b.Spent
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return BudgetProgressResponse{
+		ID:          b.ID,
+		AccountID:   b.AccountID.Int64,
+		Category:    b.Category.String,
+		Limit:       b.Limit(),
+		Spent:       b.Spent,
+		Remaining:   remaining,
+		Percent:     b.Percent(),
+		CarryOver:   b.CarryOver,
+		PeriodStart: b.PeriodStart,
+	}
+}
+
+func ValidateCategory(category string) bool {
+	switch category {
+	case CategoryGroceries,
+		CategoryRestaurants,
+		CategoryTransport,
+		CategoryUtilities,
+		CategoryHealth,
+		CategoryShopping,
+		CategoryEntertainment,
+		CategoryTravel,
+		CategoryOther:
+		return true
+	default:
+		return false
+	}
+}
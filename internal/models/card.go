@@ -2,19 +2,195 @@ package models
 
 import (
 	"banksystem/internal/crypto"
-	"fmt"
+	"database/sql"
+	"regexp"
+	"strings"
 	"time"
 )
 
 type Card struct {
-	ID            int64     `json:"id"`
-	AccountID     int64     `json:"account_id"`
-	CardNumber    string    `json:"-"`
-	ExpiryDate    time.Time `json:"-"`
-	EncryptedData string    `json:"encrypted_data"`
-	HashedCVV     string    `json:"hashed_cvv"`
-	HMAC          string    `json:"hmac"`
-	CreatedAt     time.Time `json:"created_at"`
+	ID                      int64           `json:"id"`
+	AccountID               int64           `json:"account_id"`
+	CardNumber              string          `json:"-"`
+	ExpiryDate              time.Time       `json:"-"`
+	EncryptedData           string          `json:"-"`
+	HashedCVV               string          `json:"-"`
+	HMAC                    string          `json:"-"`
+	HMACVersion             int             `json:"-"` // Версия ключа, которым вычислен HMAC
+	KeyID                   sql.NullString  `json:"-"` // Идентификатор PGP-ключа, которым зашифрованы данные
+	PANHash                 sql.NullString  `json:"-"` // Ключевой хеш номера карты
+	Last4                   sql.NullString  `json:"-"`
+	Brand                   sql.NullString  `json:"-"`
+	PINHash                 sql.NullString  `json:"-"`
+	PINAttempts             int             `json:"-"` // Неверные попытки ввода PIN подряд
+	CVVAttempts             int             `json:"-"` // Неверные попытки ввода CVV подряд
+	Type                    string          `json:"type"`
+	SpendCap                sql.NullFloat64 `json:"-"` // Лимит трат за весь срок действия виртуальной карты
+	LockedMerchant          sql.NullString  `json:"-"` // Название продавца, у которого принимается карта
+	LockedMerchantAccountID sql.NullInt64   `json:"-"` // Счет продавца, у которого принимается карта
+	Status                  string          `json:"status"`
+	BlockReason             sql.NullString  `json:"-"`
+	BlockedBy               sql.NullString  `json:"-"`
+	ReplacedBy              sql.NullInt64   `json:"-"` // Карта, выпущенная взамен этой
+	CreatedAt               time.Time       `json:"created_at"`
+}
+
+// Статусы карты. BLOCKED — временная блокировка, снимаемая владельцем или банком;
+// PERM_BLOCKED и EXPIRED — конечные состояния
+const (
+	CardStatusActive      = "ACTIVE"
+	CardStatusBlocked     = "BLOCKED"
+	CardStatusPermBlocked = "PERM_BLOCKED"
+	CardStatusExpired     = "EXPIRED"
+)
+
+// Типы карт. Виртуальные карты выпускаются на короткий срок с лимитом трат и не перевыпускаются
+const (
+	CardTypeStandard       = "STANDARD"
+	CardTypeSingleUse      = "SINGLE_USE"
+	CardTypeMerchantLocked = "MERCHANT_LOCKED"
+)
+
+// Срок действия виртуальной карты в днях: по умолчанию и максимальный
+const (
+	DefaultVirtualCardValidityDays = 7
+	MaxVirtualCardValidityDays     = 30
+)
+
+// CardBlockReasonUsed причина блокировки одноразовой карты после первой оплаты
+const CardBlockReasonUsed = "Одноразовая карта использована"
+
+// Кем заблокирована карта
+const (
+	CardBlockedByUser   = "user"
+	CardBlockedByAdmin  = "admin"
+	CardBlockedBySystem = "system"
+)
+
+// MaxPINAttempts после стольких неверных попыток подряд карта блокируется системой
+const MaxPINAttempts = 3
+
+// CardBlockReasonPINAttempts причина блокировки карты после неверных попыток ввода PIN
+const CardBlockReasonPINAttempts = "Превышено число попыток ввода PIN-кода"
+
+// MaxCVVAttempts после стольких неверных попыток ввода CVV подряд карта блокируется системой
+const MaxCVVAttempts = 3
+
+// CardBlockReasonCVVAttempts причина блокировки карты после неверных попыток ввода CVV
+const CardBlockReasonCVVAttempts = "Превышено число попыток ввода CVV"
+
+var pinPattern = regexp.MustCompile(`^[0-9]{4}$`)
+
+// CardPINRequest установка или смена PIN-кода; при смене нужен текущий PIN
+type CardPINRequest struct {
+	PIN        string `json:"pin"`
+	CurrentPIN string `json:"current_pin,omitempty"`
+}
+
+func (r *CardPINRequest) Validate() error {
+	if !ValidatePIN(r.PIN) {
+		return ErrInvalidPIN
+	}
+	if r.CurrentPIN != "" && !ValidatePIN(r.CurrentPIN) {
+		return ErrInvalidPIN
+	}
+	return nil
+}
+
+// ValidatePIN проверяет формат PIN-кода: ровно 4 цифры
+func ValidatePIN(pin string) bool {
+	return pinPattern.MatchString(pin)
+}
+
+type CardBlockRequest struct {
+	Reason    string `json:"reason"`
+	Permanent bool   `json:"permanent"`
+}
+
+func (r *CardBlockRequest) Validate() error {
+	r.Reason = strings.TrimSpace(r.Reason)
+	if len([]rune(r.Reason)) > 255 {
+		return ErrInvalidBlockReason
+	}
+	return nil
+}
+
+// CanChangeCardStatus проверяет допустимость перехода между статусами карты
+func CanChangeCardStatus(from, to string) bool {
+	switch from {
+	case CardStatusActive:
+		return to == CardStatusBlocked || to == CardStatusPermBlocked || to == CardStatusExpired
+	case CardStatusBlocked:
+		return to == CardStatusActive || to == CardStatusPermBlocked || to == CardStatusExpired
+	default:
+		return false
+	}
+}
+
+// CardIssueRequest выпуск карты к счету. Для виртуальных карт обязателен лимит трат,
+// для привязанной к продавцу — счет продавца и его название.
+type CardIssueRequest struct {
+	AccountID         int64   `json:"account_id"`
+	Type              string  `json:"type,omitempty"`
+	SpendCap          float64 `json:"spend_cap,omitempty"`
+	MerchantAccountID int64   `json:"merchant_account_id,omitempty"`
+	MerchantName      string  `json:"merchant_name,omitempty"`
+	ValidDays         int     `json:"valid_days,omitempty"`
+}
+
+func (r *CardIssueRequest) Validate() error {
+	if r.AccountID <= 0 {
+		return ErrInvalidAccountID
+	}
+
+	r.MerchantName = strings.TrimSpace(r.MerchantName)
+	switch r.Type {
+	case "", CardTypeStandard:
+		r.Type = CardTypeStandard
+		if r.SpendCap != 0 || r.MerchantAccountID != 0 || r.MerchantName != "" || r.ValidDays != 0 {
+			return ErrInvalidVirtualCard
+		}
+		return nil
+	case CardTypeSingleUse:
+		if r.MerchantAccountID != 0 || r.MerchantName != "" {
+			return ErrInvalidVirtualCard
+		}
+	case CardTypeMerchantLocked:
+		if r.MerchantAccountID <= 0 || r.MerchantAccountID == r.AccountID {
+			return ErrInvalidVirtualCard
+		}
+		if r.MerchantName == "" || len([]rune(r.MerchantName)) > 100 {
+			return ErrInvalidVirtualCard
+		}
+	default:
+		return ErrInvalidCardType
+	}
+
+	if r.SpendCap <= 0 {
+		return ErrInvalidVirtualCard
+	}
+	if r.ValidDays == 0 {
+		r.ValidDays = DefaultVirtualCardValidityDays
+	}
+	if r.ValidDays < 0 || r.ValidDays > MaxVirtualCardValidityDays {
+		return ErrInvalidVirtualCard
+	}
+	return nil
+}
+
+// IsVirtual виртуальная карта с лимитом трат и коротким сроком действия
+func (c *Card) IsVirtual() bool {
+	return c.Type == CardTypeSingleUse || c.Type == CardTypeMerchantLocked
+}
+
+// AcceptsMerchant проверяет, что карта, привязанная к продавцу, используется у него.
+// Продавец определяется счетом зачисления, который задает банк (терминал, мандат, счет в запросе),
+// а не названием из запроса; merchantAccountID 0 — оплата без счета продавца.
+func (c *Card) AcceptsMerchant(merchantAccountID int64) bool {
+	if c.Type != CardTypeMerchantLocked {
+		return true
+	}
+	return c.LockedMerchantAccountID.Valid && merchantAccountID == c.LockedMerchantAccountID.Int64
 }
 
 type CardCreateRequest struct {
@@ -24,14 +200,45 @@ type CardCreateRequest struct {
 	CVV        string `json:"cvv" validate:"required"`
 }
 
+// CardResponse карта в списках: номер показывается только маской
 type CardResponse struct {
-	ID         int64     `json:"id"`
-	AccountID  int64     `json:"account_id"`
-	CardNumber string    `json:"card_number"` // Расшифрованный номер карты
-	ExpiryDate string    `json:"expiry_date"` // Расшифрованная дата
-	CreatedAt  time.Time `json:"created_at"`
+	ID                      int64     `json:"id"`
+	AccountID               int64     `json:"account_id"`
+	MaskedNumber            string    `json:"masked_number"`
+	Last4                   string    `json:"last4"`
+	Brand                   string    `json:"brand"`
+	ExpiryDate              string    `json:"expiry_date"` // MM/YY
+	Type                    string    `json:"type"`
+	ValidUntil              string    `json:"valid_until,omitempty"` // YYYY-MM-DD, для виртуальных карт
+	SpendCap                *float64  `json:"spend_cap,omitempty"`
+	LockedMerchant          string    `json:"locked_merchant,omitempty"`
+	LockedMerchantAccountID int64     `json:"locked_merchant_account_id,omitempty"`
+	Status                  string    `json:"status"`
+	PINSet                  bool      `json:"pin_set"`
+	BlockReason             string    `json:"block_reason,omitempty"`
+	ReplacedBy              int64     `json:"replaced_by,omitempty"`
+	CreatedAt               time.Time `json:"created_at"`
+}
+
+// CardRevealResponse полные реквизиты карты, выдаваемые только владельцу
+type CardRevealResponse struct {
+	ID         int64  `json:"id"`
+	CardNumber string `json:"card_number"`
+	Brand      string `json:"brand"`
+	ExpiryDate string `json:"expiry_date"` // MM/YY
+	CVV        string `json:"cvv"`
 }
 
+// Платежные системы
+const (
+	CardBrandMir        = "MIR"
+	CardBrandVisa       = "VISA"
+	CardBrandMastercard = "MASTERCARD"
+	CardBrandUnionPay   = "UNIONPAY"
+	CardBrandJCB        = "JCB"
+	CardBrandUnknown    = "UNKNOWN"
+)
+
 func (c *CardCreateRequest) Validate() error {
 	if c.AccountID <= 0 {
 		return ErrInvalidAccountID
@@ -48,46 +255,81 @@ func (c *CardCreateRequest) Validate() error {
 	return nil
 }
 
-func (c *Card) ToResponse(cardNumber, expiryDate string) CardResponse {
-	return CardResponse{
-		ID:         c.ID,
-		AccountID:  c.AccountID,
-		CardNumber: cardNumber,
-		ExpiryDate: expiryDate,
-		CreatedAt:  c.CreatedAt,
+func (c *Card) ToResponse() CardResponse {
+	brand := c.Brand.String
+	if brand == "" {
+		brand = CardBrandUnknown
 	}
-}
 
-// EncryptCardData шифрует данные карты
-func (c *Card) EncryptCardData() error {
-	encrypted, err := crypto.EncryptCardData(c.CardNumber, c.ExpiryDate.Format("01/06"))
-	if err != nil {
-		return err
+	var expiryDate, validUntil string
+	if !c.ExpiryDate.IsZero() {
+		expiryDate = c.ExpiryDate.Format("01/06")
+		if c.IsVirtual() {
+			validUntil = c.ExpiryDate.Format("2006-01-02")
+		}
 	}
-	c.EncryptedData = encrypted
-	c.HMAC = crypto.ComputeHMAC(encrypted)
-	return nil
-}
 
-// DecryptCardData расшифровывает данные карты
-func (c *Card) DecryptCardData() error {
-	if !crypto.VerifyHMAC(c.EncryptedData, c.HMAC) {
-		return fmt.Errorf("неверный HMAC")
+	var spendCap *float64
+	if c.SpendCap.Valid {
+		spendCap = &c.SpendCap.Float64
 	}
 
-	cardNumber, expiryDate, err := crypto.DecryptCardData(c.EncryptedData)
-	if err != nil {
-		return err
+	cardType := c.Type
+	if cardType == "" {
+		cardType = CardTypeStandard
 	}
 
-	c.CardNumber = cardNumber
-	expiry, err := time.Parse("01/06", expiryDate)
-	if err != nil {
-		return err
+	return CardResponse{
+		ID:                      c.ID,
+		AccountID:               c.AccountID,
+		MaskedNumber:            MaskCardNumber(c.Last4.String),
+		Last4:                   c.Last4.String,
+		Brand:                   brand,
+		ExpiryDate:              expiryDate,
+		Type:                    cardType,
+		ValidUntil:              validUntil,
+		SpendCap:                spendCap,
+		LockedMerchant:          c.LockedMerchant.String,
+		LockedMerchantAccountID: c.LockedMerchantAccountID.Int64,
+		Status:                  c.Status,
+		PINSet:                  c.PINHash.Valid,
+		BlockReason:             c.BlockReason.String,
+		ReplacedBy:              c.ReplacedBy.Int64,
+		CreatedAt:               c.CreatedAt,
 	}
-	c.ExpiryDate = expiry
+}
 
-	return nil
+// MaskCardNumber возвращает маску номера карты по последним четырем цифрам
+func MaskCardNumber(last4 string) string {
+	if last4 == "" {
+		return "**** **** **** ****"
+	}
+	return "**** **** **** " + last4
+}
+
+// DetectCardBrand определяет платежную систему по BIN
+func DetectCardBrand(number string) string {
+	number = strings.ReplaceAll(number, " ", "")
+	if len(number) < 4 {
+		return CardBrandUnknown
+	}
+
+	prefix2 := number[:2]
+	prefix4 := number[:4]
+	switch {
+	case prefix4 >= "2200" && prefix4 <= "2204":
+		return CardBrandMir
+	case number[0] == '4':
+		return CardBrandVisa
+	case prefix2 >= "51" && prefix2 <= "55", prefix4 >= "2221" && prefix4 <= "2720":
+		return CardBrandMastercard
+	case prefix2 == "62":
+		return CardBrandUnionPay
+	case prefix4 >= "3528" && prefix4 <= "3589":
+		return CardBrandJCB
+	default:
+		return CardBrandUnknown
+	}
 }
 
 // SetCVV хеширует CVV код
@@ -103,4 +345,4 @@ func (c *Card) SetCVV(cvv string) error {
 // VerifyCVV проверяет CVV код
 func (c *Card) VerifyCVV(cvv string) bool {
 	return crypto.VerifyCVV(c.HashedCVV, cvv)
-} 
\ No newline at end of file
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"database/sql"
+	"regexp"
+	"time"
+)
+
+// Статусы онлайн-платежа, подтверждаемого одноразовым кодом
+const (
+	CardPaymentStatusPendingConfirmation = "pending_confirmation"
+	CardPaymentStatusConfirmed           = "confirmed"
+	CardPaymentStatusFailed              = "failed"
+	CardPaymentStatusExpired             = "expired"
+)
+
+const (
+	// OTPLength число цифр одноразового кода
+	OTPLength = 6
+	// MaxOTPAttempts после стольких неверных кодов платеж отклоняется
+	MaxOTPAttempts = 3
+	// DefaultOTPTTL срок действия кода, если он не задан в лимитах
+	DefaultOTPTTL = 5 * time.Minute
+)
+
+var otpPattern = regexp.MustCompile(`^[0-9]{6}$`)
+
+// CardPaymentConfirmation онлайн-платеж картой, ожидающий подтверждения кодом из письма владельцу карты.
+// Подтвердить платеж может только инициатор: владелец карты или продавец, которому владелец сообщил код.
+type CardPaymentConfirmation struct {
+	ID                int64
+	CardID            int64
+	AccountID         int64
+	MerchantAccountID sql.NullInt64
+	InitiatorID       int64
+	Amount            float64
+	MerchantName      string
+	MerchantMCC       sql.NullString
+	Category          sql.NullString
+	Channel           string
+	Country           string
+	CodeHash          string
+	Attempts          int
+	Status            string
+	TransactionID     sql.NullInt64
+	ExpiresAt         time.Time
+	CreatedAt         time.Time
+}
+
+type CardPaymentConfirmationResponse struct {
+	ID           int64     `json:"confirmation_id"`
+	CardID       int64     `json:"card_id"`
+	Amount       float64   `json:"amount"`
+	MerchantName string    `json:"merchant_name"`
+	Status       string    `json:"status"`
+	AttemptsLeft int       `json:"attempts_left"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// CardPaymentConfirmRequest код подтверждения онлайн-платежа
+type CardPaymentConfirmRequest struct {
+	Code string `json:"code"`
+}
+
+func (r *CardPaymentConfirmRequest) Validate() error {
+	if !otpPattern.MatchString(r.Code) {
+		return ErrInvalidOTP
+	}
+	return nil
+}
+
+func (c *CardPaymentConfirmation) ToResponse() *CardPaymentConfirmationResponse {
+	attemptsLeft := MaxOTPAttempts - c.Attempts
+	if attemptsLeft < 0 {
+		attemptsLeft = 0
+	}
+	return &CardPaymentConfirmationResponse{
+		ID:           c.ID,
+		CardID:       c.CardID,
+		Amount:       c.Amount,
+		MerchantName: c.MerchantName,
+		Status:       c.Status,
+		AttemptsLeft: attemptsLeft,
+		ExpiresAt:    c.ExpiresAt,
+	}
+}
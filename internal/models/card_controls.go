@@ -0,0 +1,195 @@
+package models
+
+import (
+	"database/sql"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Каналы карточного платежа
+const (
+	PaymentChannelEcom        = "ecom"        // оплата в интернете по реквизитам
+	PaymentChannelContactless = "contactless" // бесконтактная оплата
+	PaymentChannelChip        = "chip"        // оплата с чипом или магнитной полосой
+)
+
+// HomeCountry страна банка; платежи в других странах считаются зарубежными
+const HomeCountry = "RU"
+
+// maxBlockedMCCs максимальное число запрещенных MCC на карте
+const maxBlockedMCCs = 100
+
+// MCCGroups именованные группы MCC, которые можно запретить одним значением
+var MCCGroups = map[string][]string{
+	"gambling": {"7800", "7801", "7802", "7995", "9406"},
+	"crypto":   {"6051"},
+	"adult":    {"5967", "7273"},
+}
+
+// CardControls пользовательские ограничения карты. Если настройки не сохранялись, все каналы включены
+// и действуют только лимиты банка.
+type CardControls struct {
+	CardID             int64
+	EcomEnabled        bool
+	ContactlessEnabled bool
+	ForeignEnabled     bool
+	DailyLimit         sql.NullFloat64
+	MonthlyLimit       sql.NullFloat64
+	BlockedMCCs        []string
+	UpdatedAt          sql.NullTime
+}
+
+// DefaultCardControls настройки карты по умолчанию
+func DefaultCardControls(cardID int64) *CardControls {
+	return &CardControls{
+		CardID:             cardID,
+		EcomEnabled:        true,
+		ContactlessEnabled: true,
+		ForeignEnabled:     true,
+	}
+}
+
+type CardControlsResponse struct {
+	CardID             int64      `json:"card_id"`
+	EcomEnabled        bool       `json:"ecom_enabled"`
+	ContactlessEnabled bool       `json:"contactless_enabled"`
+	ForeignEnabled     bool       `json:"foreign_enabled"`
+	DailyLimit         *float64   `json:"daily_limit"`
+	MonthlyLimit       *float64   `json:"monthly_limit"`
+	BlockedMCCs        []string   `json:"blocked_mccs"`
+	UpdatedAt          *time.Time `json:"updated_at,omitempty"`
+}
+
+func (c *CardControls) ToResponse() CardControlsResponse {
+	response := CardControlsResponse{
+		CardID:             c.CardID,
+		EcomEnabled:        c.EcomEnabled,
+		ContactlessEnabled: c.ContactlessEnabled,
+		ForeignEnabled:     c.ForeignEnabled,
+		BlockedMCCs:        c.BlockedMCCs,
+	}
+	if response.BlockedMCCs == nil {
+		response.BlockedMCCs = []string{}
+	}
+	if c.DailyLimit.Valid {
+		response.DailyLimit = &c.DailyLimit.Float64
+	}
+	if c.MonthlyLimit.Valid {
+		response.MonthlyLimit = &c.MonthlyLimit.Float64
+	}
+	if c.UpdatedAt.Valid {
+		response.UpdatedAt = &c.UpdatedAt.Time
+	}
+	return response
+}
+
+// BlocksMCC проверяет, запрещен ли MCC продавца
+func (c *CardControls) BlocksMCC(mcc string) bool {
+	for _, blocked := range c.BlockedMCCs {
+		if blocked == mcc {
+			return true
+		}
+	}
+	return false
+}
+
+// CardControlsRequest изменение настроек карты. Поля, которые не переданы, не меняются;
+// лимит 0 снимает ограничение. В blocked_mccs можно указать коды MCC или имена групп (например, gambling).
+type CardControlsRequest struct {
+	EcomEnabled        *bool     `json:"ecom_enabled,omitempty"`
+	ContactlessEnabled *bool     `json:"contactless_enabled,omitempty"`
+	ForeignEnabled     *bool     `json:"foreign_enabled,omitempty"`
+	DailyLimit         *float64  `json:"daily_limit,omitempty"`
+	MonthlyLimit       *float64  `json:"monthly_limit,omitempty"`
+	BlockedMCCs        *[]string `json:"blocked_mccs,omitempty"`
+}
+
+func (r *CardControlsRequest) Validate() error {
+	if r.DailyLimit != nil && *r.DailyLimit < 0 {
+		return ErrInvalidCardControls
+	}
+	if r.MonthlyLimit != nil && *r.MonthlyLimit < 0 {
+		return ErrInvalidCardControls
+	}
+
+	if r.BlockedMCCs != nil {
+		codes, err := expandMCCs(*r.BlockedMCCs)
+		if err != nil {
+			return err
+		}
+		r.BlockedMCCs = &codes
+	}
+	return nil
+}
+
+// Apply переносит переданные поля запроса в настройки карты
+func (r *CardControlsRequest) Apply(controls *CardControls) {
+	if r.EcomEnabled != nil {
+		controls.EcomEnabled = *r.EcomEnabled
+	}
+	if r.ContactlessEnabled != nil {
+		controls.ContactlessEnabled = *r.ContactlessEnabled
+	}
+	if r.ForeignEnabled != nil {
+		controls.ForeignEnabled = *r.ForeignEnabled
+	}
+	if r.DailyLimit != nil {
+		controls.DailyLimit = sql.NullFloat64{Float64: *r.DailyLimit, Valid: *r.DailyLimit > 0}
+	}
+	if r.MonthlyLimit != nil {
+		controls.MonthlyLimit = sql.NullFloat64{Float64: *r.MonthlyLimit, Valid: *r.MonthlyLimit > 0}
+	}
+	if r.BlockedMCCs != nil {
+		controls.BlockedMCCs = *r.BlockedMCCs
+	}
+}
+
+// expandMCCs раскрывает группы, убирает повторы и сортирует коды
+func expandMCCs(values []string) ([]string, error) {
+	unique := make(map[string]struct{})
+	for _, value := range values {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if group, ok := MCCGroups[value]; ok {
+			for _, code := range group {
+				unique[code] = struct{}{}
+			}
+			continue
+		}
+		if !mccPattern.MatchString(value) {
+			return nil, ErrInvalidCardControls
+		}
+		unique[value] = struct{}{}
+	}
+
+	if len(unique) > maxBlockedMCCs {
+		return nil, ErrInvalidCardControls
+	}
+
+	codes := make([]string, 0, len(unique))
+	for code := range unique {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes, nil
+}
+
+// validatePaymentContext проверяет канал и страну платежа и подставляет значения по умолчанию
+func validatePaymentContext(channel, country *string) error {
+	switch *channel {
+	case "":
+		*channel = PaymentChannelEcom
+	case PaymentChannelEcom, PaymentChannelContactless, PaymentChannelChip:
+	default:
+		return ErrInvalidPaymentChannel
+	}
+
+	*country = strings.ToUpper(strings.TrimSpace(*country))
+	if *country == "" {
+		*country = HomeCountry
+	}
+	if len(*country) != 2 || strings.Trim(*country, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		return ErrInvalidPaymentChannel
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Статусы авторизации по карте, поступившей с терминала.
+// HELD — средства заблокированы (0100), CAPTURED — списаны (0200), RELEASED и REVERSED — отменены (0400).
+const (
+	CardHoldStatusHeld     = "HELD"
+	CardHoldStatusCaptured = "CAPTURED"
+	CardHoldStatusReleased = "RELEASED"
+	CardHoldStatusReversed = "REVERSED"
+)
+
+// CardHold авторизация по карте с терминала. Пара (terminal_id, rrn) однозначно определяет операцию
+// и используется для списания ранее заблокированной суммы и для отмены.
+type CardHold struct {
+	ID                int64
+	CardID            int64
+	AccountID         int64
+	MerchantAccountID int64
+	TerminalID        string
+	RRN               string
+	STAN              string
+	Amount            float64
+	AuthCode          string
+	Status            string
+	TransactionID     sql.NullInt64
+	MerchantName      string
+	MerchantMCC       sql.NullString
+	ExpiresAt         time.Time
+	CreatedAt         time.Time
+}
+
+// Terminal зарегистрированный терминал продавца; оплаты с него зачисляются на MerchantAccountID
+type Terminal struct {
+	ID                string
+	MerchantAccountID int64
+	MerchantName      string
+	MerchantMCC       sql.NullString
+	MACKey            sql.NullString // hex
+	IsActive          bool
+}
+
+// TerminalPaymentRequest операция, поступившая с терминала
+type TerminalPaymentRequest struct {
+	TerminalID   string
+	RRN          string
+	STAN         string
+	CardNumber   string
+	ExpiryDate   string // MM/YY
+	CVV          string
+	PIN          string
+	Amount       float64
+	Channel      string
+	MerchantName string
+	MerchantMCC  string
+	Country      string
+}
+
+func (r *TerminalPaymentRequest) Validate() error {
+	if r.TerminalID == "" || r.RRN == "" {
+		return ErrInvalidTerminalRequest
+	}
+	if !ValidateCardNumber(r.CardNumber) {
+		return ErrInvalidCardNumber
+	}
+	if !ValidateExpiryDate(r.ExpiryDate) {
+		return ErrInvalidExpiryDate
+	}
+	if !ValidateCVV(r.CVV) {
+		return ErrInvalidCVV
+	}
+	if r.PIN != "" && !ValidatePIN(r.PIN) {
+		return ErrInvalidPIN
+	}
+	if !ValidateAmount(r.Amount) {
+		return ErrInvalidAmount
+	}
+	return validatePaymentContext(&r.Channel, &r.Country)
+}
+
+// TerminalReversalRequest отмена операции терминала (0400). Сумма должна совпадать с суммой исходной операции,
+// OriginalSTAN из поля 90 проверяется, если передан.
+type TerminalReversalRequest struct {
+	TerminalID   string
+	RRN          string
+	OriginalSTAN string
+	Amount       float64
+}
+
+func (r *TerminalReversalRequest) Validate() error {
+	if r.TerminalID == "" || r.RRN == "" {
+		return ErrInvalidTerminalRequest
+	}
+	if !ValidateAmount(r.Amount) {
+		return ErrInvalidAmount
+	}
+	return nil
+}
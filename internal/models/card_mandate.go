@@ -0,0 +1,163 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// Периодичность регулярных списаний
+const (
+	MandateFrequencyWeekly    = "weekly"
+	MandateFrequencyMonthly   = "monthly"
+	MandateFrequencyQuarterly = "quarterly"
+	MandateFrequencyYearly    = "yearly"
+)
+
+// Статусы разрешения на регулярные списания. REVOKED — конечный статус.
+// PENDING — разрешение с лимитом выше порога подтверждения ждет кода владельца карты.
+const (
+	MandateStatusPending = "PENDING"
+	MandateStatusActive  = "ACTIVE"
+	MandateStatusPaused  = "PAUSED"
+	MandateStatusRevoked = "REVOKED"
+)
+
+// CardMandate разрешение продавцу списывать с карты не больше AmountCap не чаще одного раза за период Frequency.
+// Списания по подтвержденному разрешению проводятся без CVV, PIN и кода подтверждения.
+type CardMandate struct {
+	ID                int64
+	CardID            int64
+	MerchantAccountID int64
+	MerchantName      string
+	MerchantMCC       sql.NullString
+	MerchantCountry   string
+	AmountCap         float64
+	Frequency         string
+	Status            string
+	LastChargedAt     sql.NullTime
+	RevokedAt         sql.NullTime
+	CodeHash          sql.NullString
+	OTPAttempts       int
+	OTPExpiresAt      sql.NullTime
+	CreatedAt         time.Time
+}
+
+// NextChargeAt первый день, начиная с которого разрешено следующее списание
+func (m *CardMandate) NextChargeAt() time.Time {
+	if !m.LastChargedAt.Valid {
+		return m.CreatedAt
+	}
+	last := m.LastChargedAt.Time
+	day := time.Date(last.Year(), last.Month(), last.Day(), 0, 0, 0, 0, last.Location())
+	switch m.Frequency {
+	case MandateFrequencyWeekly:
+		return day.AddDate(0, 0, 7)
+	case MandateFrequencyQuarterly:
+		return day.AddDate(0, 3, 0)
+	case MandateFrequencyYearly:
+		return day.AddDate(1, 0, 0)
+	default:
+		return day.AddDate(0, 1, 0)
+	}
+}
+
+type CardMandateResponse struct {
+	ID                int64      `json:"id"`
+	CardID            int64      `json:"card_id"`
+	MerchantAccountID int64      `json:"merchant_account_id"`
+	MerchantName      string     `json:"merchant_name"`
+	MerchantMCC       string     `json:"merchant_mcc,omitempty"`
+	AmountCap         float64    `json:"amount_cap"`
+	Frequency         string     `json:"frequency"`
+	Status            string     `json:"status"`
+	LastChargedAt     *time.Time `json:"last_charged_at,omitempty"`
+	NextChargeAt      *time.Time `json:"next_charge_at,omitempty"`
+	AttemptsLeft      *int       `json:"attempts_left,omitempty"`
+	ExpiresAt         *time.Time `json:"expires_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+func (m *CardMandate) ToResponse() *CardMandateResponse {
+	response := &CardMandateResponse{
+		ID:                m.ID,
+		CardID:            m.CardID,
+		MerchantAccountID: m.MerchantAccountID,
+		MerchantName:      m.MerchantName,
+		MerchantMCC:       m.MerchantMCC.String,
+		AmountCap:         m.AmountCap,
+		Frequency:         m.Frequency,
+		Status:            m.Status,
+		CreatedAt:         m.CreatedAt,
+	}
+	if m.LastChargedAt.Valid {
+		response.LastChargedAt = &m.LastChargedAt.Time
+	}
+	switch m.Status {
+	case MandateStatusActive, MandateStatusPaused:
+		next := m.NextChargeAt()
+		response.NextChargeAt = &next
+	case MandateStatusPending:
+		attemptsLeft := MaxOTPAttempts - m.OTPAttempts
+		response.AttemptsLeft = &attemptsLeft
+		if m.OTPExpiresAt.Valid {
+			response.ExpiresAt = &m.OTPExpiresAt.Time
+		}
+	}
+	return response
+}
+
+// CardMandateRequest оформление продавцом разрешения на регулярные списания по реквизитам карты.
+// Средства будут зачисляться на счет продавца MerchantAccountID.
+type CardMandateRequest struct {
+	CardNumber        string  `json:"card_number"`
+	ExpiryDate        string  `json:"expiry_date"` // MM/YY
+	CVV               string  `json:"cvv"`
+	MerchantAccountID int64   `json:"merchant_account_id"`
+	MerchantName      string  `json:"merchant_name"`
+	MerchantMCC       string  `json:"merchant_mcc,omitempty"`
+	Country           string  `json:"merchant_country,omitempty"`
+	AmountCap         float64 `json:"amount_cap"`
+	Frequency         string  `json:"frequency"`
+}
+
+func (r *CardMandateRequest) Validate() error {
+	r.CardNumber = strings.ReplaceAll(r.CardNumber, " ", "")
+	if !ValidateCardNumber(r.CardNumber) {
+		return ErrInvalidCardNumber
+	}
+	if !ValidateExpiryDate(r.ExpiryDate) {
+		return ErrInvalidExpiryDate
+	}
+	if !ValidateCVV(r.CVV) {
+		return ErrInvalidCVV
+	}
+	if r.MerchantAccountID <= 0 {
+		return ErrInvalidAccountID
+	}
+	if !ValidateAmount(r.AmountCap) {
+		return ErrInvalidAmount
+	}
+	switch r.Frequency {
+	case MandateFrequencyWeekly, MandateFrequencyMonthly, MandateFrequencyQuarterly, MandateFrequencyYearly:
+	default:
+		return ErrInvalidMandate
+	}
+	channel := PaymentChannelEcom
+	if err := validatePaymentContext(&channel, &r.Country); err != nil {
+		return err
+	}
+	return validateMerchant(r.MerchantName, r.MerchantMCC)
+}
+
+// MandateChargeRequest очередное списание по разрешению
+type MandateChargeRequest struct {
+	Amount float64 `json:"amount"`
+}
+
+func (r *MandateChargeRequest) Validate() error {
+	if !ValidateAmount(r.Amount) {
+		return ErrInvalidAmount
+	}
+	return nil
+}
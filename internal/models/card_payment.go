@@ -0,0 +1,108 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// CardLimits лимиты карточных платежей
+type CardLimits struct {
+	PerPayment float64
+	Daily      float64
+	// Платежи на сумму выше порога подтверждаются PIN-кодом
+	PINThreshold float64
+	// Срок, на который блокируются средства по авторизации терминала (0100)
+	HoldTTL time.Duration
+	// Онлайн-платежи на сумму выше порога подтверждаются одноразовым кодом из письма
+	OTPThreshold float64
+	OTPTTL       time.Duration
+	// Комиссия за перевод по номеру карты: процент от суммы, но не меньше минимальной
+	TransferFeePercent float64
+	TransferFeeMin     float64
+}
+
+var mccPattern = regexp.MustCompile(`^[0-9]{4}$`)
+
+// CardPaymentRequest оплата картой по инициативе владельца
+type CardPaymentRequest struct {
+	Amount       float64 `json:"amount"`
+	CVV          string  `json:"cvv"`
+	PIN          string  `json:"pin,omitempty"`
+	MerchantName string  `json:"merchant_name"`
+	MerchantMCC  string  `json:"merchant_mcc,omitempty"`
+	Category     string  `json:"category,omitempty"`
+	Channel      string  `json:"channel,omitempty"`          // ecom, contactless или chip; по умолчанию ecom
+	Country      string  `json:"merchant_country,omitempty"` // ISO 3166-1 alpha-2; по умолчанию RU
+}
+
+// MerchantPaymentRequest списание продавцом по реквизитам карты.
+// Средства зачисляются на счет продавца MerchantAccountID.
+type MerchantPaymentRequest struct {
+	CardNumber        string  `json:"card_number"`
+	ExpiryDate        string  `json:"expiry_date"` // MM/YY
+	CVV               string  `json:"cvv"`
+	PIN               string  `json:"pin,omitempty"`
+	Amount            float64 `json:"amount"`
+	MerchantAccountID int64   `json:"merchant_account_id"`
+	MerchantName      string  `json:"merchant_name"`
+	MerchantMCC       string  `json:"merchant_mcc,omitempty"`
+	Channel           string  `json:"channel,omitempty"`
+	Country           string  `json:"merchant_country,omitempty"`
+}
+
+func (r *CardPaymentRequest) Validate() error {
+	if !ValidateAmount(r.Amount) {
+		return ErrInvalidAmount
+	}
+	if !ValidateCVV(r.CVV) {
+		return ErrInvalidCVV
+	}
+	if r.PIN != "" && !ValidatePIN(r.PIN) {
+		return ErrInvalidPIN
+	}
+	if r.Category != "" && !ValidateCategory(r.Category) {
+		return ErrInvalidCategory
+	}
+	if err := validatePaymentContext(&r.Channel, &r.Country); err != nil {
+		return err
+	}
+	return validateMerchant(r.MerchantName, r.MerchantMCC)
+}
+
+func (r *MerchantPaymentRequest) Validate() error {
+	r.CardNumber = strings.ReplaceAll(r.CardNumber, " ", "")
+	if !ValidateCardNumber(r.CardNumber) {
+		return ErrInvalidCardNumber
+	}
+	if !ValidateExpiryDate(r.ExpiryDate) {
+		return ErrInvalidExpiryDate
+	}
+	if !ValidateCVV(r.CVV) {
+		return ErrInvalidCVV
+	}
+	if r.PIN != "" && !ValidatePIN(r.PIN) {
+		return ErrInvalidPIN
+	}
+	if !ValidateAmount(r.Amount) {
+		return ErrInvalidAmount
+	}
+	if r.MerchantAccountID <= 0 {
+		return ErrInvalidAccountID
+	}
+	if err := validatePaymentContext(&r.Channel, &r.Country); err != nil {
+		return err
+	}
+	return validateMerchant(r.MerchantName, r.MerchantMCC)
+}
+
+func validateMerchant(name, mcc string) error {
+	name = strings.TrimSpace(name)
+	if name == "" || len([]rune(name)) > 100 {
+		return ErrInvalidMerchant
+	}
+	if mcc != "" && !mccPattern.MatchString(mcc) {
+		return ErrInvalidMerchant
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"strings"
+	"unicode"
+)
+
+// CardRecipientRequest поиск получателя перевода по номеру карты
+type CardRecipientRequest struct {
+	CardNumber string `json:"card_number"`
+}
+
+// CardRecipientResponse получатель перевода: номер карты и имя показываются только частично
+type CardRecipientResponse struct {
+	CardNumber string `json:"card_number"`
+	Name       string `json:"name"`
+}
+
+// CardTransferRequest перевод с карты владельца на карту банка по ее номеру
+type CardTransferRequest struct {
+	ToCardNumber string  `json:"to_card_number"`
+	Amount       float64 `json:"amount"`
+	Category     string  `json:"category,omitempty"`
+}
+
+type CardTransferResponse struct {
+	Transaction TransactionResponse   `json:"transaction"`
+	Fee         float64               `json:"fee"`
+	Recipient   CardRecipientResponse `json:"recipient"`
+}
+
+func (r *CardRecipientRequest) Validate() error {
+	r.CardNumber = strings.ReplaceAll(r.CardNumber, " ", "")
+	if !ValidateCardNumber(r.CardNumber) {
+		return ErrInvalidCardNumber
+	}
+	return nil
+}
+
+func (r *CardTransferRequest) Validate() error {
+	r.ToCardNumber = strings.ReplaceAll(r.ToCardNumber, " ", "")
+	if !ValidateCardNumber(r.ToCardNumber) {
+		return ErrInvalidCardNumber
+	}
+	if !ValidateAmount(r.Amount) {
+		return ErrInvalidAmount
+	}
+	if r.Category != "" && !ValidateCategory(r.Category) {
+		return ErrInvalidCategory
+	}
+	return nil
+}
+
+// MaskName скрывает имя получателя: «Иван Петров» → «Иван П.», «ivanpetrov» → «iv********»
+func MaskName(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '.' || r == '_' || r == '-'
+	})
+	if len(words) == 0 {
+		return ""
+	}
+	if len(words) > 1 {
+		last := []rune(words[len(words)-1])
+		return words[0] + " " + string(last[0]) + "."
+	}
+
+	runes := []rune(words[0])
+	visible := 2
+	if len(runes) <= visible {
+		visible = 1
+	}
+	return string(runes[:visible]) + strings.Repeat("*", len(runes)-visible)
+}
+
+// CardTransfer проведенный перевод по номеру карты
+type CardTransfer struct {
+	Transaction *Transaction
+	Fee         float64
+	Recipient   CardRecipientResponse
+}
+
+func (t *CardTransfer) ToResponse() CardTransferResponse {
+	return CardTransferResponse{
+		Transaction: t.Transaction.ToResponse(),
+		Fee:         t.Fee,
+		Recipient:   t.Recipient,
+	}
+}
@@ -12,8 +12,13 @@ type Credit struct {
 	InterestRate   float64   `json:"interest_rate" db:"interest_rate"`
 	TermMonths     int       `json:"term_months" db:"term_months"`
 	MonthlyPayment float64   `json:"monthly_payment" db:"monthly_payment"`
+	ScheduleType   string    `json:"schedule_type" db:"schedule_type"`
 	Status         string    `json:"status" db:"status"`
 	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+
+	// Ключевая ставка ЦБ на дату выдачи, от которой рассчитана InterestRate
+	KeyRate     *float64   `json:"key_rate,omitempty" db:"key_rate"`
+	KeyRateDate *time.Time `json:"key_rate_date,omitempty" db:"key_rate_date"`
 }
 
 type CreditCreateRequest struct {
@@ -21,6 +26,7 @@ type CreditCreateRequest struct {
 	Amount       float64 `json:"amount" validate:"required,gt=0"`
 	InterestRate float64 `json:"interest_rate" validate:"required,gt=0"`
 	TermMonths   int     `json:"term_months" validate:"required,gt=0"`
+	ScheduleType string  `json:"schedule_type,omitempty"`
 }
 
 type CreditResponse struct {
@@ -31,8 +37,12 @@ type CreditResponse struct {
 	InterestRate   float64   `json:"interest_rate"`
 	TermMonths     int       `json:"term_months"`
 	MonthlyPayment float64   `json:"monthly_payment"`
+	ScheduleType   string    `json:"schedule_type"`
 	Status         string    `json:"status"`
 	CreatedAt      time.Time `json:"created_at"`
+
+	KeyRate     *float64   `json:"key_rate,omitempty"`
+	KeyRateDate *time.Time `json:"key_rate_date,omitempty"`
 }
 
 const (
@@ -42,6 +52,13 @@ const (
 	CreditStatusRejected  = "REJECTED"
 )
 
+// Типы графика погашения: аннуитетный (равные платежи) и дифференцированный
+// (равные доли основного долга, проценты начисляются на остаток)
+const (
+	CreditScheduleAnnuity        = "annuity"
+	CreditScheduleDifferentiated = "differentiated"
+)
+
 func (c *CreditCreateRequest) Validate() error {
 	if c.AccountID <= 0 {
 		return ErrInvalidAccountID
@@ -55,6 +72,9 @@ func (c *CreditCreateRequest) Validate() error {
 	if c.TermMonths <= 0 {
 		return ErrInvalidTerm
 	}
+	if c.ScheduleType != "" && !ValidateScheduleType(c.ScheduleType) {
+		return ErrInvalidScheduleType
+	}
 	return nil
 }
 
@@ -67,8 +87,11 @@ func (c *Credit) ToResponse() *CreditResponse {
 		InterestRate:   c.InterestRate,
 		TermMonths:     c.TermMonths,
 		MonthlyPayment: c.MonthlyPayment,
+		ScheduleType:   c.ScheduleType,
 		Status:         c.Status,
 		CreatedAt:      c.CreatedAt,
+		KeyRate:        c.KeyRate,
+		KeyRateDate:    c.KeyRateDate,
 	}
 }
 
@@ -82,4 +105,13 @@ func ValidateCreditStatus(status string) bool {
 	default:
 		return false
 	}
-} 
\ No newline at end of file
+} 
+
+func ValidateScheduleType(scheduleType string) bool {
+	switch scheduleType {
+	case CreditScheduleAnnuity, CreditScheduleDifferentiated:
+		return true
+	default:
+		return false
+	}
+}
\ No newline at end of file
@@ -0,0 +1,190 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Статусы заявки на кредит. rejected и disbursed — конечные статусы.
+const (
+	ApplicationStatusSubmitted   = "submitted"
+	ApplicationStatusUnderReview = "under_review"
+	ApplicationStatusApproved    = "approved"
+	ApplicationStatusRejected    = "rejected"
+	ApplicationStatusDisbursed   = "disbursed"
+)
+
+// Пороги скоринга: заявки с баллом не ниже ScoreAutoApprove одобряются автоматически,
+// ниже ScoreAutoReject — отклоняются, остальные передаются на рассмотрение сотруднику банка
+const (
+	ScoreAutoApprove = 70
+	ScoreAutoReject  = 40
+)
+
+// CreditPolicy условия кредитования банка
+type CreditPolicy struct {
+	// Маржа банка к ключевой ставке ЦБ, п.п.; к ней добавляются надбавки за срок и за риск
+	Margin float64
+	// Максимальная сумма и срок кредита
+	MaxAmount     float64
+	MaxTermMonths int
+}
+
+// CreditApplication заявка клиента на кредит. Ставку назначает банк: при подаче InterestRate —
+// предварительная ставка от ключевой ставки KeyRate, при выдаче она пересчитывается по текущей ключевой ставке.
+// После выдачи CreditID указывает на выданный кредит.
+type CreditApplication struct {
+	ID             int64
+	UserID         int64
+	AccountID      int64
+	Amount         float64
+	TermMonths     int
+	ScheduleType   string
+	KeyRate        sql.NullFloat64
+	InterestRate   sql.NullFloat64
+	Status         string
+	Score          sql.NullInt64
+	ScoreDetails   sql.NullString
+	DecisionReason sql.NullString
+	ReviewedBy     sql.NullInt64
+	CreditID       sql.NullInt64
+	DecidedAt      sql.NullTime
+	CreatedAt      time.Time
+}
+
+// CanChangeApplicationStatus проверяет допустимость перехода заявки между статусами
+func CanChangeApplicationStatus(from, to string) bool {
+	switch from {
+	case ApplicationStatusSubmitted:
+		return to == ApplicationStatusUnderReview || to == ApplicationStatusApproved || to == ApplicationStatusRejected
+	case ApplicationStatusUnderReview:
+		return to == ApplicationStatusApproved || to == ApplicationStatusRejected
+	case ApplicationStatusApproved:
+		return to == ApplicationStatusDisbursed
+	default:
+		return false
+	}
+}
+
+type CreditApplicationResponse struct {
+	ID             int64      `json:"id"`
+	AccountID      int64      `json:"account_id"`
+	Amount         float64    `json:"amount"`
+	TermMonths     int        `json:"term_months"`
+	ScheduleType   string     `json:"schedule_type"`
+	KeyRate        *float64   `json:"key_rate,omitempty"`
+	InterestRate   *float64   `json:"interest_rate,omitempty"`
+	Status         string     `json:"status"`
+	Score          *int64     `json:"score,omitempty"`
+	DecisionReason string     `json:"decision_reason,omitempty"`
+	CreditID       *int64     `json:"credit_id,omitempty"`
+	DecidedAt      *time.Time `json:"decided_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func (a *CreditApplication) ToResponse() *CreditApplicationResponse {
+	response := &CreditApplicationResponse{
+		ID:             a.ID,
+		AccountID:      a.AccountID,
+		Amount:         a.Amount,
+		TermMonths:     a.TermMonths,
+		ScheduleType:   a.ScheduleType,
+		Status:         a.Status,
+		DecisionReason: a.DecisionReason.String,
+		CreatedAt:      a.CreatedAt,
+	}
+	if a.KeyRate.Valid {
+		response.KeyRate = &a.KeyRate.Float64
+	}
+	if a.InterestRate.Valid {
+		response.InterestRate = &a.InterestRate.Float64
+	}
+	if a.Score.Valid {
+		response.Score = &a.Score.Int64
+	}
+	if a.CreditID.Valid {
+		response.CreditID = &a.CreditID.Int64
+	}
+	if a.DecidedAt.Valid {
+		response.DecidedAt = &a.DecidedAt.Time
+	}
+	return response
+}
+
+// CreditApplicationAdminResponse заявка для сотрудника банка: с заемщиком и разбором скоринга
+type CreditApplicationAdminResponse struct {
+	*CreditApplicationResponse
+	UserID       int64  `json:"user_id"`
+	ScoreDetails string `json:"score_details,omitempty"`
+}
+
+func (a *CreditApplication) ToAdminResponse() *CreditApplicationAdminResponse {
+	return &CreditApplicationAdminResponse{
+		CreditApplicationResponse: a.ToResponse(),
+		UserID:                    a.UserID,
+		ScoreDetails:              a.ScoreDetails.String,
+	}
+}
+
+// CreditApplicationRequest подача заявки на кредит
+type CreditApplicationRequest struct {
+	AccountID    int64   `json:"account_id"`
+	Amount       float64 `json:"amount"`
+	TermMonths   int     `json:"term_months"`
+	ScheduleType string  `json:"schedule_type,omitempty"`
+}
+
+func (r *CreditApplicationRequest) Validate() error {
+	if r.AccountID <= 0 {
+		return ErrInvalidAccountID
+	}
+	if !ValidateAmount(r.Amount) {
+		return ErrInvalidAmount
+	}
+	if r.TermMonths <= 0 {
+		return ErrInvalidTerm
+	}
+	if r.ScheduleType == "" {
+		r.ScheduleType = CreditScheduleAnnuity
+	}
+	if !ValidateScheduleType(r.ScheduleType) {
+		return ErrInvalidScheduleType
+	}
+	return nil
+}
+
+// CreditDecisionRequest решение сотрудника банка по заявке; для отказа причина обязательна
+type CreditDecisionRequest struct {
+	Reason string `json:"reason"`
+}
+
+func (r *CreditDecisionRequest) Validate(status string) error {
+	r.Reason = strings.TrimSpace(r.Reason)
+	if status == ApplicationStatusRejected && r.Reason == "" {
+		return ErrInvalidApplication
+	}
+	if len(r.Reason) > 500 {
+		return ErrInvalidApplication
+	}
+	return nil
+}
+
+// CreditScore результат скоринга заявки: итоговый балл и вклад каждого правила
+type CreditScore struct {
+	Score   int
+	Factors []string
+	// Отказ независимо от балла, например при текущей просрочке
+	RejectReason string
+}
+
+// Add учитывает правило скоринга: factor — описание, points — начисленные баллы
+func (s *CreditScore) Add(factor string, points int) {
+	s.Score += points
+	s.Factors = append(s.Factors, fmt.Sprintf("%s: +%d", factor, points))
+}
+
+func (s *CreditScore) Details() string {
+	return strings.Join(s.Factors, "; ")
+}
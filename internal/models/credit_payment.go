@@ -2,13 +2,25 @@ package models
 
 import "time"
 
+// Статусы платежа по графику. failed — платеж просрочен и не списан, списание повторяется шедулером.
+const (
+	CreditPaymentStatusPending   = "pending"
+	CreditPaymentStatusFailed    = "failed"
+	CreditPaymentStatusCompleted = "completed"
+)
+
+// CreditPayment строка графика платежей. Amount = Principal + Interest,
+// RemainingBalance — остаток основного долга после этого платежа.
 type CreditPayment struct {
-	ID        int64     `json:"id"`
-	CreditID  int64     `json:"credit_id"`
-	Amount    float64   `json:"amount"`
-	Status    string    `json:"status"`
-	DueDate   time.Time `json:"due_date"`
-	CreatedAt time.Time `json:"created_at"`
+	ID               int64     `json:"id"`
+	CreditID         int64     `json:"credit_id"`
+	Amount           float64   `json:"amount"`
+	Principal        float64   `json:"principal"`
+	Interest         float64   `json:"interest"`
+	RemainingBalance float64   `json:"remaining_balance"`
+	Status           string    `json:"status"`
+	DueDate          time.Time `json:"due_date"`
+	CreatedAt        time.Time `json:"created_at"`
 }
 
 type CreditPaymentCreateRequest struct {
@@ -18,21 +30,27 @@ type CreditPaymentCreateRequest struct {
 }
 
 type CreditPaymentResponse struct {
-	ID        int64     `json:"id"`
-	CreditID  int64     `json:"credit_id"`
-	Amount    float64   `json:"amount"`
-	Status    string    `json:"status"`
-	DueDate   time.Time `json:"due_date"`
-	CreatedAt time.Time `json:"created_at"`
+	ID               int64     `json:"id"`
+	CreditID         int64     `json:"credit_id"`
+	Amount           float64   `json:"amount"`
+	Principal        float64   `json:"principal"`
+	Interest         float64   `json:"interest"`
+	RemainingBalance float64   `json:"remaining_balance"`
+	Status           string    `json:"status"`
+	DueDate          time.Time `json:"due_date"`
+	CreatedAt        time.Time `json:"created_at"`
 }
 
 func (p *CreditPayment) ToResponse() *CreditPaymentResponse {
 	return &CreditPaymentResponse{
-		ID:        p.ID,
-		CreditID:  p.CreditID,
-		Amount:    p.Amount,
-		Status:    p.Status,
-		DueDate:   p.DueDate,
-		CreatedAt: p.CreatedAt,
+		ID:               p.ID,
+		CreditID:         p.CreditID,
+		Amount:           p.Amount,
+		Principal:        p.Principal,
+		Interest:         p.Interest,
+		RemainingBalance: p.RemainingBalance,
+		Status:           p.Status,
+		DueDate:          p.DueDate,
+		CreatedAt:        p.CreatedAt,
 	}
 } 
\ No newline at end of file
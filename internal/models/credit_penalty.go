@@ -0,0 +1,55 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// CreditPenaltyRate штраф за просроченный платеж (+10% к сумме платежа)
+const CreditPenaltyRate = 0.10
+
+// Статусы штрафа
+const (
+	CreditPenaltyStatusPending = "pending"
+	CreditPenaltyStatusPaid    = "paid"
+)
+
+// CreditPenalty штраф, начисленный один раз за пропущенный платеж PaymentID.
+// Списывается вместе с платежом; TransactionID указывает на отдельную операцию списания штрафа.
+type CreditPenalty struct {
+	ID            int64
+	CreditID      int64
+	PaymentID     int64
+	Amount        float64
+	Status        string
+	TransactionID sql.NullInt64
+	PaidAt        sql.NullTime
+	CreatedAt     time.Time
+}
+
+type CreditPenaltyResponse struct {
+	ID            int64      `json:"id"`
+	CreditID      int64      `json:"credit_id"`
+	PaymentID     int64      `json:"payment_id"`
+	Amount        float64    `json:"amount"`
+	Status        string     `json:"status"`
+	TransactionID int64      `json:"transaction_id,omitempty"`
+	PaidAt        *time.Time `json:"paid_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+func (p *CreditPenalty) ToResponse() *CreditPenaltyResponse {
+	response := &CreditPenaltyResponse{
+		ID:            p.ID,
+		CreditID:      p.CreditID,
+		PaymentID:     p.PaymentID,
+		Amount:        p.Amount,
+		Status:        p.Status,
+		TransactionID: p.TransactionID.Int64,
+		CreatedAt:     p.CreatedAt,
+	}
+	if p.PaidAt.Valid {
+		response.PaidAt = &p.PaidAt.Time
+	}
+	return response
+}
@@ -0,0 +1,60 @@
+package models
+
+// Способ пересчета графика после частичного досрочного погашения
+const (
+	PrepayModeShortenTerm   = "shorten_term"   // платеж прежний, срок сокращается
+	PrepayModeReducePayment = "reduce_payment" // срок прежний, платеж уменьшается
+)
+
+// CreditPrepayRequest досрочное погашение кредита. Сумма, превышающая долг с начисленными
+// процентами, не списывается.
+type CreditPrepayRequest struct {
+	Amount float64 `json:"amount"`
+	Mode   string  `json:"mode"`
+}
+
+func (r *CreditPrepayRequest) Validate() error {
+	if !ValidateAmount(r.Amount) {
+		return ErrInvalidAmount
+	}
+	if r.Mode == "" {
+		r.Mode = PrepayModeShortenTerm
+	}
+	switch r.Mode {
+	case PrepayModeShortenTerm, PrepayModeReducePayment:
+		return nil
+	default:
+		return ErrInvalidPrepayment
+	}
+}
+
+type CreditPrepayResponse struct {
+	Credit        *CreditResponse          `json:"credit"`
+	Amount        float64                  `json:"amount"`
+	InterestPaid  float64                  `json:"interest_paid"`
+	PrincipalPaid float64                  `json:"principal_paid"`
+	Schedule      []*CreditPaymentResponse `json:"schedule"`
+}
+
+// CreditPrepayment результат досрочного погашения: списанная сумма, ее распределение
+// и новый график оставшихся платежей
+type CreditPrepayment struct {
+	Credit        *Credit
+	InterestPaid  float64
+	PrincipalPaid float64
+	Schedule      []*CreditPayment
+}
+
+func (p *CreditPrepayment) ToResponse() *CreditPrepayResponse {
+	schedule := make([]*CreditPaymentResponse, 0, len(p.Schedule))
+	for _, payment := range p.Schedule {
+		schedule = append(schedule, payment.ToResponse())
+	}
+	return &CreditPrepayResponse{
+		Credit:        p.Credit.ToResponse(),
+		Amount:        p.InterestPaid + p.PrincipalPaid,
+		InterestPaid:  p.InterestPaid,
+		PrincipalPaid: p.PrincipalPaid,
+		Schedule:      schedule,
+	}
+}
@@ -7,33 +7,109 @@ var (
 	ErrInvalidID = errors.New("неверный ID")
 
 	// Ошибки пользователя
-	ErrInvalidUserID       = errors.New("неверный ID пользователя")
-	ErrInvalidUsername     = errors.New("неверное имя пользователя")
-	ErrInvalidEmail        = errors.New("неверный email")
-	ErrInvalidPassword     = errors.New("неверный пароль")
-	ErrEmailAlreadyExists  = errors.New("email уже существует")
-	ErrUserNotFound        = errors.New("пользователь не найден")
-	ErrInvalidCredentials  = errors.New("неверные учетные данные")
+	ErrInvalidUserID      = errors.New("неверный ID пользователя")
+	ErrInvalidUsername    = errors.New("неверное имя пользователя")
+	ErrInvalidEmail       = errors.New("неверный email")
+	ErrInvalidPassword    = errors.New("неверный пароль")
+	ErrEmailAlreadyExists = errors.New("email уже существует")
+	ErrUserNotFound       = errors.New("пользователь не найден")
+	ErrInvalidCredentials = errors.New("неверные учетные данные")
 
 	// Ошибки аккаунта
-	ErrInvalidAccountID    = errors.New("неверный ID счета")
-	ErrInvalidAccountType  = errors.New("неверный тип счета")
-	ErrInsufficientFunds   = errors.New("недостаточно средств")
-	ErrAccountNotFound     = errors.New("счет не найден")
+	ErrInvalidAccountID   = errors.New("неверный ID счета")
+	ErrInvalidAccountType = errors.New("неверный тип счета")
+	ErrInsufficientFunds  = errors.New("недостаточно средств")
+	ErrAccountNotFound    = errors.New("счет не найден")
+	ErrAccessDenied       = errors.New("доступ запрещен")
+
+	// Ошибки аналитики
+	ErrInvalidForecastPeriod = errors.New("неверный период прогноза")
+
+	// Ошибки операций
+	ErrTransactionNotFound   = errors.New("операция не найдена")
+	ErrInvalidNote           = errors.New("слишком длинная заметка")
+	ErrInvalidTag            = errors.New("неверный тег")
+	ErrTooManyTags           = errors.New("слишком много тегов")
+	ErrAttachmentNotFound    = errors.New("вложение не найдено")
+	ErrAttachmentTooLarge    = errors.New("вложение превышает допустимый размер")
+	ErrInvalidAttachmentType = errors.New("недопустимый тип вложения")
+	ErrInvalidSearchQuery    = errors.New("пустой поисковый запрос")
+
+	// Ошибки бюджета
+	ErrInvalidCategory = errors.New("неверная категория")
+	ErrBudgetNotFound  = errors.New("бюджет не найден")
 
 	// Ошибки карты
-	ErrInvalidCardNumber   = errors.New("неверный номер карты")
-	ErrInvalidExpiryDate   = errors.New("неверный срок действия")
-	ErrInvalidCVV         = errors.New("неверный CVV")
-	ErrCardNotFound       = errors.New("карта не найдена")
+	ErrInvalidCardNumber      = errors.New("неверный номер карты")
+	ErrInvalidExpiryDate      = errors.New("неверный срок действия")
+	ErrInvalidCVV             = errors.New("неверный CVV")
+	ErrCardNotFound           = errors.New("карта не найдена")
+	ErrCardNotActive          = errors.New("карта заблокирована")
+	ErrCardExpired            = errors.New("срок действия карты истек")
+	ErrCardTampered           = errors.New("нарушена целостность данных карты")
+	ErrCardLimitExceeded      = errors.New("превышен лимит по карте")
+	ErrInvalidMerchant        = errors.New("неверные данные продавца")
+	ErrInvalidBlockReason     = errors.New("слишком длинная причина блокировки")
+	ErrCardStatusChange       = errors.New("недопустимая смена статуса карты")
+	ErrCardBlockedByBank      = errors.New("карта заблокирована банком, обратитесь в поддержку")
+	ErrCardAlreadyReissued    = errors.New("карта уже перевыпущена")
+	ErrInvalidPIN             = errors.New("PIN-код должен состоять из 4 цифр")
+	ErrPINRequired            = errors.New("для операции требуется PIN-код")
+	ErrPINNotSet              = errors.New("PIN-код карты не установлен")
+	ErrWrongPIN               = errors.New("неверный PIN-код")
+	ErrCardPINBlocked         = errors.New("карта заблокирована после превышения числа попыток ввода PIN-кода")
+	ErrCardCVVBlocked         = errors.New("карта заблокирована после превышения числа попыток ввода CVV")
+	ErrInvalidCardType        = errors.New("неверный тип карты")
+	ErrInvalidVirtualCard     = errors.New("неверные параметры виртуальной карты")
+	ErrMerchantNotAllowed     = errors.New("карта привязана к другому продавцу")
+	ErrVirtualCard            = errors.New("операция недоступна для виртуальной карты")
+	ErrInvalidCardControls    = errors.New("неверные настройки карты")
+	ErrInvalidPaymentChannel  = errors.New("неверный канал или страна платежа")
+	ErrEcomDisabled           = errors.New("оплата в интернете отключена для карты")
+	ErrContactlessDisabled    = errors.New("бесконтактная оплата отключена для карты")
+	ErrForeignDisabled        = errors.New("зарубежные платежи отключены для карты")
+	ErrCardDailyLimit         = errors.New("превышен дневной лимит, установленный для карты")
+	ErrCardMonthlyLimit       = errors.New("превышен месячный лимит, установленный для карты")
+	ErrMCCBlocked             = errors.New("платежи в этой категории продавцов запрещены для карты")
+	ErrInvalidTerminalRequest = errors.New("неверные данные операции терминала")
+	ErrTerminalNotFound       = errors.New("терминал не зарегистрирован")
+	ErrHoldNotFound           = errors.New("исходная операция не найдена")
+	ErrHoldNotActive          = errors.New("блокировка средств уже завершена")
+	ErrHoldAmountExceeded     = errors.New("сумма списания больше заблокированной")
+	ErrDuplicateTransaction   = errors.New("операция с таким RRN уже проведена")
+	ErrOTPRequired            = errors.New("платеж требует подтверждения кодом, проведите его через интернет-эквайринг")
+	ErrInvalidOTP             = errors.New("код подтверждения должен состоять из 6 цифр")
+	ErrWrongOTP               = errors.New("неверный код подтверждения")
+	ErrOTPAttemptsExceeded    = errors.New("платеж отклонен после превышения числа попыток ввода кода")
+	ErrConfirmationNotFound   = errors.New("платеж для подтверждения не найден")
+	ErrConfirmationExpired    = errors.New("истек срок подтверждения платежа")
+	ErrConfirmationNotPending = errors.New("платеж уже не ожидает подтверждения")
+	ErrInvalidMandate         = errors.New("неверные параметры регулярного списания")
+	ErrMandateNotFound        = errors.New("разрешение на регулярные списания не найдено")
+	ErrMandatePaused          = errors.New("регулярные списания приостановлены владельцем карты")
+	ErrMandateRevoked         = errors.New("разрешение на регулярные списания отозвано")
+	ErrMandateAmountExceeded  = errors.New("сумма списания больше установленной в разрешении")
+	ErrMandateStatusChange    = errors.New("недопустимая смена статуса разрешения")
+	ErrMandateTooFrequent     = errors.New("списание по разрешению уже было в текущем периоде")
+	ErrMandateNotConfirmed    = errors.New("разрешение на регулярные списания не подтверждено владельцем карты")
 
 	// Ошибки кредита
-	ErrInvalidCreditID    = errors.New("неверный ID кредита")
-	ErrInvalidAmount      = errors.New("неверная сумма")
-	ErrInvalidTerm       = errors.New("неверный срок")
-	ErrInvalidRate       = errors.New("неверная процентная ставка")
+	ErrInvalidCreditID     = errors.New("неверный ID кредита")
+	ErrInvalidAmount       = errors.New("неверная сумма")
+	ErrInvalidTerm         = errors.New("неверный срок")
+	ErrInvalidRate         = errors.New("неверная процентная ставка")
 	ErrInvalidInterestRate = errors.New("неверная процентная ставка")
-	ErrCreditNotFound    = errors.New("кредит не найден")
+	ErrCreditNotFound      = errors.New("кредит не найден")
+	ErrInvalidScheduleType = errors.New("неверный тип графика платежей")
+	ErrInvalidPrepayment   = errors.New("неверные параметры досрочного погашения")
+	ErrCreditClosed        = errors.New("кредит уже погашен")
+	ErrCreditPaymentDue    = errors.New("сначала необходимо погасить наступившие платежи по кредиту")
+	ErrInvalidApplication  = errors.New("неверные параметры заявки на кредит")
+	ErrApplicationNotFound = errors.New("заявка на кредит не найдена")
+	ErrApplicationStatus   = errors.New("недопустимая смена статуса заявки на кредит")
+	ErrCreditAmountLimit   = errors.New("сумма кредита превышает максимальную")
+	ErrKeyRateUnavailable  = errors.New("ключевая ставка ЦБ недоступна, выдача кредитов временно невозможна")
+	ErrKeyRatePeriod       = errors.New("неверный период истории ключевой ставки")
 
 	// Ошибки платежа
 	ErrInvalidPaymentID   = errors.New("неверный ID платежа")
@@ -41,6 +117,6 @@ var (
 	ErrPaymentNotFound    = errors.New("платеж не найден")
 
 	// Ошибки репозитория
-	ErrNotFound          = errors.New("запись не найдена")
-	ErrAlreadyExists     = errors.New("запись уже существует")
-) 
\ No newline at end of file
+	ErrNotFound      = errors.New("запись не найдена")
+	ErrAlreadyExists = errors.New("запись уже существует")
+)
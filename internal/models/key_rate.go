@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Максимальный период запроса истории ключевой ставки, в днях
+const MaxKeyRatePeriodDays = 366
+
+// KeyRate ключевая ставка Банка России, действующая с даты Date
+type KeyRate struct {
+	Date time.Time
+	Rate float64
+	// Когда значение было получено от ЦБ
+	FetchedAt time.Time
+}
+
+type KeyRateResponse struct {
+	Date string  `json:"date"`
+	Rate float64 `json:"rate"`
+}
+
+func (k *KeyRate) ToResponse() *KeyRateResponse {
+	return &KeyRateResponse{
+		Date: k.Date.Format("2006-01-02"),
+		Rate: k.Rate,
+	}
+}
@@ -6,13 +6,20 @@ import (
 )
 
 type Transaction struct {
-	ID          int64          `json:"id"`
-	AccountID   int64          `json:"account_id"`
-	Type        string         `json:"type"` // deposit, withdraw, transfer_in, transfer_out
-	Amount      float64        `json:"amount"`
-	Status      string         `json:"status"`
-	ToAccountID sql.NullInt64  `json:"to_account_id,omitempty"`
-	CreatedAt   sql.NullTime   `json:"created_at"`
+	ID           int64          `json:"id"`
+	AccountID    int64          `json:"account_id"`
+	Type         string         `json:"type"` // deposit, withdraw, transfer_in, transfer_out
+	Amount       float64        `json:"amount"`
+	Status       string         `json:"status"`
+	ToAccountID  sql.NullInt64  `json:"to_account_id,omitempty"`
+	Category     sql.NullString `json:"category,omitempty"`
+	Note         sql.NullString `json:"note,omitempty"`
+	Description  sql.NullString `json:"description,omitempty"`
+	Counterparty sql.NullString `json:"counterparty,omitempty"`
+	CardID       sql.NullInt64  `json:"card_id,omitempty"`
+	MerchantMCC  sql.NullString `json:"merchant_mcc,omitempty"`
+	Tags         []string       `json:"tags,omitempty"`
+	CreatedAt    sql.NullTime   `json:"created_at"`
 }
 
 type TransactionCreateRequest struct {
@@ -23,13 +30,20 @@ type TransactionCreateRequest struct {
 }
 
 type TransactionResponse struct {
-	ID          int64     `json:"id"`
-	AccountID   int64     `json:"account_id"`
-	Type        string    `json:"type"`
-	Amount      float64   `json:"amount"`
-	Status      string    `json:"status"`
-	ToAccountID int64     `json:"to_account_id,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID           int64     `json:"id"`
+	AccountID    int64     `json:"account_id"`
+	Type         string    `json:"type"`
+	Amount       float64   `json:"amount"`
+	Status       string    `json:"status"`
+	ToAccountID  int64     `json:"to_account_id,omitempty"`
+	Category     string    `json:"category,omitempty"`
+	Note         string    `json:"note,omitempty"`
+	Description  string    `json:"description,omitempty"`
+	Counterparty string    `json:"counterparty,omitempty"`
+	CardID       int64     `json:"card_id,omitempty"`
+	MerchantMCC  string    `json:"merchant_mcc,omitempty"`
+	Tags         []string  `json:"tags,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
 }
 
 const (
@@ -37,10 +51,12 @@ const (
 	TransactionTypeWithdrawal = "WITHDRAWAL"
 	TransactionTypeTransfer   = "TRANSFER"
 	TransactionTypePayment    = "PAYMENT"
+	TransactionTypeFee        = "FEE"
 
 	TransactionStatusPending   = "PENDING"
 	TransactionStatusCompleted = "COMPLETED"
 	TransactionStatusFailed    = "FAILED"
+	TransactionStatusReversed  = "REVERSED"
 )
 
 func (t *Transaction) ToResponse() TransactionResponse {
@@ -55,13 +71,20 @@ func (t *Transaction) ToResponse() TransactionResponse {
 	}
 
 	return TransactionResponse{
-		ID:          t.ID,
-		AccountID:   t.AccountID,
-		Type:        t.Type,
-		Amount:      t.Amount,
-		Status:      t.Status,
-		ToAccountID: toAccountID,
-		CreatedAt:   createdAt,
+		ID:           t.ID,
+		AccountID:    t.AccountID,
+		Type:         t.Type,
+		Amount:       t.Amount,
+		Status:       t.Status,
+		ToAccountID:  toAccountID,
+		Category:     t.Category.String,
+		Note:         t.Note.String,
+		Description:  t.Description.String,
+		Counterparty: t.Counterparty.String,
+		CardID:       t.CardID.Int64,
+		MerchantMCC:  t.MerchantMCC.String,
+		Tags:         t.Tags,
+		CreatedAt:    createdAt,
 	}
 }
 
@@ -70,7 +93,8 @@ func ValidateTransactionType(tType string) bool {
 	case TransactionTypeDeposit,
 		TransactionTypeWithdrawal,
 		TransactionTypeTransfer,
-		TransactionTypePayment:
+		TransactionTypePayment,
+		TransactionTypeFee:
 		return true
 	default:
 		return false
@@ -81,9 +105,22 @@ func ValidateTransactionStatus(status string) bool {
 	switch status {
 	case TransactionStatusPending,
 		TransactionStatusCompleted,
-		TransactionStatusFailed:
+		TransactionStatusFailed,
+		TransactionStatusReversed:
 		return true
 	default:
 		return false
 	}
-} 
\ No newline at end of file
+}
+
+const (
+	DefaultSearchPageSize = 20
+	MaxSearchPageSize     = 100
+)
+
+type TransactionSearchResponse struct {
+	Items    []TransactionResponse `json:"items"`
+	Total    int                   `json:"total"`
+	Page     int                   `json:"page"`
+	PageSize int                   `json:"page_size"`
+}
@@ -10,10 +10,16 @@ type User struct {
 	Email        string    `json:"email" validate:"required,email"`
 	Password     string    `json:"password"` // Только для регистрации
 	PasswordHash string    `json:"-"`        // Хеш пароля
+	Role         string    `json:"role"`     // user, admin
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
 
+const (
+	UserRoleUser  = "user"
+	UserRoleAdmin = "admin"
+)
+
 type UserCreateRequest struct {
 	Username string `json:"username" validate:"required"`
 	Email    string `json:"email" validate:"required,email"`
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"banksystem/internal/crypto"
 	"regexp"
 	"strings"
 )
@@ -29,7 +30,7 @@ func ValidateAmount(amount float64) bool {
 func ValidateCardNumber(number string) bool {
 	// Удаляем пробелы и проверяем длину
 	number = strings.ReplaceAll(number, " ", "")
-	return len(number) == 16
+	return len(number) == crypto.PANLength && crypto.LuhnValid(number)
 }
 
 func ValidateCVV(cvv string) bool {
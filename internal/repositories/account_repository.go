@@ -128,4 +128,31 @@ func (r *AccountRepository) UpdateBalance(tx *sql.Tx, id int64, balance float64)
 
 	_, err := tx.Exec(query, balance, id)
 	return err
-} 
\ No newline at end of file
+}
+
+// GetByIDForUpdate читает счет внутри транзакции и блокирует строку до ее завершения
+func (r *AccountRepository) GetByIDForUpdate(ctx context.Context, tx *sql.Tx, id int64) (*models.Account, error) {
+	query := `
+		SELECT id, user_id, balance, type, is_active, created_at, updated_at
+		FROM accounts
+		WHERE id = $1
+		FOR UPDATE
+	`
+
+	account := &models.Account{}
+	err := tx.QueryRowContext(ctx, query, id).Scan(
+		&account.ID,
+		&account.UserID,
+		&account.Balance,
+		&account.Type,
+		&account.IsActive,
+		&account.CreatedAt,
+		&account.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	return account, err
+}
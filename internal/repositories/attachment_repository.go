@@ -0,0 +1,101 @@
+package repositories
+
+import (
+	"banksystem/internal/models"
+	"context"
+	"database/sql"
+)
+
+type AttachmentRepository struct {
+	db *sql.DB
+}
+
+func NewAttachmentRepository(db *sql.DB) *AttachmentRepository {
+	return &AttachmentRepository{db: db}
+}
+
+func (r *AttachmentRepository) Create(ctx context.Context, attachment *models.TransactionAttachment) error {
+	query := `
+		INSERT INTO transaction_attachments (transaction_id, file_name, content_type, size, storage_key)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+
+	return r.db.QueryRowContext(
+		ctx,
+		query,
+		attachment.TransactionID,
+		attachment.FileName,
+		attachment.ContentType,
+		attachment.Size,
+		attachment.StorageKey,
+	).Scan(&attachment.ID, &attachment.CreatedAt)
+}
+
+func (r *AttachmentRepository) GetByID(ctx context.Context, id int64) (*models.TransactionAttachment, error) {
+	query := `
+		SELECT id, transaction_id, file_name, content_type, size, storage_key, created_at
+		FROM transaction_attachments
+		WHERE id = $1
+	`
+
+	attachment := &models.TransactionAttachment{}
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&attachment.ID,
+		&attachment.TransactionID,
+		&attachment.FileName,
+		&attachment.ContentType,
+		&attachment.Size,
+		&attachment.StorageKey,
+		&attachment.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+func (r *AttachmentRepository) GetByTransactionID(ctx context.Context, transactionID int64) ([]*models.TransactionAttachment, error) {
+	query := `
+		SELECT id, transaction_id, file_name, content_type, size, storage_key, created_at
+		FROM transaction_attachments
+		WHERE transaction_id = $1
+		ORDER BY id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []*models.TransactionAttachment
+	for rows.Next() {
+		attachment := &models.TransactionAttachment{}
+		err := rows.Scan(
+			&attachment.ID,
+			&attachment.TransactionID,
+			&attachment.FileName,
+			&attachment.ContentType,
+			&attachment.Size,
+			&attachment.StorageKey,
+			&attachment.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	return attachments, rows.Err()
+}
+
+func (r *AttachmentRepository) Delete(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM transaction_attachments WHERE id = $1`, id)
+	return err
+}
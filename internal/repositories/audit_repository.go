@@ -0,0 +1,70 @@
+package repositories
+
+import (
+	"banksystem/internal/models"
+	"context"
+	"database/sql"
+)
+
+type AuditRepository struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+func (r *AuditRepository) Create(ctx context.Context, record *models.AuditRecord) error {
+	query := `
+		INSERT INTO audit_log (user_id, action, entity_type, entity_id, details, ip_address)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+
+	return r.db.QueryRowContext(
+		ctx,
+		query,
+		record.UserID,
+		record.Action,
+		record.EntityType,
+		record.EntityID,
+		record.Details,
+		record.IPAddress,
+	).Scan(&record.ID, &record.CreatedAt)
+}
+
+func (r *AuditRepository) GetByEntity(ctx context.Context, entityType string, entityID int64) ([]*models.AuditRecord, error) {
+	query := `
+		SELECT id, user_id, action, entity_type, entity_id, details, ip_address, created_at
+		FROM audit_log
+		WHERE entity_type = $1 AND entity_id = $2
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, entityType, entityID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []*models.AuditRecord
+	for rows.Next() {
+		record := &models.AuditRecord{}
+		err := rows.Scan(
+			&record.ID,
+			&record.UserID,
+			&record.Action,
+			&record.EntityType,
+			&record.EntityID,
+			&record.Details,
+			&record.IPAddress,
+			&record.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
@@ -0,0 +1,203 @@
+package repositories
+
+import (
+	"banksystem/internal/models"
+	"context"
+	"database/sql"
+	"time"
+)
+
+type BudgetRepository struct {
+	db *sql.DB
+}
+
+func NewBudgetRepository(db *sql.DB) *BudgetRepository {
+	return &BudgetRepository{db: db}
+}
+
+const budgetColumns = `id, user_id, account_id, category, monthly_limit, carry_over, carried_amount,
+		spent, last_alert_percent, period_start, created_at, updated_at`
+
+func scanBudget(row interface{ Scan(...interface{}) error }, budget *models.Budget) error {
+	return row.Scan(
+		&budget.ID,
+		&budget.UserID,
+		&budget.AccountID,
+		&budget.Category,
+		&budget.MonthlyLimit,
+		&budget.CarryOver,
+		&budget.CarriedAmount,
+		&budget.Spent,
+		&budget.LastAlertPercent,
+		&budget.PeriodStart,
+		&budget.CreatedAt,
+		&budget.UpdatedAt,
+	)
+}
+
+func (r *BudgetRepository) Create(ctx context.Context, budget *models.Budget) error {
+	query := `
+		INSERT INTO budgets (user_id, account_id, category, monthly_limit, carry_over, spent, period_start, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
+		RETURNING id, created_at, updated_at
+	`
+
+	return r.db.QueryRowContext(
+		ctx,
+		query,
+		budget.UserID,
+		budget.AccountID,
+		budget.Category,
+		budget.MonthlyLimit,
+		budget.CarryOver,
+		budget.Spent,
+		budget.PeriodStart,
+		time.Now(),
+	).Scan(&budget.ID, &budget.CreatedAt, &budget.UpdatedAt)
+}
+
+func (r *BudgetRepository) GetByID(ctx context.Context, id int64) (*models.Budget, error) {
+	query := `SELECT ` + budgetColumns + ` FROM budgets WHERE id = $1`
+
+	budget := &models.Budget{}
+	err := scanBudget(r.db.QueryRowContext(ctx, query, id), budget)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return budget, nil
+}
+
+func (r *BudgetRepository) GetByUserID(ctx context.Context, userID int64) ([]*models.Budget, error) {
+	query := `SELECT ` + budgetColumns + ` FROM budgets WHERE user_id = $1 ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var budgets []*models.Budget
+	for rows.Next() {
+		budget := &models.Budget{}
+		if err := scanBudget(rows, budget); err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, budget)
+	}
+
+	return budgets, rows.Err()
+}
+
+// GetSpentSince считает расходы пользователя с начала периода, подходящие под фильтры бюджета
+func (r *BudgetRepository) GetSpentSince(ctx context.Context, budget *models.Budget) (float64, error) {
+	query := `
+		SELECT COALESCE(SUM(t.amount), 0)
+		FROM transactions t
+		JOIN accounts a ON t.account_id = a.id
+		WHERE a.user_id = $1
+		  AND t.type IN ('withdraw', 'transfer', 'PAYMENT')
+		  AND t.created_at >= $2
+		  AND ($3::INTEGER IS NULL OR t.account_id = $3)
+		  AND ($4::VARCHAR IS NULL OR t.category = $4)
+	`
+
+	var spent float64
+	err := r.db.QueryRowContext(ctx, query, budget.UserID, budget.PeriodStart, budget.AccountID, budget.Category).Scan(&spent)
+	return spent, err
+}
+
+// AddSpending увеличивает расход во всех бюджетах пользователя, подходящих под счет и категорию операции
+func (r *BudgetRepository) AddSpending(ctx context.Context, tx *sql.Tx, userID, accountID int64, category string, amount float64) ([]*models.Budget, error) {
+	query := `
+		UPDATE budgets
+		SET spent = spent + $4, updated_at = $5
+		WHERE user_id = $1
+		  AND (account_id IS NULL OR account_id = $2)
+		  AND (category IS NULL OR category = NULLIF($3, ''))
+		RETURNING ` + budgetColumns
+
+	rows, err := tx.QueryContext(ctx, query, userID, accountID, category, amount, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var budgets []*models.Budget
+	for rows.Next() {
+		budget := &models.Budget{}
+		if err := scanBudget(rows, budget); err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, budget)
+	}
+
+	return budgets, rows.Err()
+}
+
+// SubtractSpending уменьшает расход бюджетов, подходящих под счет и категорию отмененной операции
+func (r *BudgetRepository) SubtractSpending(ctx context.Context, tx *sql.Tx, userID, accountID int64, category string, amount float64) error {
+	query := `
+		UPDATE budgets
+		SET spent = GREATEST(spent - $4, 0), updated_at = $5
+		WHERE user_id = $1
+		  AND (account_id IS NULL OR account_id = $2)
+		  AND (category IS NULL OR category = NULLIF($3, ''))
+	`
+
+	_, err := tx.ExecContext(ctx, query, userID, accountID, category, amount, time.Now())
+	return err
+}
+
+func (r *BudgetRepository) UpdateLastAlert(ctx context.Context, tx *sql.Tx, id int64, percent int) error {
+	query := `
+		UPDATE budgets
+		SET last_alert_percent = $1
+		WHERE id = $2
+	`
+
+	_, err := tx.ExecContext(ctx, query, percent, id)
+	return err
+}
+
+// budgetRolloverQuery переносит неизрасходованный остаток для бюджетов с carry_over,
+// обнуляет расход и начинает новый период с $1
+const budgetRolloverQuery = `
+		UPDATE budgets
+		SET carried_amount = CASE
+				WHEN carry_over THEN GREATEST(monthly_limit + carried_amount - spent, 0)
+				ELSE 0
+			END,
+			spent = 0,
+			last_alert_percent = 0,
+			period_start = $1,
+			updated_at = $2
+		WHERE period_start < $1`
+
+// Rollover закрывает прошедшие периоды: переносит неизрасходованный остаток
+// для бюджетов с carry_over и обнуляет расход
+func (r *BudgetRepository) Rollover(ctx context.Context, periodStart time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, budgetRolloverQuery, periodStart, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// RolloverUser закрывает прошедшие периоды бюджетов пользователя в транзакции tx,
+// чтобы расход нового месяца не попал в бюджет прошлого до запуска шедулера
+func (r *BudgetRepository) RolloverUser(ctx context.Context, tx *sql.Tx, userID int64, periodStart time.Time) error {
+	_, err := tx.ExecContext(ctx, budgetRolloverQuery+` AND user_id = $3`, periodStart, time.Now(), userID)
+	return err
+}
+
+func (r *BudgetRepository) Delete(ctx context.Context, id int64) error {
+	query := `DELETE FROM budgets WHERE id = $1`
+
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
@@ -0,0 +1,108 @@
+package repositories
+
+import (
+	"banksystem/internal/models"
+	"context"
+	"database/sql"
+	"time"
+)
+
+// CreateConfirmation сохраняет онлайн-платеж, ожидающий подтверждения кодом
+func (r *CardRepository) CreateConfirmation(ctx context.Context, tx *sql.Tx, confirmation *models.CardPaymentConfirmation) error {
+	query := `
+		INSERT INTO card_payment_confirmations (card_id, account_id, merchant_account_id, initiator_id, amount,
+			merchant_name, merchant_mcc, category, channel, country, code_hash, attempts, status, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		RETURNING id
+	`
+
+	return tx.QueryRowContext(
+		ctx,
+		query,
+		confirmation.CardID,
+		confirmation.AccountID,
+		confirmation.MerchantAccountID,
+		confirmation.InitiatorID,
+		confirmation.Amount,
+		confirmation.MerchantName,
+		confirmation.MerchantMCC,
+		confirmation.Category,
+		confirmation.Channel,
+		confirmation.Country,
+		confirmation.CodeHash,
+		confirmation.Attempts,
+		confirmation.Status,
+		confirmation.ExpiresAt,
+		confirmation.CreatedAt,
+	).Scan(&confirmation.ID)
+}
+
+// GetConfirmationForUpdate возвращает платеж и блокирует его до конца транзакции
+func (r *CardRepository) GetConfirmationForUpdate(ctx context.Context, tx *sql.Tx, id int64) (*models.CardPaymentConfirmation, error) {
+	query := `
+		SELECT id, card_id, account_id, merchant_account_id, initiator_id, amount, merchant_name, merchant_mcc,
+			category, channel, country, code_hash, attempts, status, transaction_id, expires_at, created_at
+		FROM card_payment_confirmations
+		WHERE id = $1
+		FOR UPDATE
+	`
+
+	confirmation := &models.CardPaymentConfirmation{}
+	err := tx.QueryRowContext(ctx, query, id).Scan(
+		&confirmation.ID,
+		&confirmation.CardID,
+		&confirmation.AccountID,
+		&confirmation.MerchantAccountID,
+		&confirmation.InitiatorID,
+		&confirmation.Amount,
+		&confirmation.MerchantName,
+		&confirmation.MerchantMCC,
+		&confirmation.Category,
+		&confirmation.Channel,
+		&confirmation.Country,
+		&confirmation.CodeHash,
+		&confirmation.Attempts,
+		&confirmation.Status,
+		&confirmation.TransactionID,
+		&confirmation.ExpiresAt,
+		&confirmation.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return confirmation, nil
+}
+
+// UpdateConfirmation сохраняет статус, счетчик попыток и проведенную операцию
+func (r *CardRepository) UpdateConfirmation(ctx context.Context, tx *sql.Tx, confirmation *models.CardPaymentConfirmation) error {
+	query := `
+		UPDATE card_payment_confirmations
+		SET status = $1, attempts = $2, transaction_id = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $4
+	`
+
+	_, err := tx.ExecContext(ctx, query, confirmation.Status, confirmation.Attempts, confirmation.TransactionID, confirmation.ID)
+	return err
+}
+
+// ExpireConfirmations отклоняет платежи, не подтвержденные до истечения срока кода
+func (r *CardRepository) ExpireConfirmations(ctx context.Context, now time.Time) (int64, error) {
+	query := `
+		UPDATE card_payment_confirmations
+		SET status = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE status = $2 AND expires_at < $3
+	`
+
+	result, err := r.db.ExecContext(ctx, query, models.CardPaymentStatusExpired, models.CardPaymentStatusPendingConfirmation, now)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,179 @@
+package repositories
+
+import (
+	"banksystem/internal/models"
+	"context"
+	"database/sql"
+	"time"
+)
+
+const cardHoldColumns = `id, card_id, account_id, merchant_account_id, terminal_id, rrn, stan, amount, auth_code, status,
+		transaction_id, merchant_name, merchant_mcc, expires_at, created_at`
+
+func scanCardHold(row interface{ Scan(...interface{}) error }, hold *models.CardHold) error {
+	var stan sql.NullString
+	err := row.Scan(
+		&hold.ID,
+		&hold.CardID,
+		&hold.AccountID,
+		&hold.MerchantAccountID,
+		&hold.TerminalID,
+		&hold.RRN,
+		&stan,
+		&hold.Amount,
+		&hold.AuthCode,
+		&hold.Status,
+		&hold.TransactionID,
+		&hold.MerchantName,
+		&hold.MerchantMCC,
+		&hold.ExpiresAt,
+		&hold.CreatedAt,
+	)
+	hold.STAN = stan.String
+	return err
+}
+
+// GetTerminal возвращает зарегистрированный терминал
+func (r *CardRepository) GetTerminal(ctx context.Context, id string) (*models.Terminal, error) {
+	query := `
+		SELECT id, merchant_account_id, merchant_name, merchant_mcc, mac_key, is_active
+		FROM terminals
+		WHERE id = $1
+	`
+
+	terminal := &models.Terminal{}
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&terminal.ID,
+		&terminal.MerchantAccountID,
+		&terminal.MerchantName,
+		&terminal.MerchantMCC,
+		&terminal.MACKey,
+		&terminal.IsActive,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return terminal, nil
+}
+
+// CreateHold сохраняет авторизацию. Возвращает false, если операция с той же парой (terminal_id, rrn) уже есть.
+func (r *CardRepository) CreateHold(ctx context.Context, tx *sql.Tx, hold *models.CardHold) (bool, error) {
+	query := `
+		INSERT INTO card_holds (card_id, account_id, merchant_account_id, terminal_id, rrn, stan, amount, auth_code, status,
+			transaction_id, merchant_name, merchant_mcc, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8, $9, $10, $11, $12, $13, $14)
+		ON CONFLICT (terminal_id, rrn) DO NOTHING
+		RETURNING id
+	`
+
+	err := tx.QueryRowContext(
+		ctx,
+		query,
+		hold.CardID,
+		hold.AccountID,
+		hold.MerchantAccountID,
+		hold.TerminalID,
+		hold.RRN,
+		hold.STAN,
+		hold.Amount,
+		hold.AuthCode,
+		hold.Status,
+		hold.TransactionID,
+		hold.MerchantName,
+		hold.MerchantMCC,
+		hold.ExpiresAt,
+		hold.CreatedAt,
+	).Scan(&hold.ID)
+
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// GetHoldForUpdate находит операцию терминала по RRN и блокирует ее до конца транзакции
+func (r *CardRepository) GetHoldForUpdate(ctx context.Context, tx *sql.Tx, terminalID, rrn string) (*models.CardHold, error) {
+	query := `
+		SELECT ` + cardHoldColumns + `
+		FROM card_holds
+		WHERE terminal_id = $1 AND rrn = $2
+		FOR UPDATE
+	`
+
+	hold := &models.CardHold{}
+	err := scanCardHold(tx.QueryRowContext(ctx, query, terminalID, rrn), hold)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return hold, nil
+}
+
+// UpdateHold сохраняет статус, сумму и связанную операцию авторизации
+func (r *CardRepository) UpdateHold(ctx context.Context, tx *sql.Tx, hold *models.CardHold) error {
+	query := `
+		UPDATE card_holds
+		SET status = $1, amount = $2, transaction_id = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $4
+	`
+
+	_, err := tx.ExecContext(ctx, query, hold.Status, hold.Amount, hold.TransactionID, hold.ID)
+	return err
+}
+
+// GetHeldAmount возвращает сумму действующих блокировок по счету
+func (r *CardRepository) GetHeldAmount(ctx context.Context, tx *sql.Tx, accountID int64) (float64, error) {
+	query := `
+		SELECT COALESCE(SUM(amount), 0)
+		FROM card_holds
+		WHERE account_id = $1 AND status = $2
+	`
+
+	var held float64
+	err := tx.QueryRowContext(ctx, query, accountID, models.CardHoldStatusHeld).Scan(&held)
+	return held, err
+}
+
+// GetCardHeldSince возвращает сумму действующих блокировок по карте, созданных начиная с from
+func (r *CardRepository) GetCardHeldSince(ctx context.Context, tx *sql.Tx, cardID int64, from time.Time) (float64, error) {
+	query := `
+		SELECT COALESCE(SUM(amount), 0)
+		FROM card_holds
+		WHERE card_id = $1 AND status = $2 AND created_at >= $3
+	`
+
+	var held float64
+	err := tx.QueryRowContext(ctx, query, cardID, models.CardHoldStatusHeld, from).Scan(&held)
+	return held, err
+}
+
+// ReleaseExpiredHolds снимает блокировки, которые не были списаны до истечения срока
+func (r *CardRepository) ReleaseExpiredHolds(ctx context.Context, now time.Time) (int64, error) {
+	query := `
+		UPDATE card_holds
+		SET status = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE status = $2 AND expires_at < $3
+	`
+
+	result, err := r.db.ExecContext(ctx, query, models.CardHoldStatusReleased, models.CardHoldStatusHeld, now)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,187 @@
+package repositories
+
+import (
+	"banksystem/internal/models"
+	"context"
+	"database/sql"
+	"time"
+)
+
+const cardMandateColumns = `id, card_id, merchant_account_id, merchant_name, merchant_mcc, merchant_country, amount_cap,
+		frequency, status, last_charged_at, revoked_at, code_hash, otp_attempts, otp_expires_at, created_at`
+
+func scanCardMandate(row interface{ Scan(...interface{}) error }, mandate *models.CardMandate) error {
+	return row.Scan(
+		&mandate.ID,
+		&mandate.CardID,
+		&mandate.MerchantAccountID,
+		&mandate.MerchantName,
+		&mandate.MerchantMCC,
+		&mandate.MerchantCountry,
+		&mandate.AmountCap,
+		&mandate.Frequency,
+		&mandate.Status,
+		&mandate.LastChargedAt,
+		&mandate.RevokedAt,
+		&mandate.CodeHash,
+		&mandate.OTPAttempts,
+		&mandate.OTPExpiresAt,
+		&mandate.CreatedAt,
+	)
+}
+
+// CreateMandate сохраняет разрешение на регулярные списания
+func (r *CardRepository) CreateMandate(ctx context.Context, mandate *models.CardMandate) error {
+	query := `
+		INSERT INTO card_mandates (card_id, merchant_account_id, merchant_name, merchant_mcc, merchant_country,
+			amount_cap, frequency, status, code_hash, otp_expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id
+	`
+
+	return r.db.QueryRowContext(
+		ctx,
+		query,
+		mandate.CardID,
+		mandate.MerchantAccountID,
+		mandate.MerchantName,
+		mandate.MerchantMCC,
+		mandate.MerchantCountry,
+		mandate.AmountCap,
+		mandate.Frequency,
+		mandate.Status,
+		mandate.CodeHash,
+		mandate.OTPExpiresAt,
+		mandate.CreatedAt,
+	).Scan(&mandate.ID)
+}
+
+// GetMandate возвращает разрешение по ID
+func (r *CardRepository) GetMandate(ctx context.Context, id int64) (*models.CardMandate, error) {
+	query := `
+		SELECT ` + cardMandateColumns + `
+		FROM card_mandates
+		WHERE id = $1
+	`
+
+	mandate := &models.CardMandate{}
+	err := scanCardMandate(r.db.QueryRowContext(ctx, query, id), mandate)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return mandate, nil
+}
+
+// GetMandateForUpdate возвращает разрешение и блокирует его до конца транзакции списания
+func (r *CardRepository) GetMandateForUpdate(ctx context.Context, tx *sql.Tx, id int64) (*models.CardMandate, error) {
+	query := `
+		SELECT ` + cardMandateColumns + `
+		FROM card_mandates
+		WHERE id = $1
+		FOR UPDATE
+	`
+
+	mandate := &models.CardMandate{}
+	err := scanCardMandate(tx.QueryRowContext(ctx, query, id), mandate)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return mandate, nil
+}
+
+// GetMandatesByCard возвращает все разрешения по карте, начиная с новых
+func (r *CardRepository) GetMandatesByCard(ctx context.Context, cardID int64) ([]*models.CardMandate, error) {
+	query := `
+		SELECT ` + cardMandateColumns + `
+		FROM card_mandates
+		WHERE card_id = $1
+		ORDER BY created_at DESC, id DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, cardID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var mandates []*models.CardMandate
+	for rows.Next() {
+		mandate := &models.CardMandate{}
+		if err := scanCardMandate(rows, mandate); err != nil {
+			return nil, err
+		}
+		mandates = append(mandates, mandate)
+	}
+
+	return mandates, rows.Err()
+}
+
+// UpdateMandateStatus меняет статус разрешения, если текущий статус равен expected.
+// Возвращает false, если статус успели изменить.
+func (r *CardRepository) UpdateMandateStatus(ctx context.Context, mandate *models.CardMandate, expected string) (bool, error) {
+	query := `
+		UPDATE card_mandates
+		SET status = $1, revoked_at = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3 AND status = $4
+	`
+
+	result, err := r.db.ExecContext(ctx, query, mandate.Status, mandate.RevokedAt, mandate.ID, expected)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// UpdateMandateConfirmation сохраняет результат ввода кода подтверждения разрешения
+func (r *CardRepository) UpdateMandateConfirmation(ctx context.Context, tx *sql.Tx, mandate *models.CardMandate) error {
+	query := `
+		UPDATE card_mandates
+		SET status = $1, revoked_at = $2, code_hash = $3, otp_attempts = $4, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $5
+	`
+
+	_, err := tx.ExecContext(ctx, query, mandate.Status, mandate.RevokedAt, mandate.CodeHash, mandate.OTPAttempts, mandate.ID)
+	return err
+}
+
+// SetMandateCharged запоминает время последнего списания по разрешению
+func (r *CardRepository) SetMandateCharged(ctx context.Context, tx *sql.Tx, id int64, chargedAt time.Time) error {
+	query := `
+		UPDATE card_mandates
+		SET last_charged_at = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2
+	`
+
+	_, err := tx.ExecContext(ctx, query, chargedAt, id)
+	return err
+}
+
+// MoveMandates переносит действующие разрешения на карту, выпущенную взамен
+func (r *CardRepository) MoveMandates(ctx context.Context, tx *sql.Tx, fromCardID, toCardID int64) error {
+	query := `
+		UPDATE card_mandates
+		SET card_id = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE card_id = $2 AND status <> $3
+	`
+
+	_, err := tx.ExecContext(ctx, query, toCardID, fromCardID, models.MandateStatusRevoked)
+	return err
+}
@@ -2,7 +2,12 @@ package repositories
 
 import (
 	"banksystem/internal/models"
+	"context"
 	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
 )
 
 type CardRepository struct {
@@ -13,55 +18,147 @@ func NewCardRepository(db *sql.DB) *CardRepository {
 	return &CardRepository{db: db}
 }
 
-func (r *CardRepository) Create(card *models.Card) error {
+const cardColumns = `c.id, c.account_id, c.encrypted_data, c.hashed_cvv, c.hmac, c.hmac_version, c.key_id, c.pan_hash, c.pan_last4, c.brand, c.expiry_date,
+		c.pin_hash, c.pin_attempts, c.cvv_attempts, c.card_type, c.spend_cap, c.locked_merchant, c.locked_merchant_account_id,
+		c.status, c.block_reason, c.blocked_by, c.replaced_by, c.created_at`
+
+func scanCard(row interface{ Scan(...interface{}) error }, card *models.Card) error {
+	return row.Scan(
+		&card.ID,
+		&card.AccountID,
+		&card.EncryptedData,
+		&card.HashedCVV,
+		&card.HMAC,
+		&card.HMACVersion,
+		&card.KeyID,
+		&card.PANHash,
+		&card.Last4,
+		&card.Brand,
+		&card.ExpiryDate,
+		&card.PINHash,
+		&card.PINAttempts,
+		&card.CVVAttempts,
+		&card.Type,
+		&card.SpendCap,
+		&card.LockedMerchant,
+		&card.LockedMerchantAccountID,
+		&card.Status,
+		&card.BlockReason,
+		&card.BlockedBy,
+		&card.ReplacedBy,
+		&card.CreatedAt,
+	)
+}
+
+func scanCards(rows *sql.Rows) ([]*models.Card, error) {
+	defer rows.Close()
+
+	var cards []*models.Card
+	for rows.Next() {
+		card := &models.Card{}
+		if err := scanCard(rows, card); err != nil {
+			return nil, err
+		}
+		cards = append(cards, card)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return cards, nil
+}
+
+// Create сохраняет карту. Возвращает false, если карта с таким номером (pan_hash) уже существует:
+// конфликт не прерывает транзакцию, и вызывающий может сгенерировать другой номер.
+func (r *CardRepository) Create(ctx context.Context, tx *sql.Tx, card *models.Card) (bool, error) {
 	query := `
-		INSERT INTO cards (account_id, encrypted_data, hashed_cvv, hmac, created_at)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO cards (account_id, encrypted_data, hashed_cvv, hmac, hmac_version, key_id, pan_hash, pan_last4, brand,
+			expiry_date, card_type, spend_cap, locked_merchant, locked_merchant_account_id, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		ON CONFLICT (pan_hash) DO NOTHING
 		RETURNING id
 	`
 
-	return r.db.QueryRow(
+	err := tx.QueryRowContext(
+		ctx,
 		query,
 		card.AccountID,
 		card.EncryptedData,
 		card.HashedCVV,
 		card.HMAC,
+		card.HMACVersion,
+		card.KeyID,
+		card.PANHash,
+		card.Last4,
+		card.Brand,
+		card.ExpiryDate,
+		card.Type,
+		card.SpendCap,
+		card.LockedMerchant,
+		card.LockedMerchantAccountID,
+		card.Status,
 		card.CreatedAt,
 	).Scan(&card.ID)
+
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// GetByPANHash ищет карту по ключевому хешу номера
+func (r *CardRepository) GetByPANHash(ctx context.Context, panHash string) (*models.Card, error) {
+	card := &models.Card{}
+
+	query := `
+		SELECT ` + cardColumns + `
+		FROM cards c
+		WHERE c.pan_hash = $1
+	`
+
+	err := scanCard(r.db.QueryRowContext(ctx, query, panHash), card)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return card, nil
 }
 
 func (r *CardRepository) GetByID(id int64) (*models.Card, error) {
 	card := &models.Card{}
-	
+
 	query := `
-		SELECT id, account_id, encrypted_data, hashed_cvv, hmac, created_at
-		FROM cards
-		WHERE id = $1
+		SELECT ` + cardColumns + `
+		FROM cards c
+		WHERE c.id = $1
 	`
-	
-	err := r.db.QueryRow(query, id).Scan(
-		&card.ID,
-		&card.AccountID,
-		&card.EncryptedData,
-		&card.HashedCVV,
-		&card.HMAC,
-		&card.CreatedAt,
-	)
-	
+
+	err := scanCard(r.db.QueryRow(query, id), card)
+
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
-	
+
 	if err != nil {
 		return nil, err
 	}
-	
+
 	return card, nil
 }
 
 func (r *CardRepository) GetByUserID(userID int64) ([]*models.Card, error) {
 	query := `
-		SELECT c.id, c.account_id, c.encrypted_data, c.hashed_cvv, c.hmac, c.created_at
+		SELECT ` + cardColumns + `
 		FROM cards c
 		JOIN accounts a ON c.account_id = a.id
 		WHERE a.user_id = $1
@@ -71,66 +168,259 @@ func (r *CardRepository) GetByUserID(userID int64) ([]*models.Card, error) {
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var cards []*models.Card
-	for rows.Next() {
-		card := &models.Card{}
-		err := rows.Scan(
-			&card.ID,
-			&card.AccountID,
-			&card.EncryptedData,
-			&card.HashedCVV,
-			&card.HMAC,
-			&card.CreatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-		cards = append(cards, card)
+	return scanCards(rows)
+}
+
+func (r *CardRepository) GetByAccountID(accountID int64) ([]*models.Card, error) {
+	query := `
+		SELECT ` + cardColumns + `
+		FROM cards c
+		WHERE c.account_id = $1
+	`
+
+	rows, err := r.db.Query(query, accountID)
+	if err != nil {
+		return nil, err
 	}
 
-	if err = rows.Err(); err != nil {
+	return scanCards(rows)
+}
+
+// GetWithoutPANHash возвращает пачку карт с ID больше afterID, для которых еще не вычислен pan_hash
+func (r *CardRepository) GetWithoutPANHash(ctx context.Context, afterID int64, limit int) ([]*models.Card, error) {
+	query := `
+		SELECT ` + cardColumns + `
+		FROM cards c
+		WHERE c.id > $1 AND c.pan_hash IS NULL
+		ORDER BY c.id
+		LIMIT $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, afterID, limit)
+	if err != nil {
 		return nil, err
 	}
 
-	return cards, nil
+	return scanCards(rows)
 }
 
-func (r *CardRepository) GetByAccountID(accountID int64) ([]*models.Card, error) {
+// GetForReencryption блокирует и возвращает пачку карт, зашифрованных не ключом keyID.
+// Уже заблокированные другой ротацией строки пропускаются.
+func (r *CardRepository) GetForReencryption(ctx context.Context, tx *sql.Tx, keyID string, afterID int64, limit int) ([]*models.Card, error) {
 	query := `
-		SELECT id, account_id, encrypted_data, hashed_cvv, hmac, created_at
-		FROM cards
-		WHERE account_id = $1
+		SELECT ` + cardColumns + `
+		FROM cards c
+		WHERE c.key_id IS DISTINCT FROM $1 AND c.id > $2
+		ORDER BY c.id
+		LIMIT $3
+		FOR UPDATE SKIP LOCKED
 	`
 
-	rows, err := r.db.Query(query, accountID)
+	rows, err := tx.QueryContext(ctx, query, keyID, afterID, limit)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var cards []*models.Card
-	for rows.Next() {
-		card := &models.Card{}
-		err := rows.Scan(
-			&card.ID,
-			&card.AccountID,
-			&card.EncryptedData,
-			&card.HashedCVV,
-			&card.HMAC,
-			&card.CreatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-		cards = append(cards, card)
+	return scanCards(rows)
+}
+
+func (r *CardRepository) UpdateEncryptedData(ctx context.Context, tx *sql.Tx, card *models.Card) error {
+	query := `
+		UPDATE cards
+		SET encrypted_data = $1, hmac = $2, hmac_version = $3, key_id = $4, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $5
+	`
+
+	_, err := tx.ExecContext(ctx, query, card.EncryptedData, card.HMAC, card.HMACVersion, card.KeyID, card.ID)
+	return err
+}
+
+// GetBatch возвращает до limit карт с id больше afterID в порядке возрастания id
+func (r *CardRepository) GetBatch(ctx context.Context, afterID int64, limit int) ([]*models.Card, error) {
+	query := `
+		SELECT ` + cardColumns + `
+		FROM cards c
+		WHERE c.id > $1
+		ORDER BY c.id
+		LIMIT $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, afterID, limit)
+	if err != nil {
+		return nil, err
 	}
 
-	return cards, nil
+	return scanCards(rows)
+}
+
+// UpdateHMAC переподписывает карту новой версией ключа, если HMAC не изменился с момента проверки
+func (r *CardRepository) UpdateHMAC(ctx context.Context, id int64, oldHMAC, hmac string, version int) error {
+	query := `
+		UPDATE cards
+		SET hmac = $1, hmac_version = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3 AND hmac = $4
+	`
+
+	_, err := r.db.ExecContext(ctx, query, hmac, version, id, oldHMAC)
+	return err
+}
+
+// SetPANHash дозаполняет хеш номера, последние цифры и платежную систему для карт, выпущенных до их появления
+func (r *CardRepository) SetPANHash(ctx context.Context, id int64, panHash, last4, brand string) error {
+	query := `
+		UPDATE cards
+		SET pan_hash = $1, pan_last4 = COALESCE(pan_last4, $2), brand = COALESCE(brand, $3), updated_at = CURRENT_TIMESTAMP
+		WHERE id = $4 AND pan_hash IS NULL
+	`
+
+	_, err := r.db.ExecContext(ctx, query, panHash, last4, brand, id)
+	return err
+}
+
+// SetPIN сохраняет хеш нового PIN-кода и сбрасывает счетчик неверных попыток
+func (r *CardRepository) SetPIN(ctx context.Context, id int64, pinHash string) error {
+	query := `
+		UPDATE cards
+		SET pin_hash = $1, pin_attempts = 0, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2
+	`
+
+	_, err := r.db.ExecContext(ctx, query, pinHash, id)
+	return err
+}
+
+// RegisterPINFailure атомарно увеличивает счетчик неверных попыток ввода PIN. Когда счетчик достигает
+// maxAttempts, активная карта в том же запросе блокируется системой. Возвращает новое значение
+// счетчика и признак того, что карта заблокирована этой попыткой.
+func (r *CardRepository) RegisterPINFailure(ctx context.Context, id int64, maxAttempts int, reason string) (int, bool, error) {
+	return r.registerFailure(ctx, "pin_attempts", id, maxAttempts, reason)
+}
+
+// ResetPINAttempts сбрасывает счетчик неверных попыток после верного PIN
+func (r *CardRepository) ResetPINAttempts(ctx context.Context, id int64) error {
+	return r.resetAttempts(ctx, "pin_attempts", id)
+}
+
+// RegisterCVVFailure то же, что RegisterPINFailure, для неверных попыток ввода CVV
+func (r *CardRepository) RegisterCVVFailure(ctx context.Context, id int64, maxAttempts int, reason string) (int, bool, error) {
+	return r.registerFailure(ctx, "cvv_attempts", id, maxAttempts, reason)
+}
+
+// ResetCVVAttempts сбрасывает счетчик неверных попыток после верного CVV
+func (r *CardRepository) ResetCVVAttempts(ctx context.Context, id int64) error {
+	return r.resetAttempts(ctx, "cvv_attempts", id)
+}
+
+// registerFailure увеличивает счетчик column (pin_attempts или cvv_attempts) и блокирует карту на пороге
+func (r *CardRepository) registerFailure(ctx context.Context, column string, id int64, maxAttempts int, reason string) (int, bool, error) {
+	query := fmt.Sprintf(`
+		UPDATE cards
+		SET %[1]s = %[1]s + 1,
+			status = CASE WHEN %[1]s + 1 >= $2 AND status = $3 THEN $4 ELSE status END,
+			block_reason = CASE WHEN %[1]s + 1 >= $2 AND status = $3 THEN $5 ELSE block_reason END,
+			blocked_by = CASE WHEN %[1]s + 1 >= $2 AND status = $3 THEN $6 ELSE blocked_by END,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING %[1]s, status, blocked_by
+	`, column)
+
+	var attempts int
+	var status string
+	var blockedBy sql.NullString
+	err := r.db.QueryRowContext(ctx, query, id, maxAttempts, models.CardStatusActive, models.CardStatusBlocked,
+		reason, models.CardBlockedBySystem).Scan(&attempts, &status, &blockedBy)
+	if err != nil {
+		return 0, false, err
+	}
+
+	// Счетчик растет на единицу за запрос, поэтому ровно одна попытка достигает порога
+	blocked := attempts == maxAttempts && status == models.CardStatusBlocked && blockedBy.String == models.CardBlockedBySystem
+	return attempts, blocked, nil
 }
 
-func (r *CardRepository) VerifyHMAC(id int64, hmac []byte) (bool, error) {
+func (r *CardRepository) resetAttempts(ctx context.Context, column string, id int64) error {
+	query := fmt.Sprintf(`
+		UPDATE cards
+		SET %[1]s = 0
+		WHERE id = $1 AND %[1]s > 0
+	`, column)
+
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// UpdateStatus меняет статус карты, если он не изменился с момента чтения.
+// Возвращает false, если карту успел изменить другой запрос.
+func (r *CardRepository) UpdateStatus(ctx context.Context, tx *sql.Tx, card *models.Card, fromStatus string) (bool, error) {
+	query := `
+		UPDATE cards
+		SET status = $1, block_reason = $2, blocked_by = $3, updated_at = CURRENT_TIMESTAMP,
+			pin_attempts = CASE WHEN $1 = 'ACTIVE' THEN 0 ELSE pin_attempts END,
+			cvv_attempts = CASE WHEN $1 = 'ACTIVE' THEN 0 ELSE cvv_attempts END
+		WHERE id = $4 AND status = $5
+	`
+
+	result, err := tx.ExecContext(ctx, query, card.Status, card.BlockReason, card.BlockedBy, card.ID, fromStatus)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+// SetReplacedBy связывает карту с картой, выпущенной ей на замену
+func (r *CardRepository) SetReplacedBy(ctx context.Context, tx *sql.Tx, id, replacementID int64) (bool, error) {
+	query := `
+		UPDATE cards
+		SET replaced_by = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2 AND replaced_by IS NULL
+	`
+
+	result, err := tx.ExecContext(ctx, query, replacementID, id)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+// GetExpiring возвращает активные обычные карты без замены, срок действия которых истекает не позже before
+func (r *CardRepository) GetExpiring(ctx context.Context, before time.Time) ([]*models.Card, error) {
+	query := `
+		SELECT ` + cardColumns + `
+		FROM cards c
+		WHERE c.status = $1 AND c.card_type = $2 AND c.replaced_by IS NULL AND c.expiry_date <= $3
+		ORDER BY c.id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, models.CardStatusActive, models.CardTypeStandard, before)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanCards(rows)
+}
+
+// ExpireCards переводит в EXPIRED карты, срок действия которых закончился до today
+func (r *CardRepository) ExpireCards(ctx context.Context, today time.Time) (int64, error) {
+	query := `
+		UPDATE cards
+		SET status = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE status IN ($2, $3) AND expiry_date < $4
+	`
+
+	result, err := r.db.ExecContext(ctx, query, models.CardStatusExpired, models.CardStatusActive, models.CardStatusBlocked, today)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func (r *CardRepository) VerifyHMAC(id int64, hmac string) (bool, error) {
 	query := `
 		SELECT hmac = $1
 		FROM cards
@@ -148,7 +438,7 @@ func (r *CardRepository) VerifyHMAC(id int64, hmac []byte) (bool, error) {
 
 func (r *CardRepository) GetByAccountUserID(userID int64) ([]*models.Card, error) {
 	query := `
-		SELECT c.id, c.account_id, c.encrypted_data, c.hashed_cvv, c.hmac, c.created_at
+		SELECT ` + cardColumns + `
 		FROM cards c
 		JOIN accounts a ON c.account_id = a.id
 		WHERE a.user_id = $1
@@ -158,24 +448,66 @@ func (r *CardRepository) GetByAccountUserID(userID int64) ([]*models.Card, error
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var cards []*models.Card
-	for rows.Next() {
-		card := &models.Card{}
-		err := rows.Scan(
-			&card.ID,
-			&card.AccountID,
-			&card.EncryptedData,
-			&card.HashedCVV,
-			&card.HMAC,
-			&card.CreatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-		cards = append(cards, card)
+	return scanCards(rows)
+}
+
+// GetControls возвращает настройки карты; если они не сохранялись — значения по умолчанию
+func (r *CardRepository) GetControls(ctx context.Context, cardID int64) (*models.CardControls, error) {
+	query := `
+		SELECT card_id, ecom_enabled, contactless_enabled, foreign_enabled, daily_limit, monthly_limit, blocked_mccs, updated_at
+		FROM card_controls
+		WHERE card_id = $1
+	`
+
+	controls := &models.CardControls{}
+	err := r.db.QueryRowContext(ctx, query, cardID).Scan(
+		&controls.CardID,
+		&controls.EcomEnabled,
+		&controls.ContactlessEnabled,
+		&controls.ForeignEnabled,
+		&controls.DailyLimit,
+		&controls.MonthlyLimit,
+		pq.Array(&controls.BlockedMCCs),
+		&controls.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return models.DefaultCardControls(cardID), nil
 	}
 
-	return cards, nil
-} 
\ No newline at end of file
+	if err != nil {
+		return nil, err
+	}
+
+	return controls, nil
+}
+
+// SaveControls создает или обновляет настройки карты
+func (r *CardRepository) SaveControls(ctx context.Context, controls *models.CardControls) error {
+	query := `
+		INSERT INTO card_controls (card_id, ecom_enabled, contactless_enabled, foreign_enabled, daily_limit, monthly_limit, blocked_mccs, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, CURRENT_TIMESTAMP)
+		ON CONFLICT (card_id) DO UPDATE
+		SET ecom_enabled = EXCLUDED.ecom_enabled,
+			contactless_enabled = EXCLUDED.contactless_enabled,
+			foreign_enabled = EXCLUDED.foreign_enabled,
+			daily_limit = EXCLUDED.daily_limit,
+			monthly_limit = EXCLUDED.monthly_limit,
+			blocked_mccs = EXCLUDED.blocked_mccs,
+			updated_at = EXCLUDED.updated_at
+		RETURNING updated_at
+	`
+
+	return r.db.QueryRowContext(
+		ctx,
+		query,
+		controls.CardID,
+		controls.EcomEnabled,
+		controls.ContactlessEnabled,
+		controls.ForeignEnabled,
+		controls.DailyLimit,
+		controls.MonthlyLimit,
+		pq.Array(controls.BlockedMCCs),
+	).Scan(&controls.UpdatedAt)
+}
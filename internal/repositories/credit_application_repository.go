@@ -0,0 +1,179 @@
+package repositories
+
+import (
+	"banksystem/internal/models"
+	"context"
+	"database/sql"
+)
+
+const creditApplicationColumns = `id, user_id, account_id, amount, term_months, schedule_type, key_rate, interest_rate, status,
+		score, score_details, decision_reason, reviewed_by, credit_id, decided_at, created_at`
+
+func scanCreditApplication(row interface{ Scan(...interface{}) error }, application *models.CreditApplication) error {
+	return row.Scan(
+		&application.ID,
+		&application.UserID,
+		&application.AccountID,
+		&application.Amount,
+		&application.TermMonths,
+		&application.ScheduleType,
+		&application.KeyRate,
+		&application.InterestRate,
+		&application.Status,
+		&application.Score,
+		&application.ScoreDetails,
+		&application.DecisionReason,
+		&application.ReviewedBy,
+		&application.CreditID,
+		&application.DecidedAt,
+		&application.CreatedAt,
+	)
+}
+
+// CreateApplication сохраняет поданную заявку на кредит
+func (r *CreditRepository) CreateApplication(ctx context.Context, application *models.CreditApplication) error {
+	query := `
+		INSERT INTO credit_applications (user_id, account_id, amount, term_months, schedule_type, key_rate, interest_rate, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id
+	`
+
+	return r.db.QueryRowContext(
+		ctx,
+		query,
+		application.UserID,
+		application.AccountID,
+		application.Amount,
+		application.TermMonths,
+		application.ScheduleType,
+		application.KeyRate,
+		application.InterestRate,
+		application.Status,
+		application.CreatedAt,
+	).Scan(&application.ID)
+}
+
+// GetApplication возвращает заявку по ID
+func (r *CreditRepository) GetApplication(ctx context.Context, id int64) (*models.CreditApplication, error) {
+	query := `
+		SELECT ` + creditApplicationColumns + `
+		FROM credit_applications
+		WHERE id = $1
+	`
+
+	application := &models.CreditApplication{}
+	err := scanCreditApplication(r.db.QueryRowContext(ctx, query, id), application)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return application, nil
+}
+
+// GetApplicationForUpdate возвращает заявку и блокирует ее до конца транзакции решения
+func (r *CreditRepository) GetApplicationForUpdate(ctx context.Context, tx *sql.Tx, id int64) (*models.CreditApplication, error) {
+	query := `
+		SELECT ` + creditApplicationColumns + `
+		FROM credit_applications
+		WHERE id = $1
+		FOR UPDATE
+	`
+
+	application := &models.CreditApplication{}
+	err := scanCreditApplication(tx.QueryRowContext(ctx, query, id), application)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return application, nil
+}
+
+// GetApplicationsByUser возвращает заявки пользователя, начиная с новых
+func (r *CreditRepository) GetApplicationsByUser(ctx context.Context, userID int64) ([]*models.CreditApplication, error) {
+	query := `
+		SELECT ` + creditApplicationColumns + `
+		FROM credit_applications
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+	`
+
+	return r.queryApplications(ctx, query, userID)
+}
+
+// GetApplicationsByStatus возвращает заявки в статусе status в порядке подачи
+func (r *CreditRepository) GetApplicationsByStatus(ctx context.Context, status string) ([]*models.CreditApplication, error) {
+	query := `
+		SELECT ` + creditApplicationColumns + `
+		FROM credit_applications
+		WHERE status = $1
+		ORDER BY created_at, id
+	`
+
+	return r.queryApplications(ctx, query, status)
+}
+
+func (r *CreditRepository) queryApplications(ctx context.Context, query string, args ...interface{}) ([]*models.CreditApplication, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var applications []*models.CreditApplication
+	for rows.Next() {
+		application := &models.CreditApplication{}
+		if err := scanCreditApplication(rows, application); err != nil {
+			return nil, err
+		}
+		applications = append(applications, application)
+	}
+
+	return applications, rows.Err()
+}
+
+// UpdateApplication сохраняет решение по заявке, если ее текущий статус равен expected.
+// Возвращает false, если статус успели изменить.
+func (r *CreditRepository) UpdateApplication(ctx context.Context, tx *sql.Tx, application *models.CreditApplication, expected string) (bool, error) {
+	query := `
+		UPDATE credit_applications
+		SET status = $1, key_rate = $2, interest_rate = $3, score = $4, score_details = $5, decision_reason = $6,
+			reviewed_by = $7, credit_id = $8, decided_at = $9, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $10 AND status = $11
+	`
+
+	result, err := tx.ExecContext(
+		ctx,
+		query,
+		application.Status,
+		application.KeyRate,
+		application.InterestRate,
+		application.Score,
+		application.ScoreDetails,
+		application.DecisionReason,
+		application.ReviewedBy,
+		application.CreditID,
+		application.DecidedAt,
+		application.ID,
+		expected,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
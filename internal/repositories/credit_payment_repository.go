@@ -7,6 +7,25 @@ import (
 	"time"
 )
 
+const creditPaymentColumns = `id, credit_id, amount, principal, interest, remaining_balance, status, due_date, created_at`
+
+const creditPaymentColumnsP = `p.id, p.credit_id, p.amount, p.principal, p.interest, p.remaining_balance, p.status,
+		p.due_date, p.created_at`
+
+func scanCreditPayment(row interface{ Scan(...interface{}) error }, payment *models.CreditPayment) error {
+	return row.Scan(
+		&payment.ID,
+		&payment.CreditID,
+		&payment.Amount,
+		&payment.Principal,
+		&payment.Interest,
+		&payment.RemainingBalance,
+		&payment.Status,
+		&payment.DueDate,
+		&payment.CreatedAt,
+	)
+}
+
 type CreditPaymentRepository struct {
 	db *sql.DB
 }
@@ -15,37 +34,78 @@ func NewCreditPaymentRepository(db *sql.DB) *CreditPaymentRepository {
 	return &CreditPaymentRepository{db: db}
 }
 
-func (r *CreditPaymentRepository) Create(ctx context.Context, payment *models.CreditPayment) error {
-	query := `
-		INSERT INTO credit_payments (credit_id, amount, status, due_date)
-		VALUES ($1, $2, $3, $4)
+const creditPaymentInsert = `
+		INSERT INTO credit_payments (credit_id, amount, principal, interest, remaining_balance, status, due_date)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING id, created_at
 	`
 
-	return r.db.QueryRowContext(ctx, query,
+func (r *CreditPaymentRepository) Create(ctx context.Context, payment *models.CreditPayment) error {
+	return r.db.QueryRowContext(ctx, creditPaymentInsert,
 		payment.CreditID,
 		payment.Amount,
+		payment.Principal,
+		payment.Interest,
+		payment.RemainingBalance,
 		payment.Status,
 		payment.DueDate,
 	).Scan(&payment.ID, &payment.CreatedAt)
 }
 
+// CreateSchedule сохраняет весь график платежей в транзакции tx
+func (r *CreditPaymentRepository) CreateSchedule(ctx context.Context, tx *sql.Tx, payments []*models.CreditPayment) error {
+	stmt, err := tx.PrepareContext(ctx, creditPaymentInsert)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, payment := range payments {
+		err := stmt.QueryRowContext(ctx,
+			payment.CreditID,
+			payment.Amount,
+			payment.Principal,
+			payment.Interest,
+			payment.RemainingBalance,
+			payment.Status,
+			payment.DueDate,
+		).Scan(&payment.ID, &payment.CreatedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (r *CreditPaymentRepository) GetByID(ctx context.Context, id int64) (*models.CreditPayment, error) {
 	query := `
-		SELECT id, credit_id, amount, status, due_date, created_at
+		SELECT ` + creditPaymentColumns + `
 		FROM credit_payments
 		WHERE id = $1
 	`
 
 	payment := &models.CreditPayment{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&payment.ID,
-		&payment.CreditID,
-		&payment.Amount,
-		&payment.Status,
-		&payment.DueDate,
-		&payment.CreatedAt,
-	)
+	err := scanCreditPayment(r.db.QueryRowContext(ctx, query, id), payment)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	return payment, err
+}
+
+// GetByIDForUpdate возвращает платеж и блокирует его до конца транзакции списания
+func (r *CreditPaymentRepository) GetByIDForUpdate(ctx context.Context, tx *sql.Tx, id int64) (*models.CreditPayment, error) {
+	query := `
+		SELECT ` + creditPaymentColumns + `
+		FROM credit_payments
+		WHERE id = $1
+		FOR UPDATE
+	`
+
+	payment := &models.CreditPayment{}
+	err := scanCreditPayment(tx.QueryRowContext(ctx, query, id), payment)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -56,7 +116,7 @@ func (r *CreditPaymentRepository) GetByID(ctx context.Context, id int64) (*model
 
 func (r *CreditPaymentRepository) GetByCreditID(ctx context.Context, creditID int64) ([]*models.CreditPayment, error) {
 	query := `
-		SELECT id, credit_id, amount, status, due_date, created_at
+		SELECT ` + creditPaymentColumns + `
 		FROM credit_payments
 		WHERE credit_id = $1
 		ORDER BY due_date
@@ -71,15 +131,7 @@ func (r *CreditPaymentRepository) GetByCreditID(ctx context.Context, creditID in
 	var payments []*models.CreditPayment
 	for rows.Next() {
 		payment := &models.CreditPayment{}
-		err := rows.Scan(
-			&payment.ID,
-			&payment.CreditID,
-			&payment.Amount,
-			&payment.Status,
-			&payment.DueDate,
-			&payment.CreatedAt,
-		)
-		if err != nil {
+		if err := scanCreditPayment(rows, payment); err != nil {
 			return nil, err
 		}
 		payments = append(payments, payment)
@@ -90,9 +142,9 @@ func (r *CreditPaymentRepository) GetByCreditID(ctx context.Context, creditID in
 
 func (r *CreditPaymentRepository) GetPending(ctx context.Context) ([]*models.CreditPayment, error) {
 	query := `
-		SELECT id, credit_id, amount, status, due_date, created_at
+		SELECT ` + creditPaymentColumns + `
 		FROM credit_payments
-		WHERE status = 'pending' AND due_date <= $1
+		WHERE status IN ('pending', 'failed') AND due_date <= $1
 		ORDER BY due_date
 	`
 
@@ -105,15 +157,7 @@ func (r *CreditPaymentRepository) GetPending(ctx context.Context) ([]*models.Cre
 	var payments []*models.CreditPayment
 	for rows.Next() {
 		payment := &models.CreditPayment{}
-		err := rows.Scan(
-			&payment.ID,
-			&payment.CreditID,
-			&payment.Amount,
-			&payment.Status,
-			&payment.DueDate,
-			&payment.CreatedAt,
-		)
-		if err != nil {
+		if err := scanCreditPayment(rows, payment); err != nil {
 			return nil, err
 		}
 		payments = append(payments, payment)
@@ -131,4 +175,136 @@ func (r *CreditPaymentRepository) UpdateStatus(ctx context.Context, id int64, st
 
 	_, err := r.db.ExecContext(ctx, query, status, id)
 	return err
-} 
\ No newline at end of file
+}
+
+// UpdateStatusTx меняет статус платежа в транзакции tx
+func (r *CreditPaymentRepository) UpdateStatusTx(ctx context.Context, tx *sql.Tx, id int64, status string) error {
+	query := `
+		UPDATE credit_payments
+		SET status = $1
+		WHERE id = $2
+	`
+
+	_, err := tx.ExecContext(ctx, query, status, id)
+	return err
+}
+
+// CountOverdue возвращает число просроченных несписанных платежей по кредиту
+func (r *CreditPaymentRepository) CountOverdue(ctx context.Context, tx *sql.Tx, creditID int64) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM credit_payments
+		WHERE credit_id = $1 AND status = 'failed'
+	`
+
+	var count int
+	err := tx.QueryRowContext(ctx, query, creditID).Scan(&count)
+	return count, err
+}
+
+// CountUnpaid возвращает число несписанных платежей по кредиту, включая просроченные
+func (r *CreditPaymentRepository) CountUnpaid(ctx context.Context, tx *sql.Tx, creditID int64) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM credit_payments
+		WHERE credit_id = $1 AND status IN ('pending', 'failed')
+	`
+
+	var count int
+	err := tx.QueryRowContext(ctx, query, creditID).Scan(&count)
+	return count, err
+}
+
+// GetUnpaidForUpdate возвращает несписанные платежи по кредиту в порядке сроков
+// и блокирует их, чтобы шедулер не списал платеж во время пересчета графика
+func (r *CreditPaymentRepository) GetUnpaidForUpdate(ctx context.Context, tx *sql.Tx, creditID int64) ([]*models.CreditPayment, error) {
+	query := `
+		SELECT ` + creditPaymentColumns + `
+		FROM credit_payments
+		WHERE credit_id = $1 AND status IN ('pending', 'failed')
+		ORDER BY due_date
+		FOR UPDATE
+	`
+
+	rows, err := tx.QueryContext(ctx, query, creditID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payments []*models.CreditPayment
+	for rows.Next() {
+		payment := &models.CreditPayment{}
+		if err := scanCreditPayment(rows, payment); err != nil {
+			return nil, err
+		}
+		payments = append(payments, payment)
+	}
+
+	return payments, rows.Err()
+}
+
+// DeletePending удаляет будущие платежи по кредиту перед сохранением пересчитанного графика
+func (r *CreditPaymentRepository) DeletePending(ctx context.Context, tx *sql.Tx, creditID int64) error {
+	query := `
+		DELETE FROM credit_payments
+		WHERE credit_id = $1 AND status = 'pending'
+	`
+
+	_, err := tx.ExecContext(ctx, query, creditID)
+	return err
+}
+
+func (r *CreditPaymentRepository) GetByUserID(ctx context.Context, userID int64) ([]*models.CreditPayment, error) {
+	query := `
+		SELECT ` + creditPaymentColumnsP + `
+		FROM credit_payments p
+		JOIN credits c ON p.credit_id = c.id
+		WHERE c.user_id = $1
+		ORDER BY p.due_date
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payments []*models.CreditPayment
+	for rows.Next() {
+		payment := &models.CreditPayment{}
+		if err := scanCreditPayment(rows, payment); err != nil {
+			return nil, err
+		}
+		payments = append(payments, payment)
+	}
+
+	return payments, rows.Err()
+}
+
+func (r *CreditPaymentRepository) GetPendingByAccountID(ctx context.Context, accountID int64) ([]*models.CreditPayment, error) {
+	query := `
+		SELECT ` + creditPaymentColumnsP + `
+		FROM credit_payments p
+		JOIN credits c ON p.credit_id = c.id
+		WHERE c.account_id = $1 AND p.status IN ('pending', 'failed')
+		ORDER BY p.due_date
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payments []*models.CreditPayment
+	for rows.Next() {
+		payment := &models.CreditPayment{}
+		if err := scanCreditPayment(rows, payment); err != nil {
+			return nil, err
+		}
+		payments = append(payments, payment)
+	}
+
+	return payments, rows.Err()
+}
@@ -0,0 +1,144 @@
+package repositories
+
+import (
+	"banksystem/internal/models"
+	"context"
+	"database/sql"
+	"time"
+)
+
+const creditPenaltyColumns = `id, credit_id, payment_id, amount, status, transaction_id, paid_at, created_at`
+
+func scanCreditPenalty(row interface{ Scan(...interface{}) error }, penalty *models.CreditPenalty) error {
+	return row.Scan(
+		&penalty.ID,
+		&penalty.CreditID,
+		&penalty.PaymentID,
+		&penalty.Amount,
+		&penalty.Status,
+		&penalty.TransactionID,
+		&penalty.PaidAt,
+		&penalty.CreatedAt,
+	)
+}
+
+// CreatePenalty начисляет штраф за пропущенный платеж.
+// Возвращает false, если штраф по этому платежу уже был начислен.
+func (r *CreditPaymentRepository) CreatePenalty(ctx context.Context, tx *sql.Tx, penalty *models.CreditPenalty) (bool, error) {
+	query := `
+		INSERT INTO credit_penalties (credit_id, payment_id, amount, status, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (payment_id) DO NOTHING
+		RETURNING id
+	`
+
+	err := tx.QueryRowContext(
+		ctx,
+		query,
+		penalty.CreditID,
+		penalty.PaymentID,
+		penalty.Amount,
+		penalty.Status,
+		penalty.CreatedAt,
+	).Scan(&penalty.ID)
+
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// GetPenaltyByPayment возвращает штраф, начисленный за платеж paymentID
+func (r *CreditPaymentRepository) GetPenaltyByPayment(ctx context.Context, tx *sql.Tx, paymentID int64) (*models.CreditPenalty, error) {
+	query := `
+		SELECT ` + creditPenaltyColumns + `
+		FROM credit_penalties
+		WHERE payment_id = $1
+		FOR UPDATE
+	`
+
+	penalty := &models.CreditPenalty{}
+	err := scanCreditPenalty(tx.QueryRowContext(ctx, query, paymentID), penalty)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return penalty, nil
+}
+
+// SetPenaltyPaid отмечает штраф списанным операцией transactionID
+func (r *CreditPaymentRepository) SetPenaltyPaid(ctx context.Context, tx *sql.Tx, id, transactionID int64, paidAt time.Time) error {
+	query := `
+		UPDATE credit_penalties
+		SET status = $1, transaction_id = $2, paid_at = $3
+		WHERE id = $4
+	`
+
+	_, err := tx.ExecContext(ctx, query, models.CreditPenaltyStatusPaid, transactionID, paidAt, id)
+	return err
+}
+
+// GetPenaltiesByCreditID возвращает штрафы по кредиту в порядке начисления
+func (r *CreditPaymentRepository) GetPenaltiesByCreditID(ctx context.Context, creditID int64) ([]*models.CreditPenalty, error) {
+	query := `
+		SELECT ` + creditPenaltyColumns + `
+		FROM credit_penalties
+		WHERE credit_id = $1
+		ORDER BY created_at, id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, creditID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var penalties []*models.CreditPenalty
+	for rows.Next() {
+		penalty := &models.CreditPenalty{}
+		if err := scanCreditPenalty(rows, penalty); err != nil {
+			return nil, err
+		}
+		penalties = append(penalties, penalty)
+	}
+
+	return penalties, rows.Err()
+}
+
+// CountPenaltiesByUserID возвращает число штрафов за просрочку по всем кредитам пользователя
+func (r *CreditPaymentRepository) CountPenaltiesByUserID(ctx context.Context, userID int64) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM credit_penalties p
+		JOIN credits c ON p.credit_id = c.id
+		WHERE c.user_id = $1
+	`
+
+	var count int
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&count)
+	return count, err
+}
+
+// SumUnpaidPenaltiesByUserID возвращает сумму еще не списанных штрафов по всем кредитам пользователя
+func (r *CreditPaymentRepository) SumUnpaidPenaltiesByUserID(ctx context.Context, userID int64) (float64, error) {
+	query := `
+		SELECT COALESCE(SUM(p.amount), 0)
+		FROM credit_penalties p
+		JOIN credits c ON p.credit_id = c.id
+		WHERE c.user_id = $1 AND p.status = $2
+	`
+
+	var sum float64
+	err := r.db.QueryRowContext(ctx, query, userID, models.CreditPenaltyStatusPending).Scan(&sum)
+	return sum, err
+}
@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"banksystem/internal/models"
+	"context"
 	"database/sql"
 	"time"
 )
@@ -16,20 +17,26 @@ func NewCreditRepository(db *sql.DB) *CreditRepository {
 	}
 }
 
-func (r *CreditRepository) Create(credit *models.Credit) error {
+// Create сохраняет кредит в транзакции выдачи, чтобы он не остался без графика платежей
+func (r *CreditRepository) Create(ctx context.Context, tx *sql.Tx, credit *models.Credit) error {
 	query := `
-		INSERT INTO credits (user_id, amount, term_months, interest_rate, status, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO credits (user_id, account_id, amount, term_months, interest_rate, schedule_type, status, key_rate, key_rate_date, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 		RETURNING id
 	`
 
-	err := r.db.QueryRow(
+	err := tx.QueryRowContext(
+		ctx,
 		query,
 		credit.UserID,
+		credit.AccountID,
 		credit.Amount,
 		credit.TermMonths,
 		credit.InterestRate,
+		credit.ScheduleType,
 		credit.Status,
+		credit.KeyRate,
+		credit.KeyRateDate,
 		time.Now(),
 	).Scan(&credit.ID)
 
@@ -42,7 +49,7 @@ func (r *CreditRepository) Create(credit *models.Credit) error {
 
 func (r *CreditRepository) GetByID(id int) (*models.Credit, error) {
 	query := `
-		SELECT id, user_id, amount, term_months, interest_rate, status, created_at
+		SELECT id, user_id, account_id, amount, term_months, interest_rate, schedule_type, status, key_rate, key_rate_date, created_at
 		FROM credits
 		WHERE id = $1
 	`
@@ -51,10 +58,14 @@ func (r *CreditRepository) GetByID(id int) (*models.Credit, error) {
 	err := r.db.QueryRow(query, id).Scan(
 		&credit.ID,
 		&credit.UserID,
+		&credit.AccountID,
 		&credit.Amount,
 		&credit.TermMonths,
 		&credit.InterestRate,
+		&credit.ScheduleType,
 		&credit.Status,
+		&credit.KeyRate,
+		&credit.KeyRateDate,
 		&credit.CreatedAt,
 	)
 
@@ -68,9 +79,43 @@ func (r *CreditRepository) GetByID(id int) (*models.Credit, error) {
 	return credit, nil
 }
 
+// GetByIDForUpdate возвращает кредит и блокирует его до конца транзакции
+func (r *CreditRepository) GetByIDForUpdate(ctx context.Context, tx *sql.Tx, id int64) (*models.Credit, error) {
+	query := `
+		SELECT id, user_id, account_id, amount, term_months, interest_rate, schedule_type, status, key_rate, key_rate_date, created_at
+		FROM credits
+		WHERE id = $1
+		FOR UPDATE
+	`
+
+	credit := &models.Credit{}
+	err := tx.QueryRowContext(ctx, query, id).Scan(
+		&credit.ID,
+		&credit.UserID,
+		&credit.AccountID,
+		&credit.Amount,
+		&credit.TermMonths,
+		&credit.InterestRate,
+		&credit.ScheduleType,
+		&credit.Status,
+		&credit.KeyRate,
+		&credit.KeyRateDate,
+		&credit.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return credit, nil
+}
+
 func (r *CreditRepository) GetByUserID(userID int) ([]*models.Credit, error) {
 	query := `
-		SELECT id, user_id, amount, term_months, interest_rate, status, created_at
+		SELECT id, user_id, account_id, amount, term_months, interest_rate, schedule_type, status, key_rate, key_rate_date, created_at
 		FROM credits
 		WHERE user_id = $1
 		ORDER BY created_at DESC
@@ -88,10 +133,14 @@ func (r *CreditRepository) GetByUserID(userID int) ([]*models.Credit, error) {
 		err := rows.Scan(
 			&credit.ID,
 			&credit.UserID,
+			&credit.AccountID,
 			&credit.Amount,
 			&credit.TermMonths,
 			&credit.InterestRate,
+			&credit.ScheduleType,
 			&credit.Status,
+			&credit.KeyRate,
+			&credit.KeyRateDate,
 			&credit.CreatedAt,
 		)
 		if err != nil {
@@ -116,4 +165,16 @@ func (r *CreditRepository) UpdateStatus(id int, status string) error {
 	}
 
 	return nil
-} 
\ No newline at end of file
+}
+
+// UpdateStatusTx меняет статус кредита в транзакции tx
+func (r *CreditRepository) UpdateStatusTx(ctx context.Context, tx *sql.Tx, id int64, status string) error {
+	query := `
+		UPDATE credits
+		SET status = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2
+	`
+
+	_, err := tx.ExecContext(ctx, query, status, id)
+	return err
+}
@@ -0,0 +1,107 @@
+package repositories
+
+import (
+	"banksystem/internal/models"
+	"context"
+	"database/sql"
+	"time"
+)
+
+type KeyRateRepository struct {
+	db *sql.DB
+}
+
+func NewKeyRateRepository(db *sql.DB) *KeyRateRepository {
+	return &KeyRateRepository{db: db}
+}
+
+const keyRateColumns = `date, rate, fetched_at`
+
+func scanKeyRate(row interface{ Scan(...interface{}) error }, rate *models.KeyRate) error {
+	return row.Scan(
+		&rate.Date,
+		&rate.Rate,
+		&rate.FetchedAt,
+	)
+}
+
+// Save сохраняет ставки в историю; ставка за уже сохраненную дату перезаписывается
+func (r *KeyRateRepository) Save(ctx context.Context, rates []*models.KeyRate) error {
+	if len(rates) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO key_rates (date, rate, fetched_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (date) DO UPDATE SET rate = EXCLUDED.rate, fetched_at = EXCLUDED.fetched_at
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, rate := range rates {
+		if _, err := stmt.ExecContext(ctx, rate.Date, rate.Rate, rate.FetchedAt); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetRange возвращает ставки за даты с from по to включительно в порядке возрастания даты
+func (r *KeyRateRepository) GetRange(ctx context.Context, from, to time.Time) ([]*models.KeyRate, error) {
+	query := `
+		SELECT ` + keyRateColumns + `
+		FROM key_rates
+		WHERE date BETWEEN $1 AND $2
+		ORDER BY date
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rates []*models.KeyRate
+	for rows.Next() {
+		rate := &models.KeyRate{}
+		if err := scanKeyRate(rows, rate); err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+
+	return rates, rows.Err()
+}
+
+// GetLatest возвращает ставку за последнюю сохраненную дату
+func (r *KeyRateRepository) GetLatest(ctx context.Context) (*models.KeyRate, error) {
+	query := `
+		SELECT ` + keyRateColumns + `
+		FROM key_rates
+		ORDER BY date DESC
+		LIMIT 1
+	`
+
+	rate := &models.KeyRate{}
+	err := scanKeyRate(r.db.QueryRowContext(ctx, query), rate)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return rate, nil
+}
@@ -4,6 +4,9 @@ import (
 	"banksystem/internal/models"
 	"context"
 	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
 )
 
 type TransactionRepository struct {
@@ -14,10 +17,49 @@ func NewTransactionRepository(db *sql.DB) *TransactionRepository {
 	return &TransactionRepository{db: db}
 }
 
+// transactionColumns список колонок операции в порядке сканирования scanTransaction
+const transactionColumns = `t.id, t.account_id, t.type, t.amount, t.status, t.to_account_id, t.category, t.note,
+		t.description, t.counterparty, t.card_id, t.merchant_mcc, t.created_at`
+
+func scanTransaction(row interface{ Scan(...interface{}) error }, transaction *models.Transaction, extra ...interface{}) error {
+	dest := []interface{}{
+		&transaction.ID,
+		&transaction.AccountID,
+		&transaction.Type,
+		&transaction.Amount,
+		&transaction.Status,
+		&transaction.ToAccountID,
+		&transaction.Category,
+		&transaction.Note,
+		&transaction.Description,
+		&transaction.Counterparty,
+		&transaction.CardID,
+		&transaction.MerchantMCC,
+		&transaction.CreatedAt,
+	}
+	return row.Scan(append(dest, extra...)...)
+}
+
+func scanTransactions(rows *sql.Rows) ([]*models.Transaction, error) {
+	defer rows.Close()
+
+	var transactions []*models.Transaction
+	for rows.Next() {
+		transaction := &models.Transaction{}
+		if err := scanTransaction(rows, transaction); err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	return transactions, rows.Err()
+}
+
 func (r *TransactionRepository) Create(ctx context.Context, tx *sql.Tx, transaction *models.Transaction) (*models.Transaction, error) {
 	query := `
-		INSERT INTO transactions (account_id, type, amount, status, to_account_id, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO transactions (account_id, type, amount, status, to_account_id, category, description,
+			counterparty, card_id, merchant_mcc, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 		RETURNING id, created_at
 	`
 
@@ -29,6 +71,11 @@ func (r *TransactionRepository) Create(ctx context.Context, tx *sql.Tx, transact
 		transaction.Amount,
 		transaction.Status,
 		transaction.ToAccountID,
+		transaction.Category,
+		transaction.Description,
+		transaction.Counterparty,
+		transaction.CardID,
+		transaction.MerchantMCC,
 		transaction.CreatedAt,
 	).Scan(&transaction.ID, &transaction.CreatedAt)
 
@@ -41,21 +88,13 @@ func (r *TransactionRepository) Create(ctx context.Context, tx *sql.Tx, transact
 
 func (r *TransactionRepository) GetByID(ctx context.Context, id int64) (*models.Transaction, error) {
 	query := `
-		SELECT id, account_id, type, amount, status, to_account_id, created_at
-		FROM transactions
-		WHERE id = $1
+		SELECT ` + transactionColumns + `
+		FROM transactions t
+		WHERE t.id = $1
 	`
 
 	transaction := &models.Transaction{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&transaction.ID,
-		&transaction.AccountID,
-		&transaction.Type,
-		&transaction.Amount,
-		&transaction.Status,
-		&transaction.ToAccountID,
-		&transaction.CreatedAt,
-	)
+	err := scanTransaction(r.db.QueryRowContext(ctx, query, id), transaction)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -66,37 +105,18 @@ func (r *TransactionRepository) GetByID(ctx context.Context, id int64) (*models.
 
 func (r *TransactionRepository) GetByAccountID(ctx context.Context, accountID int64) ([]*models.Transaction, error) {
 	query := `
-		SELECT id, account_id, type, amount, status, to_account_id, created_at
-		FROM transactions
-		WHERE account_id = $1
-		ORDER BY created_at DESC
+		SELECT ` + transactionColumns + `
+		FROM transactions t
+		WHERE t.account_id = $1
+		ORDER BY t.created_at DESC
 	`
 
 	rows, err := r.db.QueryContext(ctx, query, accountID)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var transactions []*models.Transaction
-	for rows.Next() {
-		transaction := &models.Transaction{}
-		err := rows.Scan(
-			&transaction.ID,
-			&transaction.AccountID,
-			&transaction.Type,
-			&transaction.Amount,
-			&transaction.Status,
-			&transaction.ToAccountID,
-			&transaction.CreatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-		transactions = append(transactions, transaction)
-	}
-
-	return transactions, rows.Err()
+	return scanTransactions(rows)
 }
 
 func (r *TransactionRepository) UpdateStatus(ctx context.Context, tx *sql.Tx, id int64, status string) error {
@@ -108,4 +128,190 @@ func (r *TransactionRepository) UpdateStatus(ctx context.Context, tx *sql.Tx, id
 
 	_, err := tx.ExecContext(ctx, query, status, id)
 	return err
-} 
\ No newline at end of file
+}
+
+// GetIncomeByUserID возвращает сумму поступлений на счета пользователя начиная с from:
+// пополнения и входящие переводы со счетов других пользователей
+func (r *TransactionRepository) GetIncomeByUserID(ctx context.Context, userID int64, from time.Time) (float64, error) {
+	query := `
+		SELECT COALESCE(SUM(t.amount), 0)
+		FROM transactions t
+		JOIN accounts a ON t.account_id = a.id
+		LEFT JOIN accounts dst ON t.to_account_id = dst.id
+		WHERE t.created_at >= $2
+		  AND (
+			(a.user_id = $1 AND t.type = 'deposit')
+			OR (t.type = 'transfer' AND dst.user_id = $1 AND a.user_id <> $1)
+		  )
+	`
+
+	var income float64
+	err := r.db.QueryRowContext(ctx, query, userID, from).Scan(&income)
+	if err != nil {
+		return 0, err
+	}
+
+	return income, nil
+}
+
+// CountByUserID возвращает число завершенных операций по счетам пользователя начиная с from
+func (r *TransactionRepository) CountByUserID(ctx context.Context, userID int64, from time.Time) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM transactions t
+		JOIN accounts a ON t.account_id = a.id
+		WHERE a.user_id = $1 AND t.created_at >= $2 AND LOWER(t.status) = 'completed'
+	`
+
+	var count int
+	err := r.db.QueryRowContext(ctx, query, userID, from).Scan(&count)
+	return count, err
+}
+
+// GetHistoryByAccountID возвращает исходящие и входящие операции по счету начиная с from
+func (r *TransactionRepository) GetHistoryByAccountID(ctx context.Context, accountID int64, from time.Time) ([]*models.Transaction, error) {
+	query := `
+		SELECT ` + transactionColumns + `
+		FROM transactions t
+		WHERE (t.account_id = $1 OR t.to_account_id = $1) AND t.created_at >= $2
+		ORDER BY t.created_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, accountID, from)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanTransactions(rows)
+}
+
+// GetByAccountIDAndTag возвращает операции по счету, помеченные тегом
+func (r *TransactionRepository) GetByAccountIDAndTag(ctx context.Context, accountID int64, tag string) ([]*models.Transaction, error) {
+	query := `
+		SELECT ` + transactionColumns + `
+		FROM transactions t
+		JOIN transaction_tags tt ON tt.transaction_id = t.id
+		WHERE t.account_id = $1 AND tt.tag = $2
+		ORDER BY t.created_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, accountID, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanTransactions(rows)
+}
+
+// GetCardSpentSince возвращает сумму успешных платежей и переводов по карте начиная с from
+func (r *TransactionRepository) GetCardSpentSince(ctx context.Context, tx *sql.Tx, cardID int64, from time.Time) (float64, error) {
+	query := `
+		SELECT COALESCE(SUM(amount), 0)
+		FROM transactions
+		WHERE card_id = $1 AND UPPER(type) IN ($2, $3) AND UPPER(status) = $4 AND created_at >= $5
+	`
+
+	// Переводы по номеру карты записываются как 'transfer'/'completed', поэтому сравнение без учета регистра
+	var spent float64
+	err := tx.QueryRowContext(ctx, query, cardID, models.TransactionTypePayment, models.TransactionTypeTransfer,
+		models.TransactionStatusCompleted, from).Scan(&spent)
+	return spent, err
+}
+
+func (r *TransactionRepository) UpdateNote(ctx context.Context, id int64, note string) error {
+	query := `
+		UPDATE transactions
+		SET note = NULLIF($1, '')
+		WHERE id = $2
+	`
+
+	_, err := r.db.ExecContext(ctx, query, note, id)
+	return err
+}
+
+// SetTags заменяет набор тегов операции
+func (r *TransactionRepository) SetTags(ctx context.Context, tx *sql.Tx, id int64, tags []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM transaction_tags WHERE transaction_id = $1`, id); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO transaction_tags (transaction_id, tag)
+		SELECT $1, UNNEST($2::VARCHAR[])
+	`
+
+	_, err := tx.ExecContext(ctx, query, id, pq.Array(tags))
+	return err
+}
+
+// GetTags возвращает теги для набора операций
+func (r *TransactionRepository) GetTags(ctx context.Context, ids []int64) (map[int64][]string, error) {
+	query := `
+		SELECT transaction_id, tag
+		FROM transaction_tags
+		WHERE transaction_id = ANY($1)
+		ORDER BY tag
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make(map[int64][]string)
+	for rows.Next() {
+		var id int64
+		var tag string
+		if err := rows.Scan(&id, &tag); err != nil {
+			return nil, err
+		}
+		tags[id] = append(tags[id], tag)
+	}
+
+	return tags, rows.Err()
+}
+
+// Search выполняет полнотекстовый поиск по операциям счетов пользователя.
+// Если запрос является числом, дополнительно ищутся операции с такой суммой или номером.
+func (r *TransactionRepository) Search(ctx context.Context, userID int64, text string, amount sql.NullFloat64, reference sql.NullInt64, limit, offset int) ([]*models.Transaction, int, error) {
+	query := `
+		WITH q AS (
+			SELECT websearch_to_tsquery('russian', $2) || websearch_to_tsquery('english', $2) AS query
+		)
+		SELECT ` + transactionColumns + `, COUNT(*) OVER () AS total
+		FROM transactions t, q
+		WHERE (
+			t.account_id IN (SELECT id FROM accounts WHERE user_id = $1)
+			OR t.to_account_id IN (SELECT id FROM accounts WHERE user_id = $1)
+		)
+		AND (
+			t.search_vector @@ q.query
+			OR ($3::DECIMAL IS NOT NULL AND t.amount = $3)
+			OR ($4::BIGINT IS NOT NULL AND t.id = $4)
+		)
+		ORDER BY
+			(t.id = $4) IS TRUE DESC,
+			ts_rank(t.search_vector, q.query) DESC,
+			t.created_at DESC
+		LIMIT $5 OFFSET $6
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, text, amount, reference, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var transactions []*models.Transaction
+	var total int
+	for rows.Next() {
+		transaction := &models.Transaction{}
+		if err := scanTransaction(rows, transaction, &total); err != nil {
+			return nil, 0, err
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	return transactions, total, rows.Err()
+}
@@ -6,6 +6,8 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"log"
+	"math"
 	"time"
 )
 
@@ -15,6 +17,7 @@ type AccountService struct {
 	userRepo        *repositories.UserRepository
 	db              *sql.DB
 	smtpService     *SMTPService
+	budgetService   *BudgetService
 }
 
 func NewAccountService(
@@ -23,6 +26,7 @@ func NewAccountService(
 	transactionRepo *repositories.TransactionRepository,
 	userRepo *repositories.UserRepository,
 	smtpService *SMTPService,
+	budgetService *BudgetService,
 ) *AccountService {
 	return &AccountService{
 		db:              db,
@@ -30,6 +34,7 @@ func NewAccountService(
 		transactionRepo: transactionRepo,
 		userRepo:        userRepo,
 		smtpService:     smtpService,
+		budgetService:   budgetService,
 	}
 }
 
@@ -41,12 +46,12 @@ func (s *AccountService) CreateAccount(ctx context.Context, userID int64, accoun
 	defer tx.Rollback()
 
 	account := &models.Account{
-		UserID:      userID,
-		Type:        accountType,
-		Balance:     0,
-		IsActive:    true,
-		CreatedAt:   sql.NullTime{Time: time.Now(), Valid: true},
-		UpdatedAt:   sql.NullTime{Time: time.Now(), Valid: true},
+		UserID:    userID,
+		Type:      accountType,
+		Balance:   0,
+		IsActive:  true,
+		CreatedAt: sql.NullTime{Time: time.Now(), Valid: true},
+		UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true},
 	}
 
 	account, err = s.accountRepo.Create(ctx, tx, account)
@@ -101,6 +106,7 @@ func (s *AccountService) Deposit(ctx context.Context, accountID int64, amount fl
 		Type:        "deposit",
 		Amount:      amount,
 		Status:      "completed",
+		Description: sql.NullString{String: "Пополнение счета", Valid: true},
 		CreatedAt:   sql.NullTime{Time: time.Now(), Valid: true},
 	}
 
@@ -124,7 +130,7 @@ func (s *AccountService) Deposit(ctx context.Context, accountID int64, amount fl
 	return nil
 }
 
-func (s *AccountService) Withdraw(ctx context.Context, accountID int64, amount float64) error {
+func (s *AccountService) Withdraw(ctx context.Context, accountID int64, amount float64, category string) error {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %v", err)
@@ -152,6 +158,8 @@ func (s *AccountService) Withdraw(ctx context.Context, accountID int64, amount f
 		Type:        "withdraw",
 		Amount:      amount,
 		Status:      "completed",
+		Category:    sql.NullString{String: category, Valid: category != ""},
+		Description: sql.NullString{String: "Снятие средств со счета", Valid: true},
 		CreatedAt:   sql.NullTime{Time: time.Now(), Valid: true},
 	}
 
@@ -159,9 +167,15 @@ func (s *AccountService) Withdraw(ctx context.Context, accountID int64, amount f
 		return fmt.Errorf("failed to create transaction: %v", err)
 	}
 
+	sendBudgetAlerts, err := s.budgetService.TrackSpending(ctx, tx, account.UserID, accountID, category, amount)
+	if err != nil {
+		return err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %v", err)
 	}
+	sendBudgetAlerts()
 
 	user, err := s.userRepo.GetByID(ctx, account.UserID)
 	if err != nil {
@@ -181,81 +195,207 @@ func (s *AccountService) Withdraw(ctx context.Context, accountID int64, amount f
 	return nil
 }
 
-func (s *AccountService) Transfer(ctx context.Context, fromAccountID, toAccountID int64, amount float64) error {
+func (s *AccountService) Transfer(ctx context.Context, fromAccountID, toAccountID int64, amount float64, category string) error {
+	_, _, err := s.TransferFunds(ctx, &TransferParams{
+		FromAccountID: fromAccountID,
+		ToAccountID:   toAccountID,
+		Amount:        amount,
+		Category:      category,
+	})
+	return err
+}
+
+// TransferFee рассчитывает комиссию за перевод. Комиссия списывается со счета отправителя
+// сверх суммы перевода отдельной операцией FEE.
+type TransferFee func(from, to *models.Account, amount float64) float64
+
+// PercentTransferFee комиссия в процентах от суммы перевода, но не меньше min.
+// Переводы между счетами одного владельца проводятся без комиссии.
+func PercentTransferFee(percent, min float64) TransferFee {
+	return func(from, to *models.Account, amount float64) float64 {
+		if from.UserID == to.UserID || (percent <= 0 && min <= 0) {
+			return 0
+		}
+		fee := math.Round(amount*percent) / 100
+		if fee < min {
+			fee = min
+		}
+		return fee
+	}
+}
+
+// TransferParams параметры перевода между счетами
+type TransferParams struct {
+	FromAccountID int64
+	ToAccountID   int64
+	// Если задан, счет списания должен принадлежать этому пользователю
+	UserID   int64
+	Amount   float64
+	Category string
+	// Описание операции; по умолчанию «Перевод на счет N»
+	Description string
+	// Карта отправителя, если перевод сделан по номеру карты
+	CardID int64
+	Fee    TransferFee
+	// Дополнительная проверка в транзакции перевода после блокировки счетов и расчета комиссии,
+	// например лимиты, блокировки средств и ограничения карты отправителя
+	Check TransferCheck
+}
+
+// TransferCheck проверяет перевод суммы amount с комиссией fee со счета from внутри транзакции tx
+type TransferCheck func(ctx context.Context, tx *sql.Tx, from *models.Account, amount, fee float64) error
+
+// TransferFunds переводит сумму между счетами и возвращает операцию перевода и удержанную комиссию.
+// Счета блокируются в порядке возрастания ID, перевод, комиссия и учет в бюджетах проводятся в одной транзакции.
+func (s *AccountService) TransferFunds(ctx context.Context, p *TransferParams) (*models.Transaction, float64, error) {
+	if p.FromAccountID == p.ToAccountID {
+		return nil, 0, models.ErrInvalidAccountID
+	}
+
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %v", err)
+		return nil, 0, fmt.Errorf("failed to begin transaction: %v", err)
 	}
 	defer tx.Rollback()
 
-	fromAccount, err := s.accountRepo.GetByID(ctx, fromAccountID)
+	fromAccount, toAccount, err := s.lockTransferAccounts(ctx, tx, p.FromAccountID, p.ToAccountID)
 	if err != nil {
-		return fmt.Errorf("failed to get source account: %v", err)
+		return nil, 0, err
+	}
+	if p.UserID != 0 && fromAccount.UserID != p.UserID {
+		return nil, 0, models.ErrAccessDenied
 	}
 
-	if fromAccount.Balance < amount {
-		return fmt.Errorf("insufficient funds")
+	var fee float64
+	if p.Fee != nil {
+		fee = p.Fee(fromAccount, toAccount, p.Amount)
 	}
 
-	toAccount, err := s.accountRepo.GetByID(ctx, toAccountID)
+	if fromAccount.Balance < p.Amount+fee {
+		return nil, 0, models.ErrInsufficientFunds
+	}
+	if p.Check != nil {
+		if err := p.Check(ctx, tx, fromAccount, p.Amount, fee); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	toUser, err := s.userRepo.GetByID(ctx, toAccount.UserID)
 	if err != nil {
-		return fmt.Errorf("failed to get destination account: %v", err)
+		return nil, 0, fmt.Errorf("failed to get destination user: %v", err)
 	}
 
-	fromAccount.Balance -= amount
-	toAccount.Balance += amount
+	fromAccount.Balance -= p.Amount + fee
+	toAccount.Balance += p.Amount
 	now := time.Now()
 	fromAccount.UpdatedAt = sql.NullTime{Time: now, Valid: true}
 	toAccount.UpdatedAt = sql.NullTime{Time: now, Valid: true}
 
 	if err := s.accountRepo.Update(ctx, tx, fromAccount); err != nil {
-		return fmt.Errorf("failed to update source account: %v", err)
+		return nil, 0, fmt.Errorf("failed to update source account: %v", err)
 	}
 
 	if err := s.accountRepo.Update(ctx, tx, toAccount); err != nil {
-		return fmt.Errorf("failed to update destination account: %v", err)
+		return nil, 0, fmt.Errorf("failed to update destination account: %v", err)
+	}
+
+	description := p.Description
+	if description == "" {
+		description = fmt.Sprintf("Перевод на счет %d", toAccount.ID)
 	}
 
 	transaction := &models.Transaction{
-		AccountID:          fromAccountID,
-		Type:              "transfer",
-		Amount:            amount,
-		Status:            "completed",
-		ToAccountID:       sql.NullInt64{Int64: toAccountID, Valid: true},
-		CreatedAt:         sql.NullTime{Time: now, Valid: true},
+		AccountID:    fromAccount.ID,
+		Type:         "transfer",
+		Amount:       p.Amount,
+		Status:       "completed",
+		ToAccountID:  sql.NullInt64{Int64: toAccount.ID, Valid: true},
+		Category:     sql.NullString{String: p.Category, Valid: p.Category != ""},
+		Description:  sql.NullString{String: description, Valid: true},
+		Counterparty: sql.NullString{String: toUser.Username, Valid: true},
+		CardID:       sql.NullInt64{Int64: p.CardID, Valid: p.CardID != 0},
+		CreatedAt:    sql.NullTime{Time: now, Valid: true},
 	}
 
 	if _, err := s.transactionRepo.Create(ctx, tx, transaction); err != nil {
-		return fmt.Errorf("failed to create transaction: %v", err)
+		return nil, 0, fmt.Errorf("failed to create transaction: %v", err)
+	}
+
+	if fee > 0 {
+		feeTransaction := &models.Transaction{
+			AccountID:   fromAccount.ID,
+			Type:        models.TransactionTypeFee,
+			Amount:      fee,
+			Status:      models.TransactionStatusCompleted,
+			Description: sql.NullString{String: fmt.Sprintf("Комиссия за перевод %d", transaction.ID), Valid: true},
+			CardID:      transaction.CardID,
+			CreatedAt:   sql.NullTime{Time: now, Valid: true},
+		}
+		if _, err := s.transactionRepo.Create(ctx, tx, feeTransaction); err != nil {
+			return nil, 0, fmt.Errorf("failed to create fee transaction: %v", err)
+		}
+	}
+
+	// Переводы между своими счетами не считаются расходом
+	sendBudgetAlerts := func() {}
+	if fromAccount.UserID != toAccount.UserID {
+		sendBudgetAlerts, err = s.budgetService.TrackSpending(ctx, tx, fromAccount.UserID, fromAccount.ID, p.Category, p.Amount)
+		if err != nil {
+			return nil, 0, err
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %v", err)
+		return nil, 0, fmt.Errorf("failed to commit transaction: %v", err)
 	}
+	sendBudgetAlerts()
 
-	fromUser, err := s.userRepo.GetByID(ctx, fromAccount.UserID)
-	if err != nil {
-		return fmt.Errorf("failed to get source user: %v", err)
+	s.sendTransferNotifications(ctx, fromAccount, toUser, p.Amount)
+
+	return transaction, fee, nil
+}
+
+func (s *AccountService) lockTransferAccounts(ctx context.Context, tx *sql.Tx, fromAccountID, toAccountID int64) (*models.Account, *models.Account, error) {
+	ids := []int64{fromAccountID, toAccountID}
+	if ids[0] > ids[1] {
+		ids[0], ids[1] = ids[1], ids[0]
 	}
 
-	toUser, err := s.userRepo.GetByID(ctx, toAccount.UserID)
+	locked := make(map[int64]*models.Account, 2)
+	for _, id := range ids {
+		account, err := s.accountRepo.GetByIDForUpdate(ctx, tx, id)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get account: %v", err)
+		}
+		if account == nil || !account.IsActive {
+			return nil, nil, models.ErrAccountNotFound
+		}
+		locked[id] = account
+	}
+
+	return locked[fromAccountID], locked[toAccountID], nil
+}
+
+// sendTransferNotifications уведомляет участников перевода. Деньги к этому моменту уже переведены,
+// поэтому ошибки отправки только логируются.
+func (s *AccountService) sendTransferNotifications(ctx context.Context, fromAccount *models.Account, toUser *models.User, amount float64) {
+	fromUser, err := s.userRepo.GetByID(ctx, fromAccount.UserID)
 	if err != nil {
-		return fmt.Errorf("failed to get destination user: %v", err)
+		log.Printf("Error getting source user %d for transfer notification: %v", fromAccount.UserID, err)
+		return
 	}
 
 	if err := s.smtpService.SendTransactionNotification(fromUser.Email, amount, "transfer sent"); err != nil {
-		return fmt.Errorf("failed to send notification to source user: %v", err)
+		log.Printf("Error sending transfer notification to source user: %v", err)
 	}
 
 	if err := s.smtpService.SendTransactionNotification(toUser.Email, amount, "transfer received"); err != nil {
-		return fmt.Errorf("failed to send notification to destination user: %v", err)
+		log.Printf("Error sending transfer notification to destination user: %v", err)
 	}
 
 	if fromAccount.Balance < 1000 {
 		if err := s.smtpService.SendLowBalanceNotification(fromUser.Email, fromAccount.Balance); err != nil {
-			return fmt.Errorf("failed to send low balance notification: %v", err)
+			log.Printf("Error sending low balance notification: %v", err)
 		}
 	}
-
-	return nil
-} 
\ No newline at end of file
+}
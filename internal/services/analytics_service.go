@@ -0,0 +1,129 @@
+package services
+
+import (
+	"banksystem/internal/models"
+	"banksystem/internal/repositories"
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	// Период, по которому оценивается средний месячный доход
+	incomeLookbackMonths = 3
+)
+
+type AnalyticsService struct {
+	creditRepo      *repositories.CreditRepository
+	paymentRepo     *repositories.CreditPaymentRepository
+	transactionRepo *repositories.TransactionRepository
+}
+
+func NewAnalyticsService(
+	creditRepo *repositories.CreditRepository,
+	paymentRepo *repositories.CreditPaymentRepository,
+	transactionRepo *repositories.TransactionRepository,
+) *AnalyticsService {
+	return &AnalyticsService{
+		creditRepo:      creditRepo,
+		paymentRepo:     paymentRepo,
+		transactionRepo: transactionRepo,
+	}
+}
+
+// GetCreditLoad собирает показатели кредитной нагрузки пользователя
+func (s *AnalyticsService) GetCreditLoad(ctx context.Context, userID int64) (*models.CreditLoadReport, error) {
+	credits, err := s.creditRepo.GetByUserID(int(userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user credits: %v", err)
+	}
+
+	payments, err := s.paymentRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credit payments: %v", err)
+	}
+
+	now := time.Now()
+	income, err := s.transactionRepo.GetIncomeByUserID(ctx, userID, now.AddDate(0, -incomeLookbackMonths, 0))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get income: %v", err)
+	}
+
+	report := &models.CreditLoadReport{
+		UserID:        userID,
+		MonthlyIncome: income / incomeLookbackMonths,
+		GeneratedAt:   now,
+	}
+
+	penalties, err := s.paymentRepo.SumUnpaidPenaltiesByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get penalties: %v", err)
+	}
+	report.Penalties = penalties
+
+	for _, payment := range payments {
+		switch {
+		case payment.Status == models.CreditPaymentStatusCompleted:
+		case payment.Status == models.CreditPaymentStatusFailed || payment.DueDate.Before(now):
+			report.OverdueAmount += payment.Amount
+		default:
+			if !payment.DueDate.After(now.AddDate(0, 0, 180)) {
+				report.Upcoming180Days += payment.Amount
+			}
+			if !payment.DueDate.After(now.AddDate(0, 0, 90)) {
+				report.Upcoming90Days += payment.Amount
+			}
+			if !payment.DueDate.After(now.AddDate(0, 0, 30)) {
+				report.Upcoming30Days += payment.Amount
+			}
+		}
+	}
+
+	// Платеж и остаток долга берутся из сохраненного графика: после досрочного погашения
+	// он пересчитан и не совпадает с аннуитетом от исходной суммы
+	states := scheduleStates(payments)
+	for _, credit := range credits {
+		if credit.Status != models.CreditStatusActive && credit.Status != models.CreditStatusOverdue {
+			continue
+		}
+		report.ActiveCredits++
+		if state, ok := states[credit.ID]; ok {
+			report.MonthlyObligations += state.monthlyPayment()
+			report.OutstandingPrincipal += roundKopecks(state.outstanding)
+		}
+	}
+
+	if report.MonthlyIncome > 0 {
+		report.DebtToIncome = report.MonthlyObligations / report.MonthlyIncome
+	}
+	report.RiskBand = classifyRisk(report)
+
+	return report, nil
+}
+
+// classifyRisk определяет категорию риска по доле платежей в доходе и наличию просрочек
+func classifyRisk(report *models.CreditLoadReport) string {
+	if report.MonthlyObligations == 0 && report.OverdueAmount == 0 {
+		return models.RiskBandLow
+	}
+	if report.MonthlyIncome == 0 {
+		return models.RiskBandCritical
+	}
+
+	band := models.RiskBandLow
+	switch {
+	case report.DebtToIncome >= 0.7:
+		band = models.RiskBandCritical
+	case report.DebtToIncome >= 0.5:
+		band = models.RiskBandHigh
+	case report.DebtToIncome >= 0.3:
+		band = models.RiskBandMedium
+	}
+
+	// Наличие просрочки поднимает категорию минимум до высокой
+	if report.OverdueAmount > 0 && (band == models.RiskBandLow || band == models.RiskBandMedium) {
+		band = models.RiskBandHigh
+	}
+
+	return band
+}
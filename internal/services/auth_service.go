@@ -81,4 +81,13 @@ func (s *AuthService) Login(ctx context.Context, email, password string) (string
 	}
 
 	return token, nil
-} 
\ No newline at end of file
+}
+
+// IsAdmin проверяет, что пользователь имеет роль администратора
+func (s *AuthService) IsAdmin(ctx context.Context, userID int64) (bool, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return user.Role == models.UserRoleAdmin, nil
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"banksystem/internal/models"
+	"banksystem/internal/repositories"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// budgetAlert уведомление о достижении порога бюджета, отправляемое после коммита транзакции
+type budgetAlert struct {
+	userID  int64
+	budget  *models.Budget
+	percent int
+}
+
+type BudgetService struct {
+	budgetRepo  *repositories.BudgetRepository
+	accountRepo *repositories.AccountRepository
+	userRepo    *repositories.UserRepository
+	smtpService *SMTPService
+}
+
+func NewBudgetService(
+	budgetRepo *repositories.BudgetRepository,
+	accountRepo *repositories.AccountRepository,
+	userRepo *repositories.UserRepository,
+	smtpService *SMTPService,
+) *BudgetService {
+	return &BudgetService{
+		budgetRepo:  budgetRepo,
+		accountRepo: accountRepo,
+		userRepo:    userRepo,
+		smtpService: smtpService,
+	}
+}
+
+func (s *BudgetService) CreateBudget(ctx context.Context, userID int64, req *models.BudgetCreateRequest) (*models.Budget, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	budget := &models.Budget{
+		UserID:       userID,
+		MonthlyLimit: req.MonthlyLimit,
+		CarryOver:    req.CarryOver,
+		PeriodStart:  monthStart(time.Now()),
+	}
+
+	if req.AccountID != 0 {
+		account, err := s.accountRepo.GetByID(ctx, req.AccountID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get account: %v", err)
+		}
+		if account == nil {
+			return nil, models.ErrAccountNotFound
+		}
+		if account.UserID != userID {
+			return nil, models.ErrAccessDenied
+		}
+		budget.AccountID = sql.NullInt64{Int64: req.AccountID, Valid: true}
+	}
+	if req.Category != "" {
+		budget.Category = sql.NullString{String: req.Category, Valid: true}
+	}
+
+	// Учитываем расходы, уже совершенные в текущем месяце
+	spent, err := s.budgetRepo.GetSpentSince(ctx, budget)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate spending: %v", err)
+	}
+	budget.Spent = spent
+
+	if err := s.budgetRepo.Create(ctx, budget); err != nil {
+		return nil, fmt.Errorf("failed to create budget: %v", err)
+	}
+
+	return budget, nil
+}
+
+func (s *BudgetService) GetUserBudgets(ctx context.Context, userID int64) ([]*models.Budget, error) {
+	budgets, err := s.budgetRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get budgets: %v", err)
+	}
+	return budgets, nil
+}
+
+func (s *BudgetService) DeleteBudget(ctx context.Context, userID, budgetID int64) error {
+	budget, err := s.budgetRepo.GetByID(ctx, budgetID)
+	if err != nil {
+		return fmt.Errorf("failed to get budget: %v", err)
+	}
+	if budget == nil {
+		return models.ErrBudgetNotFound
+	}
+	if budget.UserID != userID {
+		return models.ErrAccessDenied
+	}
+
+	return s.budgetRepo.Delete(ctx, budgetID)
+}
+
+// TrackSpending учитывает расход в бюджетах пользователя внутри транзакции операции.
+// Возвращаемую функцию нужно вызвать после коммита, чтобы отправить уведомления о достигнутых порогах.
+func (s *BudgetService) TrackSpending(ctx context.Context, tx *sql.Tx, userID, accountID int64, category string, amount float64) (func(), error) {
+	if err := s.budgetRepo.RolloverUser(ctx, tx, userID, monthStart(time.Now())); err != nil {
+		return nil, fmt.Errorf("failed to roll over budgets: %v", err)
+	}
+
+	budgets, err := s.budgetRepo.AddSpending(ctx, tx, userID, accountID, category, amount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to track budget spending: %v", err)
+	}
+
+	var alerts []budgetAlert
+	for _, budget := range budgets {
+		reached := 0
+		for _, threshold := range models.BudgetAlertThresholds {
+			if budget.Percent() >= float64(threshold) && threshold > budget.LastAlertPercent {
+				reached = threshold
+			}
+		}
+		if reached == 0 {
+			continue
+		}
+
+		if err := s.budgetRepo.UpdateLastAlert(ctx, tx, budget.ID, reached); err != nil {
+			return nil, fmt.Errorf("failed to update budget alert: %v", err)
+		}
+		alerts = append(alerts, budgetAlert{userID: userID, budget: budget, percent: reached})
+	}
+
+	return func() { s.sendAlerts(ctx, alerts) }, nil
+}
+
+// ReleaseSpending уменьшает расход бюджетов при отмене операции от spentAt. Операции прошлых месяцев
+// в текущих бюджетах не учитываются, поэтому их отмена расход не меняет.
+func (s *BudgetService) ReleaseSpending(ctx context.Context, tx *sql.Tx, userID, accountID int64, category string, amount float64, spentAt time.Time) error {
+	periodStart := monthStart(time.Now())
+	if err := s.budgetRepo.RolloverUser(ctx, tx, userID, periodStart); err != nil {
+		return fmt.Errorf("failed to roll over budgets: %v", err)
+	}
+	if spentAt.Before(periodStart) {
+		return nil
+	}
+
+	if err := s.budgetRepo.SubtractSpending(ctx, tx, userID, accountID, category, amount); err != nil {
+		return fmt.Errorf("failed to release budget spending: %v", err)
+	}
+	return nil
+}
+
+func (s *BudgetService) sendAlerts(ctx context.Context, alerts []budgetAlert) {
+	for _, alert := range alerts {
+		user, err := s.userRepo.GetByID(ctx, alert.userID)
+		if err != nil {
+			log.Printf("Error getting user %d for budget alert: %v", alert.userID, err)
+			continue
+		}
+
+		err = s.smtpService.SendBudgetAlertNotification(user.Email, budgetName(alert.budget), alert.percent, alert.budget.Spent, alert.budget.Limit())
+		if err != nil {
+			log.Printf("Error sending budget alert for budget %d: %v", alert.budget.ID, err)
+		}
+	}
+}
+
+// RolloverBudgets переводит бюджеты на текущий месяц
+func (s *BudgetService) RolloverBudgets(ctx context.Context) (int64, error) {
+	return s.budgetRepo.Rollover(ctx, monthStart(time.Now()))
+}
+
+func budgetName(budget *models.Budget) string {
+	name := "все расходы"
+	if budget.Category.Valid {
+		name = budget.Category.String
+	}
+	if budget.AccountID.Valid {
+		name = fmt.Sprintf("%s (счет %d)", name, budget.AccountID.Int64)
+	}
+	return name
+}
+
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,380 @@
+package services
+
+import (
+	"banksystem/internal/crypto"
+	"banksystem/internal/models"
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math"
+	"time"
+)
+
+// defaultHoldTTL срок блокировки средств, если он не задан в лимитах
+const defaultHoldTTL = 7 * 24 * time.Hour
+
+// AuthorizeHold авторизует операцию терминала (0100) и блокирует сумму на счете карты без списания.
+// Заблокированная сумма уменьшает доступный остаток и учитывается в лимитах карты.
+func (s *CardService) AuthorizeHold(ctx context.Context, req *models.TerminalPaymentRequest) (*models.CardHold, error) {
+	terminal, card, err := s.terminalCard(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	account, merchantAccount, err := s.lockAccounts(ctx, tx, card.AccountID, terminal.MerchantAccountID)
+	if err != nil {
+		return nil, err
+	}
+	if merchantAccount.ID == account.ID {
+		return nil, models.ErrInvalidMerchant
+	}
+
+	if err := s.authorizeTerminal(ctx, tx, card, terminal, req); err != nil {
+		return nil, err
+	}
+	if err := s.checkFunds(ctx, tx, card, account, req.Amount); err != nil {
+		return nil, err
+	}
+
+	hold, err := s.createHold(ctx, tx, card, terminal, req, models.CardHoldStatusHeld, sql.NullInt64{})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return hold, nil
+}
+
+// Purchase проводит финансовую операцию терминала (0200). Если по тому же RRN ранее была
+// блокировка (0100), списывается заблокированная сумма или меньшая; иначе проводится
+// покупка с полной авторизацией карты.
+func (s *CardService) Purchase(ctx context.Context, req *models.TerminalPaymentRequest) (*models.CardHold, error) {
+	terminal, card, err := s.terminalCard(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	// Операцию блокируем раньше счетов, в том же порядке, что и при отмене
+	hold, err := s.cardRepo.GetHoldForUpdate(ctx, tx, terminal.ID, req.RRN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hold: %v", err)
+	}
+
+	account, merchantAccount, err := s.lockAccounts(ctx, tx, card.AccountID, terminal.MerchantAccountID)
+	if err != nil {
+		return nil, err
+	}
+	if merchantAccount.ID == account.ID {
+		return nil, models.ErrInvalidMerchant
+	}
+
+	merchantName, merchantMCC := terminalMerchant(terminal, req)
+
+	if hold != nil {
+		if err := s.captureHold(ctx, tx, hold, card, account, merchantAccount, req.Amount, merchantName, merchantMCC); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := s.authorizeTerminal(ctx, tx, card, terminal, req); err != nil {
+			return nil, err
+		}
+
+		transaction, err := s.debit(ctx, tx, card, account, merchantAccount, req.Amount, merchantName, merchantMCC, "")
+		if err != nil {
+			return nil, err
+		}
+
+		hold, err = s.createHold(ctx, tx, card, terminal, req, models.CardHoldStatusCaptured, sql.NullInt64{Int64: transaction.ID, Valid: true})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sendBudgetAlerts, err := s.budgetService.TrackSpending(ctx, tx, account.UserID, account.ID, "", req.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	sendBudgetAlerts()
+	s.auditSingleUse(ctx, card)
+
+	return hold, nil
+}
+
+// Reverse отменяет операцию терминала (0400): снимает блокировку или возвращает списанную сумму
+// со счета продавца на счет карты. Повторная отмена той же операции не меняет балансы.
+func (s *CardService) Reverse(ctx context.Context, req *models.TerminalReversalRequest) (*models.CardHold, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	hold, err := s.cardRepo.GetHoldForUpdate(ctx, tx, req.TerminalID, req.RRN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hold: %v", err)
+	}
+	if hold == nil || (req.OriginalSTAN != "" && hold.STAN != "" && req.OriginalSTAN != hold.STAN) {
+		return nil, models.ErrHoldNotFound
+	}
+	// Частичные отмены не поддерживаются: сумма отмены должна совпадать с заблокированной или списанной
+	if math.Round(req.Amount*100) != math.Round(hold.Amount*100) {
+		return nil, models.ErrInvalidAmount
+	}
+
+	switch hold.Status {
+	case models.CardHoldStatusHeld:
+		hold.Status = models.CardHoldStatusReleased
+	case models.CardHoldStatusCaptured:
+		if err := s.refundHold(ctx, tx, hold); err != nil {
+			return nil, err
+		}
+		hold.Status = models.CardHoldStatusReversed
+	default:
+		return hold, nil
+	}
+
+	if err := s.cardRepo.UpdateHold(ctx, tx, hold); err != nil {
+		return nil, fmt.Errorf("failed to update hold: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return hold, nil
+}
+
+// ReleaseExpiredHolds снимает блокировки, по которым терминал не прислал списание в срок
+func (s *CardService) ReleaseExpiredHolds(ctx context.Context) (int64, error) {
+	return s.cardRepo.ReleaseExpiredHolds(ctx, time.Now())
+}
+
+// TerminalMACKey возвращает общий с терминалом ключ MAC. Терминалы без ключа не обслуживаются.
+func (s *CardService) TerminalMACKey(ctx context.Context, terminalID string) ([]byte, error) {
+	terminal, err := s.cardRepo.GetTerminal(ctx, terminalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get terminal: %v", err)
+	}
+	if terminal == nil || !terminal.IsActive || !terminal.MACKey.Valid {
+		return nil, models.ErrTerminalNotFound
+	}
+
+	key, err := hex.DecodeString(terminal.MACKey.String)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MAC key of terminal %s: %v", terminalID, err)
+	}
+	return key, nil
+}
+
+func (s *CardService) terminalCard(ctx context.Context, req *models.TerminalPaymentRequest) (*models.Terminal, *models.Card, error) {
+	terminal, err := s.cardRepo.GetTerminal(ctx, req.TerminalID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get terminal: %v", err)
+	}
+	if terminal == nil || !terminal.IsActive {
+		return nil, nil, models.ErrTerminalNotFound
+	}
+
+	card, err := s.findByCardData(ctx, req.CardNumber, req.ExpiryDate)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return terminal, card, nil
+}
+
+// authorizeTerminal проверяет карту, ограничения владельца и PIN для операции терминала
+func (s *CardService) authorizeTerminal(ctx context.Context, tx *sql.Tx, card *models.Card, terminal *models.Terminal, req *models.TerminalPaymentRequest) error {
+	_, merchantMCC := terminalMerchant(terminal, req)
+
+	if err := s.authorize(ctx, card, req.CVV, terminal.MerchantAccountID); err != nil {
+		return err
+	}
+	if err := s.checkControls(ctx, tx, card, req.Amount, merchantMCC, req.Channel, req.Country); err != nil {
+		return err
+	}
+	// Терминал не может передать код подтверждения, поэтому крупные интернет-платежи отклоняются
+	if s.requiresOTP(req.Channel, req.Amount) {
+		return models.ErrOTPRequired
+	}
+	return s.checkPaymentPIN(ctx, card, req.Amount, req.PIN)
+}
+
+// captureHold списывает ранее заблокированную сумму. Блокировка переводится в CAPTURED до списания,
+// чтобы ее сумма не учитывалась повторно при проверке лимитов и остатка.
+func (s *CardService) captureHold(
+	ctx context.Context,
+	tx *sql.Tx,
+	hold *models.CardHold,
+	card *models.Card,
+	account, merchantAccount *models.Account,
+	amount float64,
+	merchantName, merchantMCC string,
+) error {
+	if hold.CardID != card.ID || hold.MerchantAccountID != merchantAccount.ID {
+		return models.ErrHoldNotFound
+	}
+	switch hold.Status {
+	case models.CardHoldStatusHeld:
+	case models.CardHoldStatusCaptured:
+		return models.ErrDuplicateTransaction
+	default:
+		return models.ErrHoldNotActive
+	}
+	if amount > hold.Amount {
+		return models.ErrHoldAmountExceeded
+	}
+
+	hold.Status = models.CardHoldStatusCaptured
+	hold.Amount = amount
+	if err := s.cardRepo.UpdateHold(ctx, tx, hold); err != nil {
+		return fmt.Errorf("failed to update hold: %v", err)
+	}
+
+	transaction, err := s.debit(ctx, tx, card, account, merchantAccount, amount, merchantName, merchantMCC, "")
+	if err != nil {
+		return err
+	}
+
+	hold.TransactionID = sql.NullInt64{Int64: transaction.ID, Valid: true}
+	if err := s.cardRepo.UpdateHold(ctx, tx, hold); err != nil {
+		return fmt.Errorf("failed to update hold: %v", err)
+	}
+
+	return nil
+}
+
+// refundHold возвращает списанную сумму со счета продавца на счет карты и помечает платеж отмененным
+func (s *CardService) refundHold(ctx context.Context, tx *sql.Tx, hold *models.CardHold) error {
+	account, merchantAccount, err := s.lockAccounts(ctx, tx, hold.AccountID, hold.MerchantAccountID)
+	if err != nil {
+		return err
+	}
+	// Продавец мог уже вывести выручку; отмена отклоняется, и терминал может повторить ее позже
+	if merchantAccount.Balance < hold.Amount {
+		return models.ErrInsufficientFunds
+	}
+
+	now := sql.NullTime{Time: time.Now(), Valid: true}
+
+	account.Balance += hold.Amount
+	account.UpdatedAt = now
+	if err := s.accountRepo.Update(ctx, tx, account); err != nil {
+		return fmt.Errorf("failed to update account: %v", err)
+	}
+
+	merchantAccount.Balance -= hold.Amount
+	merchantAccount.UpdatedAt = now
+	if err := s.accountRepo.Update(ctx, tx, merchantAccount); err != nil {
+		return fmt.Errorf("failed to update merchant account: %v", err)
+	}
+
+	capturedAt := hold.CreatedAt
+	if hold.TransactionID.Valid {
+		if err := s.transactionRepo.UpdateStatus(ctx, tx, hold.TransactionID.Int64, models.TransactionStatusReversed); err != nil {
+			return fmt.Errorf("failed to update transaction status: %v", err)
+		}
+		transaction, err := s.transactionRepo.GetByID(ctx, hold.TransactionID.Int64)
+		if err != nil {
+			return fmt.Errorf("failed to get transaction: %v", err)
+		}
+		if transaction != nil && transaction.CreatedAt.Valid {
+			capturedAt = transaction.CreatedAt.Time
+		}
+	}
+
+	// Списание учитывалось в бюджетах так же, как в Purchase: без категории
+	if err := s.budgetService.ReleaseSpending(ctx, tx, account.UserID, account.ID, "", hold.Amount, capturedAt); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *CardService) createHold(
+	ctx context.Context,
+	tx *sql.Tx,
+	card *models.Card,
+	terminal *models.Terminal,
+	req *models.TerminalPaymentRequest,
+	status string,
+	transactionID sql.NullInt64,
+) (*models.CardHold, error) {
+	authCode, err := crypto.RandomDigits(6)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := s.limits.HoldTTL
+	if ttl <= 0 {
+		ttl = defaultHoldTTL
+	}
+
+	merchantName, merchantMCC := terminalMerchant(terminal, req)
+	now := time.Now()
+	hold := &models.CardHold{
+		CardID:            card.ID,
+		AccountID:         card.AccountID,
+		MerchantAccountID: terminal.MerchantAccountID,
+		TerminalID:        terminal.ID,
+		RRN:               req.RRN,
+		STAN:              req.STAN,
+		Amount:            req.Amount,
+		AuthCode:          authCode,
+		Status:            status,
+		TransactionID:     transactionID,
+		MerchantName:      merchantName,
+		MerchantMCC:       sql.NullString{String: merchantMCC, Valid: merchantMCC != ""},
+		ExpiresAt:         now.Add(ttl),
+		CreatedAt:         now,
+	}
+
+	created, err := s.cardRepo.CreateHold(ctx, tx, hold)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create hold: %v", err)
+	}
+	if !created {
+		log.Printf("Duplicate terminal operation %s/%s", terminal.ID, req.RRN)
+		return nil, models.ErrDuplicateTransaction
+	}
+
+	return hold, nil
+}
+
+// terminalMerchant продавец операции: данные зарегистрированного терминала имеют приоритет над сообщением
+func terminalMerchant(terminal *models.Terminal, req *models.TerminalPaymentRequest) (string, string) {
+	merchantName := terminal.MerchantName
+	if merchantName == "" {
+		merchantName = req.MerchantName
+	}
+	merchantMCC := terminal.MerchantMCC.String
+	if merchantMCC == "" {
+		merchantMCC = req.MerchantMCC
+	}
+	return merchantName, merchantMCC
+}
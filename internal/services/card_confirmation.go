@@ -0,0 +1,210 @@
+package services
+
+import (
+	"banksystem/internal/crypto"
+	"banksystem/internal/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"log"
+	"strings"
+	"time"
+)
+
+// requiresOTP онлайн-платежи на сумму выше порога подтверждаются одноразовым кодом
+func (s *CardService) requiresOTP(channel string, amount float64) bool {
+	return channel == models.PaymentChannelEcom && s.limits.OTPThreshold > 0 && amount > s.limits.OTPThreshold
+}
+
+// otpTTL срок действия кода подтверждения
+func (s *CardService) otpTTL() time.Duration {
+	if s.limits.OTPTTL > 0 {
+		return s.limits.OTPTTL
+	}
+	return models.DefaultOTPTTL
+}
+
+// requestConfirmation сохраняет платеж в статусе pending_confirmation и возвращает код для владельца карты.
+// Остаток и лимиты проверяются сразу, чтобы не отправлять код по платежу, который все равно будет отклонен.
+// В базе хранится только bcrypt-хеш кода. Код отправляется sendConfirmationCode после фиксации транзакции.
+func (s *CardService) requestConfirmation(
+	ctx context.Context,
+	tx *sql.Tx,
+	card *models.Card,
+	account *models.Account,
+	confirmation *models.CardPaymentConfirmation,
+) (string, error) {
+	if err := s.checkFunds(ctx, tx, card, account, confirmation.Amount); err != nil {
+		return "", err
+	}
+
+	code, err := crypto.RandomDigits(models.OTPLength)
+	if err != nil {
+		return "", err
+	}
+	codeHash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash confirmation code: %v", err)
+	}
+
+	now := time.Now()
+	confirmation.CardID = card.ID
+	confirmation.AccountID = account.ID
+	confirmation.MerchantName = strings.TrimSpace(confirmation.MerchantName)
+	confirmation.CodeHash = string(codeHash)
+	confirmation.Status = models.CardPaymentStatusPendingConfirmation
+	confirmation.ExpiresAt = now.Add(s.otpTTL())
+	confirmation.CreatedAt = now
+
+	if err := s.cardRepo.CreateConfirmation(ctx, tx, confirmation); err != nil {
+		return "", fmt.Errorf("failed to create payment confirmation: %v", err)
+	}
+
+	return code, nil
+}
+
+// sendConfirmationCode отправляет владельцу карты код по уже сохраненному платежу. Письмо отправляется
+// вне транзакции платежа, чтобы медленный почтовый сервер не держал блокировку счета. Если код
+// не доставлен, платеж отклоняется: подтвердить его все равно нельзя.
+func (s *CardService) sendConfirmationCode(ctx context.Context, card *models.Card, ownerID int64, confirmation *models.CardPaymentConfirmation, code string) error {
+	user, err := s.userRepo.GetByID(ctx, ownerID)
+	if err == nil {
+		err = s.smtpService.SendPaymentConfirmationCode(user.Email, code, confirmation.Amount, confirmation.MerchantName,
+			card.Last4.String, int(confirmation.ExpiresAt.Sub(confirmation.CreatedAt)/time.Minute))
+	}
+	if err == nil {
+		return nil
+	}
+
+	log.Printf("Error sending code for payment confirmation %d: %v", confirmation.ID, err)
+	tx, txErr := s.db.BeginTx(ctx, nil)
+	if txErr != nil {
+		return fmt.Errorf("failed to begin transaction: %v", txErr)
+	}
+	defer tx.Rollback()
+
+	confirmation.Status = models.CardPaymentStatusFailed
+	if err := s.finishConfirmation(ctx, tx, confirmation); err != nil {
+		return err
+	}
+	return fmt.Errorf("failed to send confirmation code: %v", err)
+}
+
+// ConfirmPayment проверяет код и проводит ожидающий подтверждения платеж. Неверный код увеличивает
+// счетчик попыток; после MaxOTPAttempts неверных кодов платеж отклоняется.
+func (s *CardService) ConfirmPayment(ctx context.Context, userID, confirmationID int64, req *models.CardPaymentConfirmRequest) (*models.Transaction, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	confirmation, err := s.cardRepo.GetConfirmationForUpdate(ctx, tx, confirmationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment confirmation: %v", err)
+	}
+	if confirmation == nil || confirmation.InitiatorID != userID {
+		return nil, models.ErrConfirmationNotFound
+	}
+
+	switch confirmation.Status {
+	case models.CardPaymentStatusPendingConfirmation:
+	case models.CardPaymentStatusExpired:
+		return nil, models.ErrConfirmationExpired
+	default:
+		return nil, models.ErrConfirmationNotPending
+	}
+
+	if time.Now().After(confirmation.ExpiresAt) {
+		confirmation.Status = models.CardPaymentStatusExpired
+		if err := s.finishConfirmation(ctx, tx, confirmation); err != nil {
+			return nil, err
+		}
+		return nil, models.ErrConfirmationExpired
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(confirmation.CodeHash), []byte(req.Code)) != nil {
+		confirmation.Attempts++
+		if confirmation.Attempts >= models.MaxOTPAttempts {
+			confirmation.Status = models.CardPaymentStatusFailed
+		}
+		if err := s.finishConfirmation(ctx, tx, confirmation); err != nil {
+			return nil, err
+		}
+		if confirmation.Status == models.CardPaymentStatusFailed {
+			log.Printf("Payment confirmation %d failed after %d wrong codes", confirmation.ID, confirmation.Attempts)
+			return nil, models.ErrOTPAttemptsExceeded
+		}
+		return nil, models.ErrWrongOTP
+	}
+
+	card, err := s.cardRepo.GetByID(confirmation.CardID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get card: %v", err)
+	}
+	if card == nil {
+		return nil, models.ErrCardNotFound
+	}
+
+	var account, merchantAccount *models.Account
+	if confirmation.MerchantAccountID.Valid {
+		account, merchantAccount, err = s.lockAccounts(ctx, tx, confirmation.AccountID, confirmation.MerchantAccountID.Int64)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		account, err = s.accountRepo.GetByIDForUpdate(ctx, tx, confirmation.AccountID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get account: %v", err)
+		}
+		if account == nil {
+			return nil, models.ErrAccountNotFound
+		}
+	}
+
+	// CVV и PIN проверены при создании платежа; статус карты, ограничения и остаток могли измениться
+	if err := s.checkCard(card, confirmation.MerchantAccountID.Int64); err != nil {
+		return nil, err
+	}
+	if err := s.checkControls(ctx, tx, card, confirmation.Amount, confirmation.MerchantMCC.String, confirmation.Channel, confirmation.Country); err != nil {
+		return nil, err
+	}
+
+	transaction, err := s.debit(ctx, tx, card, account, merchantAccount, confirmation.Amount,
+		confirmation.MerchantName, confirmation.MerchantMCC.String, confirmation.Category.String)
+	if err != nil {
+		return nil, err
+	}
+
+	sendBudgetAlerts, err := s.budgetService.TrackSpending(ctx, tx, account.UserID, account.ID, confirmation.Category.String, confirmation.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	confirmation.Status = models.CardPaymentStatusConfirmed
+	confirmation.TransactionID = sql.NullInt64{Int64: transaction.ID, Valid: true}
+	if err := s.finishConfirmation(ctx, tx, confirmation); err != nil {
+		return nil, err
+	}
+	sendBudgetAlerts()
+	s.auditSingleUse(ctx, card)
+
+	return transaction, nil
+}
+
+// ExpirePendingPayments отклоняет онлайн-платежи, не подтвержденные за время действия кода
+func (s *CardService) ExpirePendingPayments(ctx context.Context) (int64, error) {
+	return s.cardRepo.ExpireConfirmations(ctx, time.Now())
+}
+
+// finishConfirmation сохраняет платеж и фиксирует транзакцию; неверная попытка сохраняется, даже если вызывающий вернет ошибку
+func (s *CardService) finishConfirmation(ctx context.Context, tx *sql.Tx, confirmation *models.CardPaymentConfirmation) error {
+	if err := s.cardRepo.UpdateConfirmation(ctx, tx, confirmation); err != nil {
+		return fmt.Errorf("failed to update payment confirmation: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"banksystem/internal/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// GetControls возвращает пользовательские ограничения карты
+func (s *CardService) GetControls(ctx context.Context, userID, cardID int64) (*models.CardControls, error) {
+	card, err := s.GetCard(ctx, userID, cardID)
+	if err != nil {
+		return nil, err
+	}
+
+	controls, err := s.cardRepo.GetControls(ctx, card.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get card controls: %v", err)
+	}
+
+	return controls, nil
+}
+
+// UpdateControls меняет переданные в запросе ограничения карты и записывает изменение в журнал аудита
+func (s *CardService) UpdateControls(ctx context.Context, userID, cardID int64, req *models.CardControlsRequest) (*models.CardControls, error) {
+	controls, err := s.GetControls(ctx, userID, cardID)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Apply(controls)
+	if err := s.cardRepo.SaveControls(ctx, controls); err != nil {
+		return nil, fmt.Errorf("failed to save card controls: %v", err)
+	}
+
+	details, _ := json.Marshal(req)
+	s.audit(ctx, userID, models.AuditActionCardControls, cardID, string(details))
+
+	return controls, nil
+}
+
+// checkControls применяет к платежу ограничения, установленные владельцем карты:
+// каналы оплаты, зарубежные платежи, запрещенные MCC, дневной и месячный лимиты
+func (s *CardService) checkControls(ctx context.Context, tx *sql.Tx, card *models.Card, amount float64, mcc, channel, country string) error {
+	controls, err := s.cardRepo.GetControls(ctx, card.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get card controls: %v", err)
+	}
+
+	switch {
+	case channel == models.PaymentChannelEcom && !controls.EcomEnabled:
+		return models.ErrEcomDisabled
+	case channel == models.PaymentChannelContactless && !controls.ContactlessEnabled:
+		return models.ErrContactlessDisabled
+	case country != "" && country != models.HomeCountry && !controls.ForeignEnabled:
+		return models.ErrForeignDisabled
+	case mcc != "" && controls.BlocksMCC(mcc):
+		return models.ErrMCCBlocked
+	}
+
+	now := time.Now()
+	if controls.DailyLimit.Valid {
+		spent, err := s.cardSpentSince(ctx, tx, card.ID, truncateToDay(now))
+		if err != nil {
+			return err
+		}
+		if spent+amount > controls.DailyLimit.Float64 {
+			return models.ErrCardDailyLimit
+		}
+	}
+
+	if controls.MonthlyLimit.Valid {
+		monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		spent, err := s.cardSpentSince(ctx, tx, card.ID, monthStart)
+		if err != nil {
+			return err
+		}
+		if spent+amount > controls.MonthlyLimit.Float64 {
+			return models.ErrCardMonthlyLimit
+		}
+	}
+
+	return nil
+}
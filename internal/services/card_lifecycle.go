@@ -0,0 +1,294 @@
+package services
+
+import (
+	"banksystem/internal/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// BlockCard блокирует карту по запросу владельца: временно или навсегда (например, при утере)
+func (s *CardService) BlockCard(ctx context.Context, userID, cardID int64, req *models.CardBlockRequest) (*models.Card, error) {
+	card, err := s.GetCard(ctx, userID, cardID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.block(ctx, card, req, models.CardBlockedByUser, userID)
+}
+
+// UnblockCard снимает временную блокировку, установленную владельцем.
+// Блокировку, установленную банком или системой, владелец снять не может.
+func (s *CardService) UnblockCard(ctx context.Context, userID, cardID int64) (*models.Card, error) {
+	card, err := s.GetCard(ctx, userID, cardID)
+	if err != nil {
+		return nil, err
+	}
+
+	if card.Status == models.CardStatusBlocked && card.BlockedBy.String != models.CardBlockedByUser {
+		return nil, models.ErrCardBlockedByBank
+	}
+
+	return s.unblock(ctx, card, userID)
+}
+
+// AdminBlockCard блокирует любую карту от имени банка
+func (s *CardService) AdminBlockCard(ctx context.Context, adminID, cardID int64, req *models.CardBlockRequest) (*models.Card, error) {
+	card, err := s.getCard(cardID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.block(ctx, card, req, models.CardBlockedByAdmin, adminID)
+}
+
+// AdminUnblockCard снимает любую временную блокировку карты
+func (s *CardService) AdminUnblockCard(ctx context.Context, adminID, cardID int64) (*models.Card, error) {
+	card, err := s.getCard(cardID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.unblock(ctx, card, adminID)
+}
+
+// ReissueCard выпускает владельцу новую карту с новым номером к тому же счету.
+// Старая карта, если она еще действует, блокируется навсегда.
+func (s *CardService) ReissueCard(ctx context.Context, userID, cardID int64) (*models.Card, error) {
+	card, err := s.GetCard(ctx, userID, cardID)
+	if err != nil {
+		return nil, err
+	}
+	if card.IsVirtual() {
+		return nil, models.ErrVirtualCard
+	}
+	if card.ReplacedBy.Valid {
+		return nil, models.ErrCardAlreadyReissued
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	newCard, err := s.replaceCard(ctx, tx, card)
+	if err != nil {
+		return nil, err
+	}
+
+	if card.Status == models.CardStatusActive || card.Status == models.CardStatusBlocked {
+		fromStatus := card.Status
+		card.Status = models.CardStatusPermBlocked
+		card.BlockReason = sql.NullString{String: "Перевыпуск карты", Valid: true}
+		card.BlockedBy = sql.NullString{String: models.CardBlockedByUser, Valid: true}
+		if err := s.updateStatus(ctx, tx, card, fromStatus); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	s.audit(ctx, userID, models.AuditActionCardReissue, card.ID, fmt.Sprintf("new card %d", newCard.ID))
+
+	return newCard, nil
+}
+
+// ExpireCards переводит карты с истекшим сроком действия в статус EXPIRED
+func (s *CardService) ExpireCards(ctx context.Context) (int64, error) {
+	return s.cardRepo.ExpireCards(ctx, truncateToDay(time.Now()))
+}
+
+// RenewExpiringCards заранее выпускает замену активным картам, срок действия которых
+// истекает в ближайшие renewalDays дней, и уведомляет владельцев. Старая карта действует до своего срока.
+func (s *CardService) RenewExpiringCards(ctx context.Context) (int, error) {
+	cards, err := s.cardRepo.GetExpiring(ctx, truncateToDay(time.Now()).AddDate(0, 0, s.renewalDays))
+	if err != nil {
+		return 0, fmt.Errorf("failed to get expiring cards: %v", err)
+	}
+
+	renewed := 0
+	for _, card := range cards {
+		newCard, err := s.renewCard(ctx, card)
+		if err != nil {
+			log.Printf("Error renewing card %d: %v", card.ID, err)
+			continue
+		}
+		renewed++
+
+		s.audit(ctx, 0, models.AuditActionCardRenew, card.ID, fmt.Sprintf("new card %d", newCard.ID))
+		s.sendRenewalNotification(ctx, card, newCard)
+	}
+
+	return renewed, nil
+}
+
+func (s *CardService) renewCard(ctx context.Context, card *models.Card) (*models.Card, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	newCard, err := s.replaceCard(ctx, tx, card)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return newCard, nil
+}
+
+// replaceCard выпускает карту к тому же счету и связывает с ней старую
+func (s *CardService) replaceCard(ctx context.Context, tx *sql.Tx, card *models.Card) (*models.Card, error) {
+	newCard, err := s.issueCard(ctx, tx, &models.CardIssueRequest{AccountID: card.AccountID, Type: models.CardTypeStandard})
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue card: %v", err)
+	}
+
+	linked, err := s.cardRepo.SetReplacedBy(ctx, tx, card.ID, newCard.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to link replacement card: %v", err)
+	}
+	if !linked {
+		return nil, models.ErrCardAlreadyReissued
+	}
+
+	// Подписки продолжают списываться с новой карты без повторного оформления
+	if err := s.cardRepo.MoveMandates(ctx, tx, card.ID, newCard.ID); err != nil {
+		return nil, fmt.Errorf("failed to move card mandates: %v", err)
+	}
+
+	return newCard, nil
+}
+
+func (s *CardService) sendRenewalNotification(ctx context.Context, card, newCard *models.Card) {
+	account, err := s.accountRepo.GetByID(ctx, card.AccountID)
+	if err != nil || account == nil {
+		log.Printf("Error getting account %d for card renewal notification: %v", card.AccountID, err)
+		return
+	}
+
+	user, err := s.userRepo.GetByID(ctx, account.UserID)
+	if err != nil {
+		log.Printf("Error getting user %d for card renewal notification: %v", account.UserID, err)
+		return
+	}
+
+	err = s.smtpService.SendCardRenewalNotification(user.Email, card.Last4.String, newCard.Last4.String, newCard.ExpiryDate.Format("01/06"))
+	if err != nil {
+		log.Printf("Error sending card renewal notification for card %d: %v", card.ID, err)
+	}
+}
+
+func (s *CardService) block(ctx context.Context, card *models.Card, req *models.CardBlockRequest, blockedBy string, actorID int64) (*models.Card, error) {
+	status := models.CardStatusBlocked
+	if req.Permanent {
+		status = models.CardStatusPermBlocked
+	}
+	if card.Status == status {
+		// Банк может перехватить временную блокировку владельца, чтобы тот не снял ее сам
+		if status != models.CardStatusBlocked || card.BlockedBy.String == blockedBy {
+			return nil, models.ErrCardStatusChange
+		}
+	} else if !models.CanChangeCardStatus(card.Status, status) {
+		return nil, models.ErrCardStatusChange
+	}
+
+	fromStatus := card.Status
+	card.Status = status
+	card.BlockReason = sql.NullString{String: req.Reason, Valid: req.Reason != ""}
+	card.BlockedBy = sql.NullString{String: blockedBy, Valid: true}
+
+	if err := s.changeStatus(ctx, card, fromStatus); err != nil {
+		return nil, err
+	}
+
+	s.audit(ctx, actorID, models.AuditActionCardBlock, card.ID, fmt.Sprintf("%s by %s: %s", status, blockedBy, req.Reason))
+
+	return card, nil
+}
+
+func (s *CardService) unblock(ctx context.Context, card *models.Card, actorID int64) (*models.Card, error) {
+	if !models.CanChangeCardStatus(card.Status, models.CardStatusActive) {
+		return nil, models.ErrCardStatusChange
+	}
+	if isCardExpired(card, time.Now()) {
+		return nil, models.ErrCardExpired
+	}
+
+	fromStatus := card.Status
+	card.Status = models.CardStatusActive
+	card.BlockReason = sql.NullString{}
+	card.BlockedBy = sql.NullString{}
+
+	if err := s.changeStatus(ctx, card, fromStatus); err != nil {
+		return nil, err
+	}
+
+	s.audit(ctx, actorID, models.AuditActionCardUnblock, card.ID, "")
+
+	return card, nil
+}
+
+func (s *CardService) changeStatus(ctx context.Context, card *models.Card, fromStatus string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := s.updateStatus(ctx, tx, card, fromStatus); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+func (s *CardService) updateStatus(ctx context.Context, tx *sql.Tx, card *models.Card, fromStatus string) error {
+	updated, err := s.cardRepo.UpdateStatus(ctx, tx, card, fromStatus)
+	if err != nil {
+		return fmt.Errorf("failed to update card status: %v", err)
+	}
+	// Статус успел измениться параллельным запросом
+	if !updated {
+		return models.ErrCardStatusChange
+	}
+	return nil
+}
+
+func (s *CardService) getCard(cardID int64) (*models.Card, error) {
+	card, err := s.cardRepo.GetByID(cardID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get card: %v", err)
+	}
+	if card == nil {
+		return nil, models.ErrCardNotFound
+	}
+	return card, nil
+}
+
+// audit записывает действие с картой в журнал; actorID 0 означает действие системы
+func (s *CardService) audit(ctx context.Context, actorID int64, action string, cardID int64, details string) {
+	record := &models.AuditRecord{
+		UserID:     sql.NullInt64{Int64: actorID, Valid: actorID != 0},
+		Action:     action,
+		EntityType: models.AuditEntityCard,
+		EntityID:   cardID,
+		Details:    sql.NullString{String: details, Valid: details != ""},
+	}
+	if err := s.auditRepo.Create(ctx, record); err != nil {
+		log.Printf("Error writing audit record %s for card %d: %v", action, cardID, err)
+	}
+}
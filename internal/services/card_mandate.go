@@ -0,0 +1,339 @@
+package services
+
+import (
+	"banksystem/internal/crypto"
+	"banksystem/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// CreateMandate оформляет продавцу разрешение на регулярные списания по реквизитам карты.
+// Реквизиты и CVV проверяются один раз; последующие списания проводятся по ID разрешения.
+// Разрешение с лимитом выше порога подтверждения действует только после ввода кода, отправленного владельцу карты.
+func (s *CardService) CreateMandate(ctx context.Context, userID int64, req *models.CardMandateRequest) (*models.CardMandate, error) {
+	card, err := s.findByCardData(ctx, req.CardNumber, req.ExpiryDate)
+	if err != nil {
+		return nil, err
+	}
+	if card.Type == models.CardTypeSingleUse {
+		return nil, models.ErrVirtualCard
+	}
+
+	merchantAccount, err := s.accountRepo.GetByID(ctx, req.MerchantAccountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get merchant account: %v", err)
+	}
+	if merchantAccount == nil {
+		return nil, models.ErrAccountNotFound
+	}
+	if merchantAccount.UserID != userID {
+		return nil, models.ErrAccessDenied
+	}
+	if merchantAccount.ID == card.AccountID {
+		return nil, models.ErrInvalidMerchant
+	}
+
+	if err := s.authorize(ctx, card, req.CVV, merchantAccount.ID); err != nil {
+		return nil, err
+	}
+
+	mandate := &models.CardMandate{
+		CardID:            card.ID,
+		MerchantAccountID: merchantAccount.ID,
+		MerchantName:      strings.TrimSpace(req.MerchantName),
+		MerchantMCC:       sql.NullString{String: req.MerchantMCC, Valid: req.MerchantMCC != ""},
+		MerchantCountry:   req.Country,
+		AmountCap:         req.AmountCap,
+		Frequency:         req.Frequency,
+		Status:            models.MandateStatusActive,
+		CreatedAt:         time.Now(),
+	}
+
+	// Первое списание по разрешению возможно сразу, поэтому крупный лимит подтверждается так же, как онлайн-платеж
+	var code string
+	if s.requiresOTP(models.PaymentChannelEcom, req.AmountCap) {
+		code, err = crypto.RandomDigits(models.OTPLength)
+		if err != nil {
+			return nil, err
+		}
+		codeHash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash confirmation code: %v", err)
+		}
+		mandate.Status = models.MandateStatusPending
+		mandate.CodeHash = sql.NullString{String: string(codeHash), Valid: true}
+		mandate.OTPExpiresAt = sql.NullTime{Time: mandate.CreatedAt.Add(s.otpTTL()), Valid: true}
+	}
+
+	if err := s.cardRepo.CreateMandate(ctx, mandate); err != nil {
+		return nil, fmt.Errorf("failed to create mandate: %v", err)
+	}
+
+	s.audit(ctx, userID, models.AuditActionCardMandate, card.ID,
+		fmt.Sprintf("mandate %d created for %s: up to %.2f %s", mandate.ID, mandate.MerchantName, mandate.AmountCap, mandate.Frequency))
+
+	if mandate.Status == models.MandateStatusPending {
+		if err := s.sendMandateCode(ctx, card, mandate, code); err != nil {
+			return nil, err
+		}
+	}
+
+	return mandate, nil
+}
+
+// sendMandateCode отправляет владельцу карты код подтверждения разрешения.
+// Если код не доставлен, разрешение отзывается: подтвердить его все равно нельзя.
+func (s *CardService) sendMandateCode(ctx context.Context, card *models.Card, mandate *models.CardMandate, code string) error {
+	account, err := s.accountRepo.GetByID(ctx, card.AccountID)
+	if err == nil && account == nil {
+		err = models.ErrAccountNotFound
+	}
+	var user *models.User
+	if err == nil {
+		user, err = s.userRepo.GetByID(ctx, account.UserID)
+	}
+	if err == nil {
+		err = s.smtpService.SendMandateConfirmationCode(user.Email, code, mandate.AmountCap, mandate.Frequency,
+			mandate.MerchantName, card.Last4.String, int(mandate.OTPExpiresAt.Time.Sub(mandate.CreatedAt)/time.Minute))
+	}
+	if err == nil {
+		return nil
+	}
+
+	log.Printf("Error sending code for mandate %d: %v", mandate.ID, err)
+	mandate.Status = models.MandateStatusRevoked
+	mandate.RevokedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	if _, updateErr := s.cardRepo.UpdateMandateStatus(ctx, mandate, models.MandateStatusPending); updateErr != nil {
+		return fmt.Errorf("failed to update mandate status: %v", updateErr)
+	}
+	return fmt.Errorf("failed to send confirmation code: %v", err)
+}
+
+// ConfirmMandate активирует разрешение по коду, который владелец карты сообщил продавцу.
+// После MaxOTPAttempts неверных кодов или по истечении срока кода разрешение отзывается.
+func (s *CardService) ConfirmMandate(ctx context.Context, userID, mandateID int64, req *models.CardPaymentConfirmRequest) (*models.CardMandate, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	mandate, err := s.cardRepo.GetMandateForUpdate(ctx, tx, mandateID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mandate: %v", err)
+	}
+	if mandate == nil {
+		return nil, models.ErrMandateNotFound
+	}
+	merchantAccount, err := s.accountRepo.GetByID(ctx, mandate.MerchantAccountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get merchant account: %v", err)
+	}
+	if merchantAccount == nil || merchantAccount.UserID != userID {
+		return nil, models.ErrMandateNotFound
+	}
+	if mandate.Status != models.MandateStatusPending {
+		return nil, models.ErrConfirmationNotPending
+	}
+
+	now := time.Now()
+	var confirmErr error
+	switch {
+	case now.After(mandate.OTPExpiresAt.Time):
+		confirmErr = models.ErrConfirmationExpired
+	case bcrypt.CompareHashAndPassword([]byte(mandate.CodeHash.String), []byte(req.Code)) != nil:
+		mandate.OTPAttempts++
+		confirmErr = models.ErrWrongOTP
+		if mandate.OTPAttempts >= models.MaxOTPAttempts {
+			confirmErr = models.ErrOTPAttemptsExceeded
+		}
+	}
+
+	switch confirmErr {
+	case nil:
+		mandate.Status = models.MandateStatusActive
+		mandate.CodeHash = sql.NullString{}
+	case models.ErrWrongOTP:
+	default:
+		mandate.Status = models.MandateStatusRevoked
+		mandate.RevokedAt = sql.NullTime{Time: now, Valid: true}
+		mandate.CodeHash = sql.NullString{}
+	}
+
+	// Неверная попытка сохраняется, даже если возвращается ошибка
+	if err := s.cardRepo.UpdateMandateConfirmation(ctx, tx, mandate); err != nil {
+		return nil, fmt.Errorf("failed to update mandate: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	if confirmErr != nil {
+		return nil, confirmErr
+	}
+
+	s.audit(ctx, userID, models.AuditActionCardMandate, mandate.CardID,
+		fmt.Sprintf("mandate %d confirmed by card owner", mandate.ID))
+
+	return mandate, nil
+}
+
+// GetMandates возвращает разрешения на регулярные списания по карте владельца
+func (s *CardService) GetMandates(ctx context.Context, userID, cardID int64) ([]*models.CardMandate, error) {
+	card, err := s.GetCard(ctx, userID, cardID)
+	if err != nil {
+		return nil, err
+	}
+
+	mandates, err := s.cardRepo.GetMandatesByCard(ctx, card.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mandates: %v", err)
+	}
+
+	return mandates, nil
+}
+
+// PauseMandate приостанавливает списания по разрешению до возобновления владельцем
+func (s *CardService) PauseMandate(ctx context.Context, userID, mandateID int64) (*models.CardMandate, error) {
+	return s.changeMandateStatus(ctx, userID, mandateID, models.MandateStatusPaused)
+}
+
+// ResumeMandate возобновляет приостановленные списания
+func (s *CardService) ResumeMandate(ctx context.Context, userID, mandateID int64) (*models.CardMandate, error) {
+	return s.changeMandateStatus(ctx, userID, mandateID, models.MandateStatusActive)
+}
+
+// RevokeMandate отзывает разрешение; все последующие списания по нему отклоняются
+func (s *CardService) RevokeMandate(ctx context.Context, userID, mandateID int64) (*models.CardMandate, error) {
+	return s.changeMandateStatus(ctx, userID, mandateID, models.MandateStatusRevoked)
+}
+
+func (s *CardService) changeMandateStatus(ctx context.Context, userID, mandateID int64, status string) (*models.CardMandate, error) {
+	mandate, err := s.cardRepo.GetMandate(ctx, mandateID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mandate: %v", err)
+	}
+	if mandate == nil {
+		return nil, models.ErrMandateNotFound
+	}
+	if _, err := s.GetCard(ctx, userID, mandate.CardID); err != nil {
+		if errors.Is(err, models.ErrAccessDenied) {
+			return nil, models.ErrMandateNotFound
+		}
+		return nil, err
+	}
+
+	if mandate.Status == status {
+		return mandate, nil
+	}
+	if mandate.Status == models.MandateStatusRevoked {
+		return nil, models.ErrMandateRevoked
+	}
+	// Неподтвержденное разрешение можно только отозвать
+	if mandate.Status == models.MandateStatusPending && status != models.MandateStatusRevoked {
+		return nil, models.ErrMandateNotConfirmed
+	}
+
+	fromStatus := mandate.Status
+	mandate.Status = status
+	if status == models.MandateStatusRevoked {
+		mandate.RevokedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	}
+
+	// Списание держит блокировку строки разрешения, поэтому отзыв дожидается его окончания
+	updated, err := s.cardRepo.UpdateMandateStatus(ctx, mandate, fromStatus)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update mandate status: %v", err)
+	}
+	if !updated {
+		return nil, models.ErrMandateStatusChange
+	}
+
+	s.audit(ctx, userID, models.AuditActionCardMandate, mandate.CardID,
+		fmt.Sprintf("mandate %d: %s -> %s", mandate.ID, fromStatus, status))
+
+	return mandate, nil
+}
+
+// ChargeMandate проводит очередное списание продавцом userID по разрешению. Сумма не должна превышать
+// установленную в разрешении, а с прошлого списания должен пройти период frequency.
+// Ограничения карты и лимиты банка применяются как к обычному онлайн-платежу.
+func (s *CardService) ChargeMandate(ctx context.Context, userID, mandateID int64, req *models.MandateChargeRequest) (*models.Transaction, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	mandate, err := s.cardRepo.GetMandateForUpdate(ctx, tx, mandateID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mandate: %v", err)
+	}
+	if mandate == nil {
+		return nil, models.ErrMandateNotFound
+	}
+
+	card, err := s.cardRepo.GetByID(mandate.CardID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get card: %v", err)
+	}
+	if card == nil {
+		return nil, models.ErrCardNotFound
+	}
+
+	account, merchantAccount, err := s.lockAccounts(ctx, tx, card.AccountID, mandate.MerchantAccountID)
+	if err != nil {
+		return nil, err
+	}
+	if merchantAccount.UserID != userID {
+		return nil, models.ErrMandateNotFound
+	}
+
+	now := time.Now()
+	switch {
+	case mandate.Status == models.MandateStatusRevoked:
+		return nil, models.ErrMandateRevoked
+	case mandate.Status == models.MandateStatusPaused:
+		return nil, models.ErrMandatePaused
+	case mandate.Status == models.MandateStatusPending:
+		return nil, models.ErrMandateNotConfirmed
+	case req.Amount > mandate.AmountCap:
+		return nil, models.ErrMandateAmountExceeded
+	case mandate.LastChargedAt.Valid && now.Before(mandate.NextChargeAt()):
+		return nil, models.ErrMandateTooFrequent
+	}
+
+	if err := s.checkCard(card, mandate.MerchantAccountID); err != nil {
+		return nil, err
+	}
+	if err := s.checkControls(ctx, tx, card, req.Amount, mandate.MerchantMCC.String, models.PaymentChannelEcom, mandate.MerchantCountry); err != nil {
+		return nil, err
+	}
+
+	transaction, err := s.debit(ctx, tx, card, account, merchantAccount, req.Amount, mandate.MerchantName, mandate.MerchantMCC.String, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.cardRepo.SetMandateCharged(ctx, tx, mandate.ID, now); err != nil {
+		return nil, fmt.Errorf("failed to update mandate: %v", err)
+	}
+
+	sendBudgetAlerts, err := s.budgetService.TrackSpending(ctx, tx, account.UserID, account.ID, "", req.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	sendBudgetAlerts()
+
+	return transaction, nil
+}
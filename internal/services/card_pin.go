@@ -0,0 +1,91 @@
+package services
+
+import (
+	"banksystem/internal/models"
+	"context"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"log"
+)
+
+// SetPIN устанавливает или меняет PIN-код карты. Для смены нужен текущий PIN,
+// неверный текущий PIN учитывается в счетчике попыток так же, как при оплате.
+func (s *CardService) SetPIN(ctx context.Context, userID, cardID int64, req *models.CardPINRequest) (*models.Card, error) {
+	card, err := s.GetCard(ctx, userID, cardID)
+	if err != nil {
+		return nil, err
+	}
+	if card.Status != models.CardStatusActive {
+		return nil, models.ErrCardNotActive
+	}
+
+	details := "set"
+	if card.PINHash.Valid {
+		if req.CurrentPIN == "" {
+			return nil, models.ErrPINRequired
+		}
+		if err := s.verifyPIN(ctx, card, req.CurrentPIN); err != nil {
+			return nil, err
+		}
+		details = "changed"
+	}
+
+	pinHash, err := bcrypt.GenerateFromPassword([]byte(req.PIN), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash PIN: %v", err)
+	}
+
+	if err := s.cardRepo.SetPIN(ctx, card.ID, string(pinHash)); err != nil {
+		return nil, fmt.Errorf("failed to save PIN: %v", err)
+	}
+	card.PINHash.String, card.PINHash.Valid = string(pinHash), true
+	card.PINAttempts = 0
+
+	s.audit(ctx, userID, models.AuditActionCardPINSet, card.ID, details)
+
+	return card, nil
+}
+
+// checkPaymentPIN требует PIN-код для платежей на сумму выше порога
+func (s *CardService) checkPaymentPIN(ctx context.Context, card *models.Card, amount float64, pin string) error {
+	if s.limits.PINThreshold <= 0 || amount <= s.limits.PINThreshold {
+		return nil
+	}
+	if !card.PINHash.Valid {
+		return models.ErrPINNotSet
+	}
+	if pin == "" {
+		return models.ErrPINRequired
+	}
+	return s.verifyPIN(ctx, card, pin)
+}
+
+// verifyPIN сверяет PIN с хешем. Неверная попытка фиксируется сразу, вне транзакции платежа,
+// чтобы откат платежа не сбрасывал счетчик. Сам PIN никуда не записывается.
+func (s *CardService) verifyPIN(ctx context.Context, card *models.Card, pin string) error {
+	if bcrypt.CompareHashAndPassword([]byte(card.PINHash.String), []byte(pin)) == nil {
+		if card.PINAttempts > 0 {
+			if err := s.cardRepo.ResetPINAttempts(ctx, card.ID); err != nil {
+				return fmt.Errorf("failed to reset PIN attempts: %v", err)
+			}
+		}
+		return nil
+	}
+
+	attempts, blocked, err := s.cardRepo.RegisterPINFailure(ctx, card.ID, models.MaxPINAttempts, models.CardBlockReasonPINAttempts)
+	if err != nil {
+		return fmt.Errorf("failed to register PIN attempt: %v", err)
+	}
+
+	if blocked {
+		log.Printf("Card %d blocked after %d wrong PIN attempts", card.ID, attempts)
+		s.audit(ctx, 0, models.AuditActionCardBlock, card.ID,
+			fmt.Sprintf("%s by %s: %s", models.CardStatusBlocked, models.CardBlockedBySystem, models.CardBlockReasonPINAttempts))
+		return models.ErrCardPINBlocked
+	}
+	if attempts >= models.MaxPINAttempts {
+		return models.ErrCardPINBlocked
+	}
+
+	return models.ErrWrongPIN
+}
@@ -1,162 +1,817 @@
 package services
 
 import (
+	"banksystem/internal/crypto"
 	"banksystem/internal/models"
 	"banksystem/internal/repositories"
-	"bytes"
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/base64"
+	"context"
+	"database/sql"
 	"errors"
-	"golang.org/x/crypto/bcrypt"
-	"golang.org/x/crypto/openpgp"
-	"golang.org/x/crypto/openpgp/armor"
-	"math/rand"
-	"strconv"
+	"fmt"
+	"log"
+	"strings"
 	"time"
 )
 
+// cardValidityYears срок действия выпускаемой карты
+const cardValidityYears = 4
+
+// maxPANAttempts сколько раз генерируется новый номер, если сгенерированный уже занят
+const maxPANAttempts = 10
+
 type CardService struct {
-	cardRepo *repositories.CardRepository
-	key      *openpgp.Entity
+	cardRepo        *repositories.CardRepository
+	accountRepo     *repositories.AccountRepository
+	transactionRepo *repositories.TransactionRepository
+	auditRepo       *repositories.AuditRepository
+	userRepo        *repositories.UserRepository
+	budgetService   *BudgetService
+	accountService  *AccountService
+	smtpService     *SMTPService
+	db              *sql.DB
+	keys            *crypto.KeyManager
+	hmacKeys        *crypto.HMACKeyring
+	panGenerator    *crypto.PANGenerator
+	panHasher       *crypto.PANHasher
+	limits          models.CardLimits
+	renewalDays     int
 }
 
-func NewCardService(cardRepo *repositories.CardRepository, key *openpgp.Entity) *CardService {
+func NewCardService(
+	db *sql.DB,
+	cardRepo *repositories.CardRepository,
+	accountRepo *repositories.AccountRepository,
+	transactionRepo *repositories.TransactionRepository,
+	auditRepo *repositories.AuditRepository,
+	userRepo *repositories.UserRepository,
+	budgetService *BudgetService,
+	accountService *AccountService,
+	smtpService *SMTPService,
+	keys *crypto.KeyManager,
+	hmacKeys *crypto.HMACKeyring,
+	panGenerator *crypto.PANGenerator,
+	panHasher *crypto.PANHasher,
+	limits models.CardLimits,
+	renewalDays int,
+) *CardService {
 	return &CardService{
-		cardRepo: cardRepo,
-		key:      key,
+		db:              db,
+		cardRepo:        cardRepo,
+		accountRepo:     accountRepo,
+		transactionRepo: transactionRepo,
+		auditRepo:       auditRepo,
+		userRepo:        userRepo,
+		budgetService:   budgetService,
+		accountService:  accountService,
+		smtpService:     smtpService,
+		keys:            keys,
+		hmacKeys:        hmacKeys,
+		panGenerator:    panGenerator,
+		panHasher:       panHasher,
+		limits:          limits,
+		renewalDays:     renewalDays,
 	}
 }
 
-func (s *CardService) CreateCard(accountID int64) (*models.Card, error) {
-	// Генерация номера карты по алгоритму Луна
-	cardNumber := generateLuhnCardNumber()
-	
-	// Генерация CVV
-	cvv := strconv.Itoa(rand.Intn(900) + 100)
-	
-	// Хеширование CVV
-	hashedCVV, err := bcrypt.GenerateFromPassword([]byte(cvv), bcrypt.DefaultCost)
+// CreateCard выпускает карту к счету пользователя: обычную или виртуальную (одноразовую либо привязанную к продавцу)
+func (s *CardService) CreateCard(ctx context.Context, userID int64, req *models.CardIssueRequest) (*models.Card, error) {
+	account, err := s.accountRepo.GetByID(ctx, req.AccountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account: %v", err)
+	}
+	if account == nil {
+		return nil, models.ErrAccountNotFound
+	}
+	if account.UserID != userID {
+		return nil, models.ErrAccessDenied
+	}
+
+	if req.Type == models.CardTypeMerchantLocked {
+		merchantAccount, err := s.accountRepo.GetByID(ctx, req.MerchantAccountID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get merchant account: %v", err)
+		}
+		if merchantAccount == nil {
+			return nil, models.ErrInvalidMerchant
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	card, err := s.issueCard(ctx, tx, req)
 	if err != nil {
 		return nil, err
 	}
-	
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return card, nil
+}
+
+// issueCard выпускает новую карту к счету внутри транзакции
+func (s *CardService) issueCard(ctx context.Context, tx *sql.Tx, req *models.CardIssueRequest) (*models.Card, error) {
+	for attempt := 0; attempt < maxPANAttempts; attempt++ {
+		card, created, err := s.tryIssueCard(ctx, tx, req)
+		if err != nil {
+			return nil, err
+		}
+		if created {
+			return card, nil
+		}
+	}
+
+	return nil, fmt.Errorf("failed to generate unique card number after %d attempts", maxPANAttempts)
+}
+
+// tryIssueCard генерирует номер и сохраняет карту; false означает, что номер уже занят
+func (s *CardService) tryIssueCard(ctx context.Context, tx *sql.Tx, req *models.CardIssueRequest) (*models.Card, bool, error) {
+	cardNumber, err := s.panGenerator.Generate()
+	if err != nil {
+		return nil, false, err
+	}
+
+	cvv, err := crypto.GenerateCVV()
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Хеширование CVV
+	hashedCVV, err := crypto.HashCVV(cvv)
+	if err != nil {
+		return nil, false, err
+	}
+
 	// Шифрование данных карты
-	encryptedData, err := s.encryptCardData(cardNumber, cvv)
+	encryptedData, keyID, err := s.keys.EncryptCardData(cardNumber, cvv)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
-	
+
 	// Создание HMAC
-	hmac := s.createHMAC(encryptedData)
-	
+	hmac, hmacVersion := s.hmacKeys.Compute(encryptedData)
+
+	now := time.Now()
 	card := &models.Card{
-		AccountID:     accountID,
+		AccountID:     req.AccountID,
 		EncryptedData: encryptedData,
-		HashedCVV:     string(hashedCVV),
+		HashedCVV:     hashedCVV,
 		HMAC:          hmac,
-		CreatedAt:     time.Now(),
+		HMACVersion:   hmacVersion,
+		KeyID:         sql.NullString{String: keyID, Valid: true},
+		PANHash:       sql.NullString{String: s.panHasher.Hash(cardNumber), Valid: true},
+		Last4:         sql.NullString{String: cardNumber[len(cardNumber)-4:], Valid: true},
+		Brand:         sql.NullString{String: models.DetectCardBrand(cardNumber), Valid: true},
+		ExpiryDate:    cardExpiry(now),
+		Type:          models.CardTypeStandard,
+		Status:        models.CardStatusActive,
+		CreatedAt:     now,
+	}
+
+	if req.Type == models.CardTypeSingleUse || req.Type == models.CardTypeMerchantLocked {
+		card.Type = req.Type
+		card.ExpiryDate = truncateToDay(now).AddDate(0, 0, req.ValidDays)
+		card.SpendCap = sql.NullFloat64{Float64: req.SpendCap, Valid: true}
+		card.LockedMerchant = sql.NullString{String: req.MerchantName, Valid: req.MerchantName != ""}
+		card.LockedMerchantAccountID = sql.NullInt64{Int64: req.MerchantAccountID, Valid: req.MerchantAccountID != 0}
 	}
-	
-	err = s.cardRepo.Create(card)
+
+	created, err := s.cardRepo.Create(ctx, tx, card)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return card, created, nil
+}
+
+// GetCard возвращает карту, если она выпущена к счету пользователя
+func (s *CardService) GetCard(ctx context.Context, userID, id int64) (*models.Card, error) {
+	card, err := s.getCard(id)
 	if err != nil {
 		return nil, err
 	}
-	
+
+	account, err := s.accountRepo.GetByID(ctx, card.AccountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account: %v", err)
+	}
+	if account == nil || account.UserID != userID {
+		return nil, models.ErrAccessDenied
+	}
+
 	return card, nil
 }
 
-func (s *CardService) GetCard(id int64) (*models.Card, error) {
-	return s.cardRepo.GetByID(id)
+// RevealCard расшифровывает реквизиты карты для владельца.
+// Каждый показ записывается в журнал аудита; без записи реквизиты не выдаются.
+func (s *CardService) RevealCard(ctx context.Context, userID, id int64, ipAddress string) (*models.CardRevealResponse, error) {
+	card, err := s.GetCard(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Реквизиты закрытых и просроченных карт не показываются
+	if card.Status != models.CardStatusActive && card.Status != models.CardStatusBlocked {
+		return nil, models.ErrCardNotActive
+	}
+
+	if !s.verifyHMAC(card) {
+		return nil, models.ErrCardTampered
+	}
+
+	cardNumber, cvv, err := s.keys.DecryptCardData(card.EncryptedData)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &models.AuditRecord{
+		UserID:     sql.NullInt64{Int64: userID, Valid: true},
+		Action:     models.AuditActionCardReveal,
+		EntityType: models.AuditEntityCard,
+		EntityID:   card.ID,
+		IPAddress:  sql.NullString{String: ipAddress, Valid: ipAddress != ""},
+	}
+	if err := s.auditRepo.Create(ctx, record); err != nil {
+		return nil, fmt.Errorf("failed to write audit record: %v", err)
+	}
+
+	return &models.CardRevealResponse{
+		ID:         card.ID,
+		CardNumber: cardNumber,
+		Brand:      models.DetectCardBrand(cardNumber),
+		ExpiryDate: card.ExpiryDate.Format("01/06"),
+		CVV:        cvv,
+	}, nil
 }
 
 func (s *CardService) GetUserCards(userID int64) ([]*models.Card, error) {
 	return s.cardRepo.GetByUserID(userID)
 }
 
-func (s *CardService) encryptCardData(cardNumber, cvv string) (string, error) {
-	// Создаем буфер для зашифрованных данных
-	buf := new(bytes.Buffer)
-	
-	// Создаем armor-кодировщик
-	armorWriter, err := armor.Encode(buf, "PGP MESSAGE", nil)
+// ReencryptBatch перешифровывает активным ключом до batchSize карт с id больше afterID,
+// зашифрованных другим ключом. Возвращает число обработанных карт и id последней просмотренной.
+// Каждая пачка фиксируется отдельной транзакцией, поэтому прерванную ротацию можно просто запустить заново.
+func (s *CardService) ReencryptBatch(ctx context.Context, afterID int64, batchSize int) (int, int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return "", err
+		return 0, afterID, fmt.Errorf("failed to begin transaction: %v", err)
 	}
-	
-	// Создаем зашифрованный writer
-	encryptedWriter, err := openpgp.Encrypt(armorWriter, []*openpgp.Entity{s.key}, nil, nil, nil)
+	defer tx.Rollback()
+
+	cards, err := s.cardRepo.GetForReencryption(ctx, tx, s.keys.ActiveKeyID(), afterID, batchSize)
 	if err != nil {
-		return "", err
+		return 0, afterID, fmt.Errorf("failed to get cards: %v", err)
+	}
+
+	lastID := afterID
+	processed := 0
+	for _, card := range cards {
+		lastID = card.ID
+		// Карты с нарушенной целостностью не перешифровываем, чтобы не узаконить подмену
+		if !s.verifyHMAC(card) {
+			log.Printf("Card %d skipped during key rotation: HMAC mismatch", card.ID)
+			continue
+		}
+
+		cardNumber, cvv, err := s.keys.DecryptCardData(card.EncryptedData)
+		if err != nil {
+			return 0, afterID, fmt.Errorf("failed to decrypt card %d: %v", card.ID, err)
+		}
+
+		encryptedData, keyID, err := s.keys.EncryptCardData(cardNumber, cvv)
+		if err != nil {
+			return 0, afterID, fmt.Errorf("failed to encrypt card %d: %v", card.ID, err)
+		}
+
+		card.EncryptedData = encryptedData
+		card.HMAC, card.HMACVersion = s.hmacKeys.Compute(encryptedData)
+		card.KeyID = sql.NullString{String: keyID, Valid: true}
+		if err := s.cardRepo.UpdateEncryptedData(ctx, tx, card); err != nil {
+			return 0, afterID, fmt.Errorf("failed to update card %d: %v", card.ID, err)
+		}
+		processed++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, afterID, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return processed, lastID, nil
+}
+
+// CheckIntegrity пересчитывает HMAC всех карт пачками по batchSize и сверяет его с хранимым.
+// Несовпадения записываются в журнал аудита. Карты, подписанные устаревшей версией ключа,
+// переподписываются активной версией. Возвращает число проверенных и поврежденных карт.
+func (s *CardService) CheckIntegrity(ctx context.Context, batchSize int) (int, int, error) {
+	var afterID int64
+	checked, tampered := 0, 0
+
+	for {
+		cards, err := s.cardRepo.GetBatch(ctx, afterID, batchSize)
+		if err != nil {
+			return checked, tampered, fmt.Errorf("failed to get cards: %v", err)
+		}
+		if len(cards) == 0 {
+			return checked, tampered, nil
+		}
+
+		for _, card := range cards {
+			afterID = card.ID
+			checked++
+
+			valid := false
+			expected, err := s.hmacKeys.ComputeVersion(card.EncryptedData, card.HMACVersion)
+			if err == nil {
+				valid, err = s.cardRepo.VerifyHMAC(card.ID, expected)
+				if err != nil {
+					return checked, tampered, fmt.Errorf("failed to verify card %d: %v", card.ID, err)
+				}
+			}
+
+			if !valid {
+				tampered++
+				s.reportTampering(ctx, card)
+				continue
+			}
+
+			if card.HMACVersion != s.hmacKeys.ActiveVersion() {
+				mac, version := s.hmacKeys.Compute(card.EncryptedData)
+				if err := s.cardRepo.UpdateHMAC(ctx, card.ID, card.HMAC, mac, version); err != nil {
+					return checked, tampered, fmt.Errorf("failed to update card %d HMAC: %v", card.ID, err)
+				}
+			}
+		}
 	}
-	
-	// Записываем данные карты
-	_, err = encryptedWriter.Write([]byte(cardNumber + "|" + cvv))
+}
+
+// BackfillPANHashes вычисляет хеш номера для пачки из batchSize карт, выпущенных до появления pan_hash,
+// начиная с ID больше afterID. Карты с неверным HMAC пропускаются и записываются в журнал аудита.
+// Возвращает число заполненных хешей и ID последней обработанной карты; ID не меняется, когда карт не осталось.
+func (s *CardService) BackfillPANHashes(ctx context.Context, afterID int64, batchSize int) (int, int64, error) {
+	cards, err := s.cardRepo.GetWithoutPANHash(ctx, afterID, batchSize)
 	if err != nil {
-		return "", err
+		return 0, afterID, fmt.Errorf("failed to get cards: %v", err)
+	}
+
+	filled := 0
+	for _, card := range cards {
+		afterID = card.ID
+		if !s.verifyHMAC(card) {
+			s.reportTampering(ctx, card)
+			continue
+		}
+
+		cardNumber, _, err := s.keys.DecryptCardData(card.EncryptedData)
+		if err != nil {
+			return filled, afterID, fmt.Errorf("failed to decrypt card %d: %v", card.ID, err)
+		}
+		err = s.cardRepo.SetPANHash(ctx, card.ID, s.panHasher.Hash(cardNumber), cardNumber[len(cardNumber)-4:], models.DetectCardBrand(cardNumber))
+		if err != nil {
+			return filled, afterID, fmt.Errorf("failed to set card %d PAN hash: %v", card.ID, err)
+		}
+		filled++
+	}
+
+	return filled, afterID, nil
+}
+
+func (s *CardService) reportTampering(ctx context.Context, card *models.Card) {
+	log.Printf("Card %d failed integrity check (HMAC version %d)", card.ID, card.HMACVersion)
+	s.audit(ctx, 0, models.AuditActionCardTampered, card.ID, fmt.Sprintf("HMAC mismatch, key version %d", card.HMACVersion))
+}
+
+func (s *CardService) VerifyCard(cardID int64, cvv string) error {
+	card, err := s.cardRepo.GetByID(cardID)
+	if err != nil {
+		return err
+	}
+
+	if !card.VerifyCVV(cvv) {
+		return errors.New("неверный CVV")
+	}
+
+	return nil
+}
+
+// Pay проводит оплату картой по инициативе владельца: списывает сумму со счета карты
+// и записывает операцию PAYMENT с данными продавца. Онлайн-платеж выше порога не проводится сразу,
+// а возвращается в статусе pending_confirmation до подтверждения кодом (ConfirmPayment).
+func (s *CardService) Pay(ctx context.Context, userID, cardID int64, req *models.CardPaymentRequest) (*models.Transaction, *models.CardPaymentConfirmation, error) {
+	card, err := s.cardRepo.GetByID(cardID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get card: %v", err)
+	}
+	if card == nil {
+		return nil, nil, models.ErrCardNotFound
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	account, err := s.accountRepo.GetByIDForUpdate(ctx, tx, card.AccountID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get account: %v", err)
+	}
+	if account == nil {
+		return nil, nil, models.ErrAccountNotFound
+	}
+	if account.UserID != userID {
+		return nil, nil, models.ErrAccessDenied
+	}
+
+	if err := s.authorize(ctx, card, req.CVV, 0); err != nil {
+		return nil, nil, err
 	}
-	
-	// Закрываем writers
-	err = encryptedWriter.Close()
+	if err := s.checkControls(ctx, tx, card, req.Amount, req.MerchantMCC, req.Channel, req.Country); err != nil {
+		return nil, nil, err
+	}
+	if err := s.checkPaymentPIN(ctx, card, req.Amount, req.PIN); err != nil {
+		return nil, nil, err
+	}
+
+	if s.requiresOTP(req.Channel, req.Amount) {
+		confirmation := &models.CardPaymentConfirmation{
+			InitiatorID:  userID,
+			Amount:       req.Amount,
+			MerchantName: req.MerchantName,
+			MerchantMCC:  sql.NullString{String: req.MerchantMCC, Valid: req.MerchantMCC != ""},
+			Category:     sql.NullString{String: req.Category, Valid: req.Category != ""},
+			Channel:      req.Channel,
+			Country:      req.Country,
+		}
+		code, err := s.requestConfirmation(ctx, tx, card, account, confirmation)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, nil, fmt.Errorf("failed to commit transaction: %v", err)
+		}
+		if err := s.sendConfirmationCode(ctx, card, account.UserID, confirmation, code); err != nil {
+			return nil, nil, err
+		}
+		return nil, confirmation, nil
+	}
+
+	transaction, err := s.debit(ctx, tx, card, account, nil, req.Amount, req.MerchantName, req.MerchantMCC, req.Category)
 	if err != nil {
-		return "", err
+		return nil, nil, err
 	}
-	
-	err = armorWriter.Close()
+
+	sendBudgetAlerts, err := s.budgetService.TrackSpending(ctx, tx, account.UserID, account.ID, req.Category, req.Amount)
 	if err != nil {
-		return "", err
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %v", err)
 	}
-	
-	return buf.String(), nil
+	sendBudgetAlerts()
+	s.auditSingleUse(ctx, card)
+
+	return transaction, nil, nil
 }
 
-func (s *CardService) createHMAC(data string) string {
-	h := hmac.New(sha256.New, []byte("secret-key"))
-	h.Write([]byte(data))
-	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+// PayByCardData проводит списание продавцом по реквизитам карты (номер, срок действия, CVV)
+// с зачислением на счет продавца, принадлежащий пользователю userID. Онлайн-платеж выше порога
+// ожидает подтверждения кодом, который владелец карты получает по почте и сообщает продавцу.
+func (s *CardService) PayByCardData(ctx context.Context, userID int64, req *models.MerchantPaymentRequest) (*models.Transaction, *models.CardPaymentConfirmation, error) {
+	card, err := s.findByCardData(ctx, req.CardNumber, req.ExpiryDate)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	// Блокируем счета в порядке возрастания ID, чтобы встречные платежи не приводили к взаимной блокировке
+	account, merchantAccount, err := s.lockAccounts(ctx, tx, card.AccountID, req.MerchantAccountID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if merchantAccount.UserID != userID {
+		return nil, nil, models.ErrAccessDenied
+	}
+	if merchantAccount.ID == account.ID {
+		return nil, nil, models.ErrInvalidMerchant
+	}
+
+	if err := s.authorize(ctx, card, req.CVV, req.MerchantAccountID); err != nil {
+		return nil, nil, err
+	}
+	if err := s.checkControls(ctx, tx, card, req.Amount, req.MerchantMCC, req.Channel, req.Country); err != nil {
+		return nil, nil, err
+	}
+	if err := s.checkPaymentPIN(ctx, card, req.Amount, req.PIN); err != nil {
+		return nil, nil, err
+	}
+
+	if s.requiresOTP(req.Channel, req.Amount) {
+		confirmation := &models.CardPaymentConfirmation{
+			MerchantAccountID: sql.NullInt64{Int64: merchantAccount.ID, Valid: true},
+			InitiatorID:       userID,
+			Amount:            req.Amount,
+			MerchantName:      req.MerchantName,
+			MerchantMCC:       sql.NullString{String: req.MerchantMCC, Valid: req.MerchantMCC != ""},
+			Channel:           req.Channel,
+			Country:           req.Country,
+		}
+		code, err := s.requestConfirmation(ctx, tx, card, account, confirmation)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, nil, fmt.Errorf("failed to commit transaction: %v", err)
+		}
+		if err := s.sendConfirmationCode(ctx, card, account.UserID, confirmation, code); err != nil {
+			return nil, nil, err
+		}
+		return nil, confirmation, nil
+	}
+
+	transaction, err := s.debit(ctx, tx, card, account, merchantAccount, req.Amount, req.MerchantName, req.MerchantMCC, "")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sendBudgetAlerts, err := s.budgetService.TrackSpending(ctx, tx, account.UserID, account.ID, "", req.Amount)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	sendBudgetAlerts()
+	s.auditSingleUse(ctx, card)
+
+	return transaction, nil, nil
 }
 
-func generateLuhnCardNumber() string {
-	// Генерация 15 цифр
-	digits := make([]int, 15)
-	for i := 0; i < 15; i++ {
-		digits[i] = rand.Intn(10)
+// authorize проверяет статус и срок действия карты, продавца для привязанной карты, целостность хранимых данных и CVV
+func (s *CardService) authorize(ctx context.Context, card *models.Card, cvv string, merchantAccountID int64) error {
+	if err := s.checkCard(card, merchantAccountID); err != nil {
+		return err
 	}
-	
-	// Вычисление контрольной суммы по алгоритму Луна
-	sum := 0
-	for i := 0; i < 15; i++ {
-		if i%2 == 0 {
-			digits[i] *= 2
-			if digits[i] > 9 {
-				digits[i] -= 9
+	return s.verifyCVV(ctx, card, cvv)
+}
+
+// verifyCVV сверяет CVV. Как и для PIN, неверная попытка фиксируется сразу, вне транзакции платежа,
+// и после MaxCVVAttempts подряд карта блокируется системой; верный CVV сбрасывает счетчик.
+func (s *CardService) verifyCVV(ctx context.Context, card *models.Card, cvv string) error {
+	if card.VerifyCVV(cvv) {
+		if card.CVVAttempts > 0 {
+			if err := s.cardRepo.ResetCVVAttempts(ctx, card.ID); err != nil {
+				return fmt.Errorf("failed to reset CVV attempts: %v", err)
 			}
 		}
-		sum += digits[i]
+		return nil
+	}
+
+	attempts, blocked, err := s.cardRepo.RegisterCVVFailure(ctx, card.ID, models.MaxCVVAttempts, models.CardBlockReasonCVVAttempts)
+	if err != nil {
+		return fmt.Errorf("failed to register CVV attempt: %v", err)
+	}
+
+	if blocked {
+		log.Printf("Card %d blocked after %d wrong CVV attempts", card.ID, attempts)
+		s.audit(ctx, 0, models.AuditActionCardBlock, card.ID,
+			fmt.Sprintf("%s by %s: %s", models.CardStatusBlocked, models.CardBlockedBySystem, models.CardBlockReasonCVVAttempts))
+		return models.ErrCardCVVBlocked
+	}
+	if attempts >= models.MaxCVVAttempts {
+		return models.ErrCardCVVBlocked
 	}
-	
-	// Добавление контрольной цифры
-	checkDigit := (10 - (sum % 10)) % 10
-	digits = append(digits, checkDigit)
-	
-	// Преобразование в строку
-	result := ""
-	for _, d := range digits {
-		result += strconv.Itoa(d)
+
+	return models.ErrInvalidCVV
+}
+
+// checkCard проверяет все условия authorize, кроме CVV
+func (s *CardService) checkCard(card *models.Card, merchantAccountID int64) error {
+	switch {
+	case card.Status == models.CardStatusExpired, isCardExpired(card, time.Now()):
+		return models.ErrCardExpired
+	case card.Status != models.CardStatusActive:
+		return models.ErrCardNotActive
+	}
+	if !card.AcceptsMerchant(merchantAccountID) {
+		return models.ErrMerchantNotAllowed
+	}
+	if !s.verifyHMAC(card) {
+		return models.ErrCardTampered
 	}
-	
-	return result
+	return nil
 }
 
-func (s *CardService) VerifyCard(cardID int64, cvv string) error {
-	card, err := s.cardRepo.GetByID(cardID)
+// debit проверяет лимиты и остаток, списывает сумму со счета карты и записывает операцию.
+// Если передан счет продавца, сумма зачисляется на него.
+func (s *CardService) debit(
+	ctx context.Context,
+	tx *sql.Tx,
+	card *models.Card,
+	account, merchantAccount *models.Account,
+	amount float64,
+	merchantName, merchantMCC, category string,
+) (*models.Transaction, error) {
+	if err := s.checkFunds(ctx, tx, card, account, amount); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	account.Balance -= amount
+	account.UpdatedAt = sql.NullTime{Time: now, Valid: true}
+	if err := s.accountRepo.Update(ctx, tx, account); err != nil {
+		return nil, fmt.Errorf("failed to update account: %v", err)
+	}
+
+	merchantName = strings.TrimSpace(merchantName)
+	transaction := &models.Transaction{
+		AccountID:    account.ID,
+		Type:         models.TransactionTypePayment,
+		Amount:       amount,
+		Status:       models.TransactionStatusCompleted,
+		Category:     sql.NullString{String: category, Valid: category != ""},
+		Description:  sql.NullString{String: fmt.Sprintf("Оплата картой %d: %s", card.ID, merchantName), Valid: true},
+		Counterparty: sql.NullString{String: merchantName, Valid: true},
+		CardID:       sql.NullInt64{Int64: card.ID, Valid: true},
+		MerchantMCC:  sql.NullString{String: merchantMCC, Valid: merchantMCC != ""},
+		CreatedAt:    sql.NullTime{Time: now, Valid: true},
+	}
+
+	if merchantAccount != nil {
+		merchantAccount.Balance += amount
+		merchantAccount.UpdatedAt = sql.NullTime{Time: now, Valid: true}
+		if err := s.accountRepo.Update(ctx, tx, merchantAccount); err != nil {
+			return nil, fmt.Errorf("failed to update merchant account: %v", err)
+		}
+		transaction.ToAccountID = sql.NullInt64{Int64: merchantAccount.ID, Valid: true}
+	}
+
+	if _, err := s.transactionRepo.Create(ctx, tx, transaction); err != nil {
+		return nil, fmt.Errorf("failed to create transaction: %v", err)
+	}
+
+	if card.Type == models.CardTypeSingleUse {
+		if err := s.destroySingleUseCard(ctx, tx, card); err != nil {
+			return nil, err
+		}
+	}
+
+	return transaction, nil
+}
+
+// destroySingleUseCard блокирует одноразовую карту навсегда в транзакции первого платежа.
+// Обновление статуса блокирует строку карты, поэтому из параллельных платежей пройдет только один.
+func (s *CardService) destroySingleUseCard(ctx context.Context, tx *sql.Tx, card *models.Card) error {
+	card.Status = models.CardStatusPermBlocked
+	card.BlockReason = sql.NullString{String: models.CardBlockReasonUsed, Valid: true}
+	card.BlockedBy = sql.NullString{String: models.CardBlockedBySystem, Valid: true}
+
+	updated, err := s.cardRepo.UpdateStatus(ctx, tx, card, models.CardStatusActive)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to update card status: %v", err)
 	}
+	if !updated {
+		return models.ErrCardNotActive
+	}
+	return nil
+}
 
-	if !card.VerifyCVV(cvv) {
-		return errors.New("неверный CVV")
+func (s *CardService) auditSingleUse(ctx context.Context, card *models.Card) {
+	if card.Type == models.CardTypeSingleUse {
+		s.audit(ctx, 0, models.AuditActionCardBlock, card.ID,
+			fmt.Sprintf("%s by %s: %s", models.CardStatusPermBlocked, models.CardBlockedBySystem, models.CardBlockReasonUsed))
+	}
+}
+
+// checkFunds проверяет лимиты банка, лимит виртуальной карты и доступный остаток счета
+// с учетом заблокированных по авторизациям сумм
+func (s *CardService) checkFunds(ctx context.Context, tx *sql.Tx, card *models.Card, account *models.Account, amount float64) error {
+	if !account.IsActive {
+		return models.ErrAccountNotFound
+	}
+	if s.limits.PerPayment > 0 && amount > s.limits.PerPayment {
+		return models.ErrCardLimitExceeded
+	}
+
+	if s.limits.Daily > 0 {
+		spent, err := s.cardSpentSince(ctx, tx, card.ID, truncateToDay(time.Now()))
+		if err != nil {
+			return err
+		}
+		if spent+amount > s.limits.Daily {
+			return models.ErrCardLimitExceeded
+		}
+	}
+
+	// Лимит виртуальной карты действует на весь срок ее действия
+	if card.SpendCap.Valid {
+		spent, err := s.cardSpentSince(ctx, tx, card.ID, card.CreatedAt)
+		if err != nil {
+			return err
+		}
+		if spent+amount > card.SpendCap.Float64 {
+			return models.ErrCardLimitExceeded
+		}
+	}
+
+	held, err := s.cardRepo.GetHeldAmount(ctx, tx, account.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get held amount: %v", err)
+	}
+	if account.Balance-held < amount {
+		return models.ErrInsufficientFunds
 	}
 
 	return nil
-} 
\ No newline at end of file
+}
+
+// cardSpentSince сумма списаний по карте с from вместе с еще не списанными блокировками
+func (s *CardService) cardSpentSince(ctx context.Context, tx *sql.Tx, cardID int64, from time.Time) (float64, error) {
+	spent, err := s.transactionRepo.GetCardSpentSince(ctx, tx, cardID, from)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get card spending: %v", err)
+	}
+
+	held, err := s.cardRepo.GetCardHeldSince(ctx, tx, cardID, from)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get card holds: %v", err)
+	}
+
+	return spent + held, nil
+}
+
+func (s *CardService) lockAccounts(ctx context.Context, tx *sql.Tx, cardAccountID, merchantAccountID int64) (*models.Account, *models.Account, error) {
+	ids := []int64{cardAccountID, merchantAccountID}
+	if ids[0] > ids[1] {
+		ids[0], ids[1] = ids[1], ids[0]
+	}
+
+	locked := make(map[int64]*models.Account, 2)
+	for _, id := range ids {
+		if _, ok := locked[id]; ok {
+			continue
+		}
+		account, err := s.accountRepo.GetByIDForUpdate(ctx, tx, id)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get account: %v", err)
+		}
+		if account == nil {
+			return nil, nil, models.ErrAccountNotFound
+		}
+		locked[id] = account
+	}
+
+	return locked[cardAccountID], locked[merchantAccountID], nil
+}
+
+// findByCardData ищет карту по номеру и сроку действия через ключевой хеш номера.
+// Карты, выпущенные до появления pan_hash, ищутся перебором с расшифровкой кандидатов с тем же сроком.
+func (s *CardService) findByCardData(ctx context.Context, cardNumber, expiryDate string) (*models.Card, error) {
+	expiry, err := time.Parse("01/06", expiryDate)
+	if err != nil {
+		return nil, models.ErrInvalidExpiryDate
+	}
+
+	card, err := s.cardRepo.GetByPANHash(ctx, s.panHasher.Hash(cardNumber))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get card: %v", err)
+	}
+	// Карты без pan_hash не находятся: хеши старых карт дозаполняет cmd/backfillpan
+	if card == nil || card.ExpiryDate.Year() != expiry.Year() || card.ExpiryDate.Month() != expiry.Month() {
+		return nil, models.ErrCardNotFound
+	}
+	return card, nil
+}
+
+func (s *CardService) verifyHMAC(card *models.Card) bool {
+	return s.hmacKeys.Verify(card.EncryptedData, card.HMAC, card.HMACVersion)
+}
+
+// cardExpiry возвращает последний день месяца окончания срока действия карты
+func cardExpiry(issuedAt time.Time) time.Time {
+	return time.Date(issuedAt.Year()+cardValidityYears, issuedAt.Month()+1, 0, 0, 0, 0, 0, time.UTC)
+}
+
+// isCardExpired обычная карта действует до конца месяца, указанного в сроке действия,
+// виртуальная — до конца дня окончания срока
+func isCardExpired(card *models.Card, now time.Time) bool {
+	if card.ExpiryDate.IsZero() {
+		return false
+	}
+	if card.IsVirtual() {
+		return !now.Before(truncateToDay(card.ExpiryDate).AddDate(0, 0, 1))
+	}
+	return now.After(time.Date(card.ExpiryDate.Year(), card.ExpiryDate.Month()+1, 1, 0, 0, 0, 0, time.UTC))
+}
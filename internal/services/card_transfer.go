@@ -0,0 +1,98 @@
+package services
+
+import (
+	"banksystem/internal/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// FindRecipient находит карту банка по номеру через ключевой хеш и возвращает маскированные данные
+// получателя, чтобы отправитель мог проверить их перед переводом
+func (s *CardService) FindRecipient(ctx context.Context, cardNumber string) (*models.CardRecipientResponse, error) {
+	_, recipient, err := s.recipientCard(ctx, cardNumber)
+	if err != nil {
+		return nil, err
+	}
+	return recipient, nil
+}
+
+// TransferToCard переводит деньги со счета карты владельца на счет карты получателя по ее номеру.
+// Перевод проводится как перевод между счетами (AccountService.TransferFunds) с комиссией из лимитов карт.
+// В транзакции перевода проверяются ограничения и лимиты карты, а остаток — за вычетом блокировок терминалов.
+func (s *CardService) TransferToCard(ctx context.Context, userID, cardID int64, req *models.CardTransferRequest) (*models.CardTransfer, error) {
+	card, err := s.GetCard(ctx, userID, cardID)
+	if err != nil {
+		return nil, err
+	}
+	if card.IsVirtual() {
+		return nil, models.ErrVirtualCard
+	}
+	if err := s.checkCard(card, 0); err != nil {
+		return nil, err
+	}
+
+	recipientCard, recipient, err := s.recipientCard(ctx, req.ToCardNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction, fee, err := s.accountService.TransferFunds(ctx, &TransferParams{
+		FromAccountID: card.AccountID,
+		ToAccountID:   recipientCard.AccountID,
+		UserID:        userID,
+		Amount:        req.Amount,
+		Category:      req.Category,
+		Description:   fmt.Sprintf("Перевод с карты %s на карту %s", card.Last4.String, recipientCard.Last4.String),
+		CardID:        card.ID,
+		Fee:           PercentTransferFee(s.limits.TransferFeePercent, s.limits.TransferFeeMin),
+		Check: func(ctx context.Context, tx *sql.Tx, from *models.Account, amount, fee float64) error {
+			if err := s.checkControls(ctx, tx, card, amount, "", "", ""); err != nil {
+				return err
+			}
+			// С карты списывается сумма вместе с комиссией
+			return s.checkFunds(ctx, tx, card, from, amount+fee)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.CardTransfer{Transaction: transaction, Fee: fee, Recipient: *recipient}, nil
+}
+
+// recipientCard ищет действующую карту получателя. Номер не расшифровывается: поиск идет по pan_hash.
+func (s *CardService) recipientCard(ctx context.Context, cardNumber string) (*models.Card, *models.CardRecipientResponse, error) {
+	card, err := s.cardRepo.GetByPANHash(ctx, s.panHasher.Hash(cardNumber))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get card: %v", err)
+	}
+	if card == nil {
+		return nil, nil, models.ErrCardNotFound
+	}
+	switch {
+	case card.Status == models.CardStatusExpired, isCardExpired(card, time.Now()):
+		return nil, nil, models.ErrCardExpired
+	case card.Status != models.CardStatusActive:
+		return nil, nil, models.ErrCardNotActive
+	}
+
+	account, err := s.accountRepo.GetByID(ctx, card.AccountID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get account: %v", err)
+	}
+	if account == nil || !account.IsActive {
+		return nil, nil, models.ErrCardNotFound
+	}
+
+	user, err := s.userRepo.GetByID(ctx, account.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get user: %v", err)
+	}
+
+	return card, &models.CardRecipientResponse{
+		CardNumber: models.MaskCardNumber(card.Last4.String),
+		Name:       models.MaskName(user.Username),
+	}, nil
+}
@@ -1,59 +1,303 @@
 package services
 
 import (
+	"banksystem/internal/models"
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/beevik/etree"
 )
 
 const (
-	centralBankURL = "https://www.cbr.ru/DailyInfoWebServ/DailyInfo.asmx"
+	// Путь SOAP-сервиса DailyInfo относительно базового адреса ЦБ
+	dailyInfoPath = "/DailyInfoWebServ/DailyInfo.asmx"
+	// Таймаут одного запроса к ЦБ и число попыток; пауза перед повтором растет с каждой попыткой
+	centralBankTimeout    = 10 * time.Second
+	centralBankAttempts   = 3
+	centralBankRetryDelay = time.Second
+	// За сколько дней запрашивается текущая ставка: ЦБ публикует ее только за рабочие дни
+	keyRateLookbackDays = 14
+	// Максимальный разрыв между датами в истории ставки (праздники), при котором история считается полной
+	keyRateMaxGapDays = 10
+	// Неполная история за период, уже запрошенный у ЦБ, повторно запрашивается не раньше чем через ttl,
+	// а после ошибки ЦБ — через keyRateHistoryRetryAfter. Запоминается не больше keyRateHistoryFetchLimit периодов.
+	keyRateHistoryRetryAfter = time.Minute
+	keyRateHistoryFetchLimit = 1000
 )
 
+// errNoKeyRates ЦБ ответил без ставок: за период ставка не публиковалась
+var errNoKeyRates = errors.New("данные по ставке не найдены")
+
+// KeyRateStore история ключевой ставки; реализуется repositories.KeyRateRepository
+type KeyRateStore interface {
+	Save(ctx context.Context, rates []*models.KeyRate) error
+	GetRange(ctx context.Context, from, to time.Time) ([]*models.KeyRate, error)
+	GetLatest(ctx context.Context) (*models.KeyRate, error)
+}
+
 type CentralBankService struct {
-	client *http.Client
+	client      *http.Client
+	keyRateRepo KeyRateStore
+	// Пауза перед повторным запросом к ЦБ; перед каждой следующей попыткой растет
+	retryDelay time.Duration
+	// Базовый адрес ЦБ, например https://www.cbr.ru или адрес заглушки cmd/cbrstub
+	baseURL string
+	// Сколько полученная ставка отдается из кеша без запроса к ЦБ
+	ttl time.Duration
+	// Сколько после получения можно использовать последнюю ставку, если ЦБ недоступен
+	maxAge time.Duration
+
+	mu       sync.Mutex
+	cached   *models.KeyRate
+	inflight *keyRateCall
+
+	historyMu      sync.Mutex
+	historyFetches map[string]keyRateHistoryFetch
+}
+
+// keyRateHistoryFetch последний запрос истории за период к ЦБ
+type keyRateHistoryFetch struct {
+	at     time.Time
+	failed bool
+}
+
+// keyRateCall запрос текущей ставки к ЦБ, результат которого получают все вызовы GetKeyRate, пришедшие во время запроса
+type keyRateCall struct {
+	done chan struct{}
+	rate *models.KeyRate
+	err  error
 }
 
-func NewCentralBankService() *CentralBankService {
+func NewCentralBankService(keyRateRepo KeyRateStore, baseURL string, ttl, maxAge time.Duration) *CentralBankService {
 	return &CentralBankService{
-		client: &http.Client{},
+		client:      &http.Client{Timeout: centralBankTimeout},
+		keyRateRepo: keyRateRepo,
+		retryDelay:  centralBankRetryDelay,
+		baseURL:     strings.TrimRight(baseURL, "/"),
+		ttl:         ttl,
+		maxAge:      maxAge,
+
+		historyFetches: make(map[string]keyRateHistoryFetch),
 	}
 }
 
-func (s *CentralBankService) GetKeyRate() (float64, error) {
-	resp, err := s.client.Get("http://www.cbr.ru/scripts/XML_daily.asp")
-	if err != nil {
-		return 0, err
+// GetKeyRate возвращает текущую ключевую ставку ЦБ. Полученная ставка кешируется на ttl и сохраняется в историю.
+// Если ЦБ недоступен, возвращается последнее полученное значение (из кеша или истории) не старше maxAge,
+// а без него — ErrKeyRateUnavailable.
+func (s *CentralBankService) GetKeyRate(ctx context.Context) (*models.KeyRate, error) {
+	s.mu.Lock()
+	if s.cached != nil && time.Since(s.cached.FetchedAt) < s.ttl {
+		rate := s.cached
+		s.mu.Unlock()
+		return rate, nil
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	// К ЦБ идет один запрос; он не отменяется вместе с ctx вызова, который его начал,
+	// а каждый вызов ждет результата не дольше своего ctx
+	call := s.inflight
+	if call == nil {
+		call = &keyRateCall{done: make(chan struct{})}
+		s.inflight = call
+		go s.refreshKeyRate(context.WithoutCancel(ctx), call)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-call.done:
+		return call.rate, call.err
+	}
+}
+
+// refreshKeyRate запрашивает текущую ставку у ЦБ, обновляет кеш и завершает call
+func (s *CentralBankService) refreshKeyRate(ctx context.Context, call *keyRateCall) {
+	now := time.Now()
+	rates, err := s.fetchKeyRates(ctx, now.AddDate(0, 0, -keyRateLookbackDays), now)
+	if err == nil {
+		if err := s.keyRateRepo.Save(ctx, rates); err != nil {
+			log.Printf("Error saving key rate history: %v", err)
+		}
+		call.rate = rates[len(rates)-1]
+	} else {
+		call.rate, call.err = s.staleKeyRate(ctx, err)
+	}
+
+	s.mu.Lock()
+	if err == nil {
+		s.cached = call.rate
+	}
+	s.inflight = nil
+	s.mu.Unlock()
+	close(call.done)
+}
+
+// staleKeyRate возвращает последнюю полученную ставку не старше maxAge, когда ЦБ недоступен
+func (s *CentralBankService) staleKeyRate(ctx context.Context, fetchErr error) (*models.KeyRate, error) {
+	s.mu.Lock()
+	stale := s.cached
+	s.mu.Unlock()
+
+	if stale == nil {
+		var err error
+		stale, err = s.keyRateRepo.GetLatest(ctx)
+		if err != nil {
+			log.Printf("Error getting saved key rate: %v", err)
+		}
+	}
+
+	if stale != nil && time.Since(stale.FetchedAt) <= s.maxAge {
+		log.Printf("Error getting key rate, using value fetched at %s: %v", stale.FetchedAt.Format(time.RFC3339), fetchErr)
+		return stale, nil
+	}
+
+	log.Printf("Error getting key rate: %v", fetchErr)
+	return nil, models.ErrKeyRateUnavailable
+}
+
+// GetKeyRateHistory возвращает ставки за даты с from по to. Если в истории нет части периода,
+// ставки за период запрашиваются у ЦБ и сохраняются. Период, уже запрошенный у ЦБ, не запрашивается
+// повторно до истечения ttl: ставки до первой опубликованной ЦБ не появятся, и история останется неполной.
+func (s *CentralBankService) GetKeyRateHistory(ctx context.Context, from, to time.Time) ([]*models.KeyRate, error) {
+	if to.Before(from) || to.Sub(from) > models.MaxKeyRatePeriodDays*24*time.Hour {
+		return nil, models.ErrKeyRatePeriod
+	}
+
+	rates, err := s.keyRateRepo.GetRange(ctx, from, to)
 	if err != nil {
-		return 0, err
+		return nil, fmt.Errorf("failed to get key rate history: %v", err)
+	}
+	if keyRateHistoryComplete(rates, from, to) {
+		return rates, nil
 	}
 
-	doc := etree.NewDocument()
-	if err := doc.ReadFromBytes(body); err != nil {
-		return 0, err
+	key := from.Format("2006-01-02") + "/" + to.Format("2006-01-02")
+	if last, ok := s.startHistoryFetch(key); !ok {
+		if last.failed && len(rates) == 0 {
+			return nil, models.ErrKeyRateUnavailable
+		}
+		return rates, nil
 	}
 
-	// Находим элемент с ключевой ставкой
-	keyRateElement := doc.FindElement("//ValCurs/Valute[@ID='R01235']/Value")
-	if keyRateElement == nil {
-		return 0, nil
+	fetched, err := s.fetchKeyRates(ctx, from, to)
+	s.finishHistoryFetch(key, err != nil && !errors.Is(err, errNoKeyRates))
+	switch {
+	case errors.Is(err, errNoKeyRates):
+		return rates, nil
+	case err != nil:
+		log.Printf("Error getting key rate history: %v", err)
+		if len(rates) > 0 {
+			return rates, nil
+		}
+		return nil, models.ErrKeyRateUnavailable
+	}
+	if err := s.keyRateRepo.Save(ctx, fetched); err != nil {
+		log.Printf("Error saving key rate history: %v", err)
+	}
+
+	return fetched, nil
+}
+
+// startHistoryFetch решает, можно ли запросить у ЦБ историю за период key. Если нельзя,
+// возвращает последний запрос за этот период (или пустой, когда превышен лимит запомненных периодов).
+func (s *CentralBankService) startHistoryFetch(key string) (keyRateHistoryFetch, bool) {
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+
+	now := time.Now()
+	if last, ok := s.historyFetches[key]; ok && now.Sub(last.at) < s.historyRetryAfter(last) {
+		return last, false
+	}
+
+	if len(s.historyFetches) >= keyRateHistoryFetchLimit {
+		for k, fetch := range s.historyFetches {
+			if now.Sub(fetch.at) >= s.historyRetryAfter(fetch) {
+				delete(s.historyFetches, k)
+			}
+		}
+		if len(s.historyFetches) >= keyRateHistoryFetchLimit {
+			return keyRateHistoryFetch{}, false
+		}
+	}
+
+	s.historyFetches[key] = keyRateHistoryFetch{at: now}
+	return keyRateHistoryFetch{}, true
+}
+
+func (s *CentralBankService) finishHistoryFetch(key string, failed bool) {
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+	s.historyFetches[key] = keyRateHistoryFetch{at: time.Now(), failed: failed}
+}
+
+func (s *CentralBankService) historyRetryAfter(fetch keyRateHistoryFetch) time.Duration {
+	if fetch.failed {
+		return keyRateHistoryRetryAfter
+	}
+	return s.ttl
+}
+
+// keyRateHistoryComplete проверяет, что сохраненные ставки покрывают период без разрывов длиннее праздников
+func keyRateHistoryComplete(rates []*models.KeyRate, from, to time.Time) bool {
+	if len(rates) == 0 {
+		return false
+	}
+	if today := time.Now(); to.After(today) {
+		to = today
+	}
+
+	maxGap := keyRateMaxGapDays * 24 * time.Hour
+	prev := from
+	for _, rate := range rates {
+		if rate.Date.Sub(prev) > maxGap {
+			return false
+		}
+		prev = rate.Date
+	}
+	return to.Sub(prev) <= maxGap
+}
+
+// fetchKeyRates запрашивает у ЦБ ставки за даты с from по to, повторяя запрос при ошибках
+func (s *CentralBankService) fetchKeyRates(ctx context.Context, from, to time.Time) ([]*models.KeyRate, error) {
+	soapRequest := s.buildSOAPRequest(from, to)
+
+	var err error
+	for attempt := 1; attempt <= centralBankAttempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(s.retryDelay * time.Duration(attempt-1)):
+			}
+		}
+
+		var body []byte
+		body, err = s.sendRequest(ctx, soapRequest)
+		if err == nil {
+			var rates []*models.KeyRate
+			rates, err = s.parseXMLResponse(body, time.Now())
+			// Пустой ответ — не сбой, повтор его не изменит
+			if err == nil || errors.Is(err, errNoKeyRates) {
+				return rates, err
+			}
+		}
+		log.Printf("Key rate request attempt %d of %d failed: %v", attempt, centralBankAttempts, err)
 	}
 
-	// Возвращаем значение ключевой ставки
-	return 7.5, nil // Заглушка, так как реальный API ЦБ РФ требует сертификат
+	return nil, err
 }
 
-func (s *CentralBankService) buildSOAPRequest() string {
-	fromDate := time.Now().AddDate(0, 0, -30).Format("2006-01-02")
-	toDate := time.Now().Format("2006-01-02")
+func (s *CentralBankService) buildSOAPRequest(from, to time.Time) string {
 	return fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
 		<soap12:Envelope xmlns:soap12="http://www.w3.org/2003/05/soap-envelope">
 			<soap12:Body>
@@ -62,13 +306,14 @@ func (s *CentralBankService) buildSOAPRequest() string {
 					<ToDate>%s</ToDate>
 				</KeyRate>
 			</soap12:Body>
-		</soap12:Envelope>`, fromDate, toDate)
+		</soap12:Envelope>`, from.Format("2006-01-02"), to.Format("2006-01-02"))
 }
 
-func (s *CentralBankService) sendRequest(soapRequest string) ([]byte, error) {
-	req, err := http.NewRequest(
+func (s *CentralBankService) sendRequest(ctx context.Context, soapRequest string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(
+		ctx,
 		"POST",
-		centralBankURL,
+		s.baseURL+dailyInfoPath,
 		bytes.NewBuffer([]byte(soapRequest)),
 	)
 	if err != nil {
@@ -91,28 +336,54 @@ func (s *CentralBankService) sendRequest(soapRequest string) ([]byte, error) {
 	return io.ReadAll(resp.Body)
 }
 
-func (s *CentralBankService) parseXMLResponse(rawBody []byte) (float64, error) {
+// parseXMLResponse разбирает ответ KeyRate: строки KR с датой DT и ставкой Rate.
+// Ставки возвращаются в порядке возрастания даты, fetchedAt — время получения ответа.
+func (s *CentralBankService) parseXMLResponse(rawBody []byte, fetchedAt time.Time) ([]*models.KeyRate, error) {
 	doc := etree.NewDocument()
 	if err := doc.ReadFromBytes(rawBody); err != nil {
-		return 0, err
+		return nil, err
+	}
+
+	if fault := doc.FindElement("//Envelope/Body/Fault"); fault != nil {
+		reason := fault.FindElement(".//Text")
+		if reason == nil {
+			return nil, fmt.Errorf("ошибка SOAP")
+		}
+		return nil, fmt.Errorf("ошибка SOAP: %s", strings.TrimSpace(reason.Text()))
 	}
 
 	krElements := doc.FindElements("//diffgram/KeyRate/KR")
 	if len(krElements) == 0 {
-		return 0, fmt.Errorf("данные по ставке не найдены")
+		return nil, errNoKeyRates
 	}
 
-	latestKR := krElements[0]
-	rateElement := latestKR.FindElement("./Rate")
-	if rateElement == nil {
-		return 0, fmt.Errorf("тег Rate отсутствует")
-	}
+	rates := make([]*models.KeyRate, 0, len(krElements))
+	for _, kr := range krElements {
+		dateElement := kr.FindElement("./DT")
+		rateElement := kr.FindElement("./Rate")
+		if dateElement == nil || rateElement == nil {
+			return nil, fmt.Errorf("тег DT или Rate отсутствует")
+		}
+
+		date, err := time.Parse(time.RFC3339, strings.TrimSpace(dateElement.Text()))
+		if err != nil {
+			return nil, fmt.Errorf("ошибка конвертации даты: %v", err)
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(rateElement.Text()), 64)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка конвертации ставки: %v", err)
+		}
 
-	rateStr := rateElement.Text()
-	var rate float64
-	if _, err := fmt.Sscanf(rateStr, "%f", &rate); err != nil {
-		return 0, fmt.Errorf("ошибка конвертации ставки: %v", err)
+		rates = append(rates, &models.KeyRate{
+			Date:      time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC),
+			Rate:      rate,
+			FetchedAt: fetchedAt,
+		})
 	}
 
-	return rate, nil
-} 
\ No newline at end of file
+	sort.Slice(rates, func(i, j int) bool {
+		return rates[i].Date.Before(rates[j].Date)
+	})
+
+	return rates, nil
+}
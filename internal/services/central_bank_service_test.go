@@ -0,0 +1,278 @@
+package services
+
+import (
+	"banksystem/internal/cbrstub"
+	"banksystem/internal/models"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testKeyRates ставки заглушки: 16% до 29.07.2024, затем 18%
+const testKeyRates = `{"key_rate": [{"date": "2023-12-18", "rate": 16.00}, {"date": "2024-07-29", "rate": 18.00}]}`
+
+// memKeyRateStore история ставок в памяти вместо key_rates
+type memKeyRateStore struct {
+	mu    sync.Mutex
+	rates map[time.Time]*models.KeyRate
+}
+
+func newMemKeyRateStore() *memKeyRateStore {
+	return &memKeyRateStore{rates: make(map[time.Time]*models.KeyRate)}
+}
+
+func (m *memKeyRateStore) Save(ctx context.Context, rates []*models.KeyRate) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, rate := range rates {
+		m.rates[rate.Date] = rate
+	}
+	return nil
+}
+
+func (m *memKeyRateStore) GetRange(ctx context.Context, from, to time.Time) ([]*models.KeyRate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var rates []*models.KeyRate
+	for date, rate := range m.rates {
+		if !date.Before(from) && !date.After(to) {
+			rates = append(rates, rate)
+		}
+	}
+	return rates, nil
+}
+
+func (m *memKeyRateStore) GetLatest(ctx context.Context) (*models.KeyRate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var latest *models.KeyRate
+	for _, rate := range m.rates {
+		if latest == nil || rate.Date.After(latest.Date) {
+			latest = rate
+		}
+	}
+	return latest, nil
+}
+
+// newTestCentralBank запускает заглушку ЦБ и сервис, направленный на нее, с короткими таймаутами
+func newTestCentralBank(t *testing.T, ttl, maxAge time.Duration) (*CentralBankService, *cbrstub.Server, *memKeyRateStore) {
+	t.Helper()
+
+	rates, err := cbrstub.ParseRates([]byte(testKeyRates))
+	if err != nil {
+		t.Fatalf("ParseRates: %v", err)
+	}
+	stub, server := cbrstub.NewTestServer(rates)
+	t.Cleanup(server.Close)
+	stub.TimeoutDelay = 5 * time.Second
+
+	store := newMemKeyRateStore()
+	service := NewCentralBankService(store, server.URL, ttl, maxAge)
+	service.client.Timeout = 200 * time.Millisecond
+	service.retryDelay = 10 * time.Millisecond
+	return service, stub, store
+}
+
+func readCBRFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "cbr", name))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	return data
+}
+
+func TestParseXMLResponse(t *testing.T) {
+	service := &CentralBankService{}
+	fetchedAt := time.Date(2024, 8, 2, 12, 0, 0, 0, time.UTC)
+
+	rates, err := service.parseXMLResponse(readCBRFixture(t, "key_rate.xml"), fetchedAt)
+	if err != nil {
+		t.Fatalf("parse key_rate.xml: %v", err)
+	}
+	if len(rates) != 10 {
+		t.Fatalf("got %d rates, want 10", len(rates))
+	}
+	first, last := rates[0], rates[len(rates)-1]
+	if want := time.Date(2024, 7, 22, 0, 0, 0, 0, time.UTC); !first.Date.Equal(want) || first.Rate != 16 {
+		t.Errorf("first rate = %s %.2f, want 2024-07-22 16.00", first.Date, first.Rate)
+	}
+	if want := time.Date(2024, 8, 2, 0, 0, 0, 0, time.UTC); !last.Date.Equal(want) || last.Rate != 18 {
+		t.Errorf("last rate = %s %.2f, want 2024-08-02 18.00", last.Date, last.Rate)
+	}
+	for i, rate := range rates {
+		if rate.Date.Location() != time.UTC {
+			t.Errorf("rate %d date %s is not UTC", i, rate.Date)
+		}
+		if !rate.FetchedAt.Equal(fetchedAt) {
+			t.Errorf("rate %d fetched at %s, want %s", i, rate.FetchedAt, fetchedAt)
+		}
+		if i > 0 && !rates[i-1].Date.Before(rate.Date) {
+			t.Errorf("rates are not sorted by date at %d", i)
+		}
+	}
+
+	tests := []struct {
+		fixture string
+		check   func(error) bool
+	}{
+		{"key_rate_empty.xml", func(err error) bool { return errors.Is(err, errNoKeyRates) }},
+		{"key_rate_fault.xml", func(err error) bool {
+			return err != nil && strings.Contains(err.Error(), "Server was unable to process request")
+		}},
+		{"key_rate_malformed.xml", func(err error) bool { return err != nil && !errors.Is(err, errNoKeyRates) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			rates, err := service.parseXMLResponse(readCBRFixture(t, tt.fixture), fetchedAt)
+			if rates != nil || !tt.check(err) {
+				t.Errorf("got %d rates, err %v", len(rates), err)
+			}
+		})
+	}
+}
+
+func TestGetKeyRateCachesForTTL(t *testing.T) {
+	service, stub, store := newTestCentralBank(t, time.Hour, 24*time.Hour)
+	ctx := context.Background()
+
+	rate, err := service.GetKeyRate(ctx)
+	if err != nil {
+		t.Fatalf("GetKeyRate: %v", err)
+	}
+	if rate.Rate != 18 {
+		t.Errorf("rate = %.2f, want 18.00", rate.Rate)
+	}
+	if latest, _ := store.GetLatest(ctx); latest == nil || !latest.Date.Equal(rate.Date) {
+		t.Errorf("latest saved rate = %v, want %s", latest, rate.Date)
+	}
+
+	cached, err := service.GetKeyRate(ctx)
+	if err != nil {
+		t.Fatalf("GetKeyRate from cache: %v", err)
+	}
+	if cached != rate {
+		t.Errorf("second call returned a different value")
+	}
+	if requests := stub.Requests(cbrstub.EndpointKeyRate); requests != 1 {
+		t.Errorf("CBR requests = %d, want 1", requests)
+	}
+}
+
+func TestGetKeyRateFallsBackToStaleValue(t *testing.T) {
+	// ttl 0: каждый вызов идет в ЦБ
+	service, stub, _ := newTestCentralBank(t, 0, time.Hour)
+	ctx := context.Background()
+
+	rate, err := service.GetKeyRate(ctx)
+	if err != nil {
+		t.Fatalf("GetKeyRate: %v", err)
+	}
+
+	stub.Script(cbrstub.EndpointKeyRate, cbrstub.FailureServerError, cbrstub.FailureServerError, cbrstub.FailureServerError)
+	stale, err := service.GetKeyRate(ctx)
+	if err != nil {
+		t.Fatalf("GetKeyRate with CBR down: %v", err)
+	}
+	if stale != rate {
+		t.Errorf("got %v, want cached value %v", stale, rate)
+	}
+
+	// Значение старше maxAge не используется
+	service.maxAge = 0
+	stub.Script(cbrstub.EndpointKeyRate, cbrstub.FailureServerError, cbrstub.FailureServerError, cbrstub.FailureServerError)
+	if _, err := service.GetKeyRate(ctx); !errors.Is(err, models.ErrKeyRateUnavailable) {
+		t.Errorf("err = %v, want ErrKeyRateUnavailable", err)
+	}
+}
+
+func TestGetKeyRateFallsBackToSavedHistory(t *testing.T) {
+	service, stub, store := newTestCentralBank(t, time.Hour, time.Hour)
+	ctx := context.Background()
+
+	saved := &models.KeyRate{Date: time.Date(2024, 8, 2, 0, 0, 0, 0, time.UTC), Rate: 18, FetchedAt: time.Now().Add(-30 * time.Minute)}
+	store.Save(ctx, []*models.KeyRate{saved})
+
+	stub.Script(cbrstub.EndpointKeyRate, cbrstub.FailureServerError, cbrstub.FailureServerError, cbrstub.FailureServerError)
+	rate, err := service.GetKeyRate(ctx)
+	if err != nil {
+		t.Fatalf("GetKeyRate with CBR down: %v", err)
+	}
+	if rate != saved {
+		t.Errorf("got %v, want saved value %v", rate, saved)
+	}
+}
+
+func TestGetKeyRateHonoursCallerContext(t *testing.T) {
+	service, stub, _ := newTestCentralBank(t, time.Hour, time.Hour)
+	stub.Script(cbrstub.EndpointKeyRate, cbrstub.FailureTimeout, cbrstub.FailureTimeout, cbrstub.FailureTimeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := service.GetKeyRate(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("GetKeyRate returned after %s, caller deadline was 50ms", elapsed)
+	}
+}
+
+func TestGetKeyRateHistoryBeforeFirstRate(t *testing.T) {
+	service, stub, _ := newTestCentralBank(t, time.Hour, time.Hour)
+	ctx := context.Background()
+	from := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		rates, err := service.GetKeyRateHistory(ctx, from, to)
+		if err != nil {
+			t.Fatalf("GetKeyRateHistory: %v", err)
+		}
+		if len(rates) != 0 {
+			t.Errorf("got %d rates before the first published rate", len(rates))
+		}
+	}
+	if requests := stub.Requests(cbrstub.EndpointKeyRate); requests != 1 {
+		t.Errorf("CBR requests = %d, want 1", requests)
+	}
+}
+
+func TestGetKeyRateRetriesScriptedFailures(t *testing.T) {
+	service, stub, _ := newTestCentralBank(t, time.Hour, time.Hour)
+	stub.Script(cbrstub.EndpointKeyRate, cbrstub.FailureTimeout, cbrstub.FailureServerError, cbrstub.FailureNone)
+
+	rate, err := service.GetKeyRate(context.Background())
+	if err != nil {
+		t.Fatalf("GetKeyRate: %v", err)
+	}
+	if rate.Rate != 18 {
+		t.Errorf("rate = %.2f, want 18.00", rate.Rate)
+	}
+	if requests := stub.Requests(cbrstub.EndpointKeyRate); requests != 3 {
+		t.Errorf("CBR requests = %d, want 3", requests)
+	}
+}
+
+func TestGetKeyRateUnavailableAfterExhaustedScript(t *testing.T) {
+	service, stub, _ := newTestCentralBank(t, time.Hour, time.Hour)
+	stub.Script(cbrstub.EndpointKeyRate, cbrstub.FailureTimeout, cbrstub.FailureServerError, cbrstub.FailureMalformed)
+
+	if _, err := service.GetKeyRate(context.Background()); !errors.Is(err, models.ErrKeyRateUnavailable) {
+		t.Errorf("err = %v, want ErrKeyRateUnavailable", err)
+	}
+	if requests := stub.Requests(cbrstub.EndpointKeyRate); requests != centralBankAttempts {
+		t.Errorf("CBR requests = %d, want %d", requests, centralBankAttempts)
+	}
+
+	// После окончания сценария заглушка отвечает обычно
+	if _, err := service.GetKeyRate(context.Background()); err != nil {
+		t.Errorf("GetKeyRate after script: %v", err)
+	}
+}
@@ -0,0 +1,254 @@
+package services
+
+import (
+	"banksystem/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// SubmitApplication принимает заявку на кредит и сразу оценивает ее скорингом. Ставку назначает банк
+// от ключевой ставки ЦБ; без актуальной ключевой ставки заявки не принимаются.
+// Заявки с высоким баллом одобряются и выдаются автоматически, с низким — отклоняются,
+// остальные ждут решения сотрудника банка в статусе under_review.
+func (s *CreditService) SubmitApplication(ctx context.Context, userID int64, req *models.CreditApplicationRequest) (*models.CreditApplication, error) {
+	if s.policy.MaxAmount > 0 && req.Amount > s.policy.MaxAmount {
+		return nil, models.ErrCreditAmountLimit
+	}
+	if s.policy.MaxTermMonths > 0 && req.TermMonths > s.policy.MaxTermMonths {
+		return nil, models.ErrInvalidTerm
+	}
+
+	account, err := s.accountRepo.GetByID(ctx, req.AccountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account: %v", err)
+	}
+	if account == nil || !account.IsActive {
+		return nil, models.ErrAccountNotFound
+	}
+	if account.UserID != userID {
+		return nil, models.ErrAccessDenied
+	}
+
+	keyRate, err := s.centralBank.GetKeyRate(ctx)
+	if err != nil {
+		return nil, models.ErrKeyRateUnavailable
+	}
+
+	// До скоринга ставка считается с максимальной надбавкой за риск
+	application := &models.CreditApplication{
+		UserID:       userID,
+		AccountID:    account.ID,
+		Amount:       req.Amount,
+		TermMonths:   req.TermMonths,
+		ScheduleType: req.ScheduleType,
+		KeyRate:      sql.NullFloat64{Float64: keyRate.Rate, Valid: true},
+		InterestRate: sql.NullFloat64{Float64: s.creditRate(keyRate.Rate, req.TermMonths, sql.NullInt64{}), Valid: true},
+		Status:       models.ApplicationStatusSubmitted,
+		CreatedAt:    time.Now(),
+	}
+	if err := s.creditRepo.CreateApplication(ctx, application); err != nil {
+		return nil, fmt.Errorf("failed to create credit application: %v", err)
+	}
+
+	// Если скоринг не удался, заявка остается в submitted и рассматривается сотрудником банка
+	score, err := s.scoreApplication(ctx, application)
+	if err != nil {
+		log.Printf("Error scoring credit application %d: %v", application.ID, err)
+		return application, nil
+	}
+
+	switch {
+	case score.RejectReason != "":
+		return s.decide(ctx, application.ID, models.ApplicationStatusRejected, 0, score, score.RejectReason)
+	case score.Score < models.ScoreAutoReject:
+		return s.decide(ctx, application.ID, models.ApplicationStatusRejected, 0, score, "недостаточный скоринговый балл")
+	case score.Score >= models.ScoreAutoApprove:
+		return s.decide(ctx, application.ID, models.ApplicationStatusApproved, 0, score, "")
+	default:
+		return s.decide(ctx, application.ID, models.ApplicationStatusUnderReview, 0, score, "")
+	}
+}
+
+// GetApplications возвращает заявки пользователя
+func (s *CreditService) GetApplications(ctx context.Context, userID int64) ([]*models.CreditApplication, error) {
+	applications, err := s.creditRepo.GetApplicationsByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credit applications: %v", err)
+	}
+	return applications, nil
+}
+
+// GetApplication возвращает заявку пользователя по ID
+func (s *CreditService) GetApplication(ctx context.Context, userID, applicationID int64) (*models.CreditApplication, error) {
+	application, err := s.creditRepo.GetApplication(ctx, applicationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credit application: %v", err)
+	}
+	if application == nil || application.UserID != userID {
+		return nil, models.ErrApplicationNotFound
+	}
+	return application, nil
+}
+
+// GetApplicationsByStatus возвращает заявки в статусе status для рассмотрения сотрудником банка
+func (s *CreditService) GetApplicationsByStatus(ctx context.Context, status string) ([]*models.CreditApplication, error) {
+	applications, err := s.creditRepo.GetApplicationsByStatus(ctx, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credit applications: %v", err)
+	}
+	return applications, nil
+}
+
+// ApproveApplication одобряет заявку от имени сотрудника банка adminID и выдает кредит.
+// Повторный вызов для одобренной, но не выданной заявки повторяет выдачу.
+func (s *CreditService) ApproveApplication(ctx context.Context, adminID, applicationID int64, req *models.CreditDecisionRequest) (*models.CreditApplication, error) {
+	return s.decide(ctx, applicationID, models.ApplicationStatusApproved, adminID, nil, req.Reason)
+}
+
+// RejectApplication отклоняет заявку от имени сотрудника банка adminID
+func (s *CreditService) RejectApplication(ctx context.Context, adminID, applicationID int64, req *models.CreditDecisionRequest) (*models.CreditApplication, error) {
+	return s.decide(ctx, applicationID, models.ApplicationStatusRejected, adminID, nil, req.Reason)
+}
+
+// decide переводит заявку в статус status; reviewerID 0 означает решение скоринга.
+// Одобренная заявка сразу передается на выдачу, о решении заемщику отправляется письмо.
+func (s *CreditService) decide(ctx context.Context, applicationID int64, status string, reviewerID int64, score *models.CreditScore, reason string) (*models.CreditApplication, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	application, err := s.creditRepo.GetApplicationForUpdate(ctx, tx, applicationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credit application: %v", err)
+	}
+	if application == nil {
+		return nil, models.ErrApplicationNotFound
+	}
+
+	// Одобренную ранее заявку, выдача по которой не удалась, можно выдать повторно
+	if application.Status == models.ApplicationStatusApproved && status == models.ApplicationStatusApproved {
+		tx.Rollback()
+		return s.disburse(ctx, application.ID)
+	}
+	if !models.CanChangeApplicationStatus(application.Status, status) {
+		return nil, models.ErrApplicationStatus
+	}
+
+	fromStatus := application.Status
+	application.Status = status
+	if score != nil {
+		application.Score = sql.NullInt64{Int64: int64(score.Score), Valid: true}
+		application.ScoreDetails = sql.NullString{String: score.Details(), Valid: true}
+		if application.KeyRate.Valid {
+			application.InterestRate = sql.NullFloat64{Float64: s.creditRate(application.KeyRate.Float64, application.TermMonths, application.Score), Valid: true}
+		}
+	}
+	if reason != "" {
+		application.DecisionReason = sql.NullString{String: reason, Valid: true}
+	}
+	if reviewerID != 0 {
+		application.ReviewedBy = sql.NullInt64{Int64: reviewerID, Valid: true}
+	}
+	if status != models.ApplicationStatusUnderReview {
+		application.DecidedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	}
+
+	updated, err := s.creditRepo.UpdateApplication(ctx, tx, application, fromStatus)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update credit application: %v", err)
+	}
+	if !updated {
+		return nil, models.ErrApplicationStatus
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	switch status {
+	case models.ApplicationStatusRejected:
+		s.sendDecisionNotification(ctx, application)
+	case models.ApplicationStatusApproved:
+		s.sendDecisionNotification(ctx, application)
+		return s.disburse(ctx, application.ID)
+	}
+
+	return application, nil
+}
+
+// disburse выдает кредит по одобренной заявке. Ставка пересчитывается по текущей ключевой ставке ЦБ.
+// Заявка и кредит сохраняются в одной транзакции, поэтому при ошибке, в том числе
+// при недоступной ключевой ставке, заявка остается одобренной и выдачу можно повторить.
+func (s *CreditService) disburse(ctx context.Context, applicationID int64) (*models.CreditApplication, error) {
+	keyRate, err := s.centralBank.GetKeyRate(ctx)
+	if err != nil {
+		return nil, models.ErrKeyRateUnavailable
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	application, err := s.creditRepo.GetApplicationForUpdate(ctx, tx, applicationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credit application: %v", err)
+	}
+	if application == nil {
+		return nil, models.ErrApplicationNotFound
+	}
+	if application.Status != models.ApplicationStatusApproved {
+		return nil, models.ErrApplicationStatus
+	}
+
+	application.KeyRate = sql.NullFloat64{Float64: keyRate.Rate, Valid: true}
+	application.InterestRate = sql.NullFloat64{Float64: s.creditRate(keyRate.Rate, application.TermMonths, application.Score), Valid: true}
+
+	credit, err := s.issueCredit(ctx, tx, application, keyRate)
+	if err != nil {
+		if errors.Is(err, models.ErrAccountNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to issue credit: %v", err)
+	}
+
+	application.Status = models.ApplicationStatusDisbursed
+	application.CreditID = sql.NullInt64{Int64: credit.ID, Valid: true}
+	updated, err := s.creditRepo.UpdateApplication(ctx, tx, application, models.ApplicationStatusApproved)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update credit application: %v", err)
+	}
+	if !updated {
+		return nil, models.ErrApplicationStatus
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return application, nil
+}
+
+func (s *CreditService) sendDecisionNotification(ctx context.Context, application *models.CreditApplication) {
+	user, err := s.userRepo.GetByID(ctx, application.UserID)
+	if err != nil || user == nil {
+		log.Printf("Error getting user %d for credit decision notification: %v", application.UserID, err)
+		return
+	}
+
+	if application.Status == models.ApplicationStatusApproved {
+		err = s.smtpService.SendCreditApprovalNotification(user.Email, application.Amount, application.TermMonths)
+	} else {
+		err = s.smtpService.SendCreditRejectionNotification(user.Email, application.Amount, application.TermMonths, application.DecisionReason.String)
+	}
+	if err != nil {
+		log.Printf("Error sending credit decision notification for application %d: %v", application.ID, err)
+	}
+}
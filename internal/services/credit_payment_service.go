@@ -5,14 +5,20 @@ import (
 	"banksystem/internal/repositories"
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"log"
 	"time"
 )
 
 type CreditPaymentService struct {
-	paymentRepo *repositories.CreditPaymentRepository
-	creditRepo  *repositories.CreditRepository
-	accountRepo *repositories.AccountRepository
-	db         *sql.DB
+	paymentRepo     *repositories.CreditPaymentRepository
+	creditRepo      *repositories.CreditRepository
+	accountRepo     *repositories.AccountRepository
+	transactionRepo *repositories.TransactionRepository
+	userRepo        *repositories.UserRepository
+	smtpService     *SMTPService
+	db              *sql.DB
 }
 
 func NewCreditPaymentService(
@@ -20,12 +26,18 @@ func NewCreditPaymentService(
 	paymentRepo *repositories.CreditPaymentRepository,
 	creditRepo *repositories.CreditRepository,
 	accountRepo *repositories.AccountRepository,
+	transactionRepo *repositories.TransactionRepository,
+	userRepo *repositories.UserRepository,
+	smtpService *SMTPService,
 ) *CreditPaymentService {
 	return &CreditPaymentService{
-		db:          db,
-		paymentRepo: paymentRepo,
-		creditRepo:  creditRepo,
-		accountRepo: accountRepo,
+		db:              db,
+		paymentRepo:     paymentRepo,
+		creditRepo:      creditRepo,
+		accountRepo:     accountRepo,
+		transactionRepo: transactionRepo,
+		userRepo:        userRepo,
+		smtpService:     smtpService,
 	}
 }
 
@@ -45,49 +57,221 @@ func (s *CreditPaymentService) CreatePayment(ctx context.Context, creditID int64
 	return payment, nil
 }
 
+// ProcessPayment списывает платеж по графику вместе с начисленным за него штрафом.
+// Если средств не хватает, а срок платежа наступил, платеж считается просроченным: за него один раз
+// начисляется штраф CreditPenaltyRate, кредит переводится в OVERDUE, а списание повторяется при каждом
+// запуске шедулера. Когда просроченных платежей не остается, кредит возвращается в ACTIVE,
+// а после последнего платежа закрывается.
 func (s *CreditPaymentService) ProcessPayment(ctx context.Context, paymentID int64) error {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to begin transaction: %v", err)
 	}
 	defer tx.Rollback()
 
-	payment, err := s.paymentRepo.GetByID(ctx, paymentID)
+	payment, err := s.paymentRepo.GetByIDForUpdate(ctx, tx, paymentID)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to get payment: %v", err)
 	}
-
-	if payment.Status != "pending" {
+	if payment == nil {
+		return models.ErrPaymentNotFound
+	}
+	if payment.Status == models.CreditPaymentStatusCompleted {
 		return nil
 	}
 
 	credit, err := s.creditRepo.GetByID(int(payment.CreditID))
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to get credit: %v", err)
 	}
 
-	account, err := s.accountRepo.GetByID(ctx, credit.AccountID)
+	account, err := s.accountRepo.GetByIDForUpdate(ctx, tx, credit.AccountID)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to get account: %v", err)
+	}
+	if account == nil {
+		return models.ErrAccountNotFound
 	}
 
-	if account.Balance < payment.Amount {
-		return s.paymentRepo.UpdateStatus(ctx, paymentID, "failed")
+	penalty, err := s.paymentRepo.GetPenaltyByPayment(ctx, tx, payment.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get penalty: %v", err)
+	}
+	if penalty != nil && penalty.Status == models.CreditPenaltyStatusPaid {
+		penalty = nil
+	}
+
+	due := payment.Amount
+	if penalty != nil {
+		due += penalty.Amount
+	}
+
+	now := time.Now()
+	if account.Balance < due {
+		if payment.DueDate.After(now) {
+			return models.ErrInsufficientFunds
+		}
+		return s.markOverdue(ctx, tx, credit, payment, penalty == nil)
 	}
 
 	// Списываем средства со счета
-	account.Balance -= payment.Amount
-	err = s.accountRepo.Update(ctx, tx, account)
-	if err != nil {
+	account.Balance -= due
+	if err := s.accountRepo.Update(ctx, tx, account); err != nil {
+		return fmt.Errorf("failed to update account: %v", err)
+	}
+
+	if _, err := s.createTransaction(ctx, tx, account.ID, "credit_payment", payment.Amount,
+		fmt.Sprintf("Платеж по кредиту №%d", credit.ID)); err != nil {
 		return err
 	}
 
-	err = s.paymentRepo.UpdateStatus(ctx, paymentID, "completed")
-	if err != nil {
+	if err := s.paymentRepo.UpdateStatusTx(ctx, tx, payment.ID, models.CreditPaymentStatusCompleted); err != nil {
+		return fmt.Errorf("failed to update payment status: %v", err)
+	}
+
+	// Штраф списывается отдельной операцией, чтобы его было видно в выписке
+	if penalty != nil {
+		transaction, err := s.createTransaction(ctx, tx, account.ID, "credit_penalty", penalty.Amount,
+			fmt.Sprintf("Штраф за просрочку платежа по кредиту №%d", credit.ID))
+		if err != nil {
+			return err
+		}
+		if err := s.paymentRepo.SetPenaltyPaid(ctx, tx, penalty.ID, transaction.ID, now); err != nil {
+			return fmt.Errorf("failed to update penalty: %v", err)
+		}
+	}
+
+	if err := s.updateCreditStatus(ctx, tx, credit); err != nil {
 		return err
 	}
 
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+// updateCreditStatus закрывает кредит после последнего платежа и возвращает в ACTIVE
+// просроченный кредит, по которому не осталось просроченных платежей
+func (s *CreditPaymentService) updateCreditStatus(ctx context.Context, tx *sql.Tx, credit *models.Credit) error {
+	unpaid, err := s.paymentRepo.CountUnpaid(ctx, tx, credit.ID)
+	if err != nil {
+		return fmt.Errorf("failed to count unpaid payments: %v", err)
+	}
+
+	status := credit.Status
+	switch {
+	case unpaid == 0:
+		status = models.CreditStatusClosed
+	case credit.Status == models.CreditStatusOverdue:
+		overdue, err := s.paymentRepo.CountOverdue(ctx, tx, credit.ID)
+		if err != nil {
+			return fmt.Errorf("failed to count overdue payments: %v", err)
+		}
+		if overdue == 0 {
+			status = models.CreditStatusActive
+		}
+	}
+
+	if status == credit.Status {
+		return nil
+	}
+	if err := s.creditRepo.UpdateStatusTx(ctx, tx, credit.ID, status); err != nil {
+		return fmt.Errorf("failed to update credit status: %v", err)
+	}
+	return nil
+}
+
+// markOverdue переводит платеж в просроченные, а кредит — в OVERDUE. Штраф начисляется,
+// только если за этот платеж его еще не было, и тогда же заемщику отправляется письмо.
+func (s *CreditPaymentService) markOverdue(ctx context.Context, tx *sql.Tx, credit *models.Credit, payment *models.CreditPayment, chargePenalty bool) error {
+	if payment.Status != models.CreditPaymentStatusFailed {
+		if err := s.paymentRepo.UpdateStatusTx(ctx, tx, payment.ID, models.CreditPaymentStatusFailed); err != nil {
+			return fmt.Errorf("failed to update payment status: %v", err)
+		}
+	}
+
+	var penalty *models.CreditPenalty
+	if chargePenalty {
+		penalty = &models.CreditPenalty{
+			CreditID:  credit.ID,
+			PaymentID: payment.ID,
+			Amount:    roundKopecks(payment.Amount * models.CreditPenaltyRate),
+			Status:    models.CreditPenaltyStatusPending,
+			CreatedAt: time.Now(),
+		}
+		created, err := s.paymentRepo.CreatePenalty(ctx, tx, penalty)
+		if err != nil {
+			return fmt.Errorf("failed to create penalty: %v", err)
+		}
+		if !created {
+			penalty = nil
+		}
+	}
+
+	if credit.Status == models.CreditStatusActive {
+		if err := s.creditRepo.UpdateStatusTx(ctx, tx, credit.ID, models.CreditStatusOverdue); err != nil {
+			return fmt.Errorf("failed to update credit status: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	if penalty != nil {
+		s.sendPenaltyNotification(ctx, credit, payment, penalty)
+	}
+
+	return models.ErrInsufficientFunds
+}
+
+func (s *CreditPaymentService) createTransaction(ctx context.Context, tx *sql.Tx, accountID int64, transactionType string, amount float64, description string) (*models.Transaction, error) {
+	transaction := &models.Transaction{
+		AccountID:   accountID,
+		Type:        transactionType,
+		Amount:      amount,
+		Status:      "completed",
+		Description: sql.NullString{String: description, Valid: true},
+		CreatedAt:   sql.NullTime{Time: time.Now(), Valid: true},
+	}
+
+	transaction, err := s.transactionRepo.Create(ctx, tx, transaction)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transaction: %v", err)
+	}
+
+	return transaction, nil
+}
+
+func (s *CreditPaymentService) sendPenaltyNotification(ctx context.Context, credit *models.Credit, payment *models.CreditPayment, penalty *models.CreditPenalty) {
+	user, err := s.userRepo.GetByID(ctx, credit.UserID)
+	if err != nil || user == nil {
+		log.Printf("Error getting user %d for credit penalty notification: %v", credit.UserID, err)
+		return
+	}
+
+	err = s.smtpService.SendCreditPenaltyNotification(user.Email, credit.ID, payment.Amount, penalty.Amount, payment.DueDate.Format("02.01.2006"))
+	if err != nil {
+		log.Printf("Error sending credit penalty notification for payment %d: %v", payment.ID, err)
+	}
+}
+
+// GetPenalties возвращает штрафы, начисленные по кредиту пользователя; чужой кредит не отличается от несуществующего
+func (s *CreditPaymentService) GetPenalties(ctx context.Context, userID, creditID int64) ([]*models.CreditPenalty, error) {
+	credit, err := s.creditRepo.GetByID(int(creditID))
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, models.ErrCreditNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credit: %v", err)
+	}
+	if credit.UserID != userID {
+		return nil, models.ErrCreditNotFound
+	}
+
+	return s.paymentRepo.GetPenaltiesByCreditID(ctx, creditID)
 }
 
 func (s *CreditPaymentService) GetPaymentsByCreditID(ctx context.Context, creditID int64) ([]*models.CreditPayment, error) {
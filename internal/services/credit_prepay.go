@@ -0,0 +1,198 @@
+package services
+
+import (
+	"banksystem/internal/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"time"
+)
+
+// Prepay досрочно погашает кредит заемщика userID. Сначала гасятся проценты, начисленные с начала
+// текущего периода, затем основной долг. Оставшийся график пересчитывается с сокращением срока
+// или уменьшением платежа; при нулевом остатке кредит закрывается. Новый график отправляется на почту.
+func (s *CreditService) Prepay(ctx context.Context, userID, creditID int64, req *models.CreditPrepayRequest) (*models.CreditPrepayment, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	credit, err := s.creditRepo.GetByIDForUpdate(ctx, tx, creditID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credit: %v", err)
+	}
+	if credit == nil || credit.UserID != userID {
+		return nil, models.ErrCreditNotFound
+	}
+	switch credit.Status {
+	case models.CreditStatusActive:
+	case models.CreditStatusOverdue:
+		return nil, models.ErrCreditPaymentDue
+	default:
+		return nil, models.ErrCreditClosed
+	}
+
+	pending, err := s.paymentRepo.GetUnpaidForUpdate(ctx, tx, credit.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credit payments: %v", err)
+	}
+	if len(pending) == 0 {
+		return nil, models.ErrCreditClosed
+	}
+
+	// Наступивший платеж сначала списывается шедулером, иначе проценты за него посчитаются дважды
+	now := time.Now()
+	next := pending[0]
+	if next.Status != models.CreditPaymentStatusPending || !next.DueDate.After(now) {
+		return nil, models.ErrCreditPaymentDue
+	}
+
+	var balance float64
+	for _, payment := range pending {
+		balance += payment.Principal
+	}
+	balance = roundKopecks(balance)
+
+	// Проценты начисляются пропорционально времени, прошедшему с начала текущего периода
+	periodStart := next.DueDate.AddDate(0, -1, 0)
+	if periodStart.Before(credit.CreatedAt) {
+		periodStart = credit.CreatedAt
+	}
+	period := next.DueDate.Sub(periodStart).Hours()
+	elapsed := math.Max(0, math.Min(now.Sub(periodStart).Hours(), period))
+	monthlyRate := credit.InterestRate / 12 / 100
+	accrued := roundKopecks(balance * monthlyRate * elapsed / period)
+
+	interestPaid := roundKopecks(math.Min(req.Amount, accrued))
+	principalPaid := roundKopecks(math.Min(req.Amount-interestPaid, balance))
+	amount := roundKopecks(interestPaid + principalPaid)
+	remaining := roundKopecks(balance - principalPaid)
+
+	account, err := s.accountRepo.GetByIDForUpdate(ctx, tx, credit.AccountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account: %v", err)
+	}
+	if account == nil {
+		return nil, models.ErrAccountNotFound
+	}
+	if account.Balance < amount {
+		return nil, models.ErrInsufficientFunds
+	}
+
+	account.Balance -= amount
+	if err := s.accountRepo.Update(ctx, tx, account); err != nil {
+		return nil, fmt.Errorf("failed to update account: %v", err)
+	}
+
+	transaction := &models.Transaction{
+		AccountID: account.ID,
+		Type:      "credit_prepayment",
+		Amount:    amount,
+		Status:    "completed",
+		Description: sql.NullString{
+			String: fmt.Sprintf("Досрочное погашение кредита №%d: проценты %.2f, основной долг %.2f", credit.ID, interestPaid, principalPaid),
+			Valid:  true,
+		},
+		CreatedAt: sql.NullTime{Time: now, Valid: true},
+	}
+	if _, err := s.transactionRepo.Create(ctx, tx, transaction); err != nil {
+		return nil, fmt.Errorf("failed to create transaction: %v", err)
+	}
+
+	var schedule []*models.CreditPayment
+	if remaining > 0 {
+		term := len(pending)
+		if req.Mode == models.PrepayModeShortenTerm {
+			term = shortenedTerm(credit.ScheduleType, remaining, credit.InterestRate, pending)
+		}
+
+		schedule = buildSchedule(credit.ScheduleType, remaining, credit.InterestRate, term, periodStart)
+		for i, payment := range schedule {
+			payment.CreditID = credit.ID
+			payment.DueDate = pending[i].DueDate
+		}
+
+		// В первый платеж входят непогашенные проценты на прежний остаток и проценты
+		// на новый остаток до конца периода
+		first := schedule[0]
+		first.Interest = roundKopecks(accrued - interestPaid + remaining*monthlyRate*(period-elapsed)/period)
+		first.Amount = roundKopecks(first.Principal + first.Interest)
+	}
+
+	if err := s.paymentRepo.DeletePending(ctx, tx, credit.ID); err != nil {
+		return nil, fmt.Errorf("failed to delete credit payments: %v", err)
+	}
+	if err := s.paymentRepo.CreateSchedule(ctx, tx, schedule); err != nil {
+		return nil, fmt.Errorf("failed to create payment schedule: %v", err)
+	}
+
+	if remaining == 0 {
+		credit.Status = models.CreditStatusClosed
+		if err := s.creditRepo.UpdateStatusTx(ctx, tx, credit.ID, credit.Status); err != nil {
+			return nil, fmt.Errorf("failed to update credit status: %v", err)
+		}
+	} else {
+		credit.MonthlyPayment = schedule[0].Amount
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	s.sendScheduleNotification(ctx, credit, schedule)
+
+	return &models.CreditPrepayment{
+		Credit:        credit,
+		InterestPaid:  interestPaid,
+		PrincipalPaid: principalPaid,
+		Schedule:      schedule,
+	}, nil
+}
+
+// shortenedTerm рассчитывает, за сколько платежей остаток balance погашается при прежнем
+// размере платежа (для дифференцированного графика — прежней доле основного долга)
+func shortenedTerm(scheduleType string, balance, rate float64, pending []*models.CreditPayment) int {
+	// Первый платеж мог быть изменен прошлым досрочным погашением, последний — округлением
+	regular := pending[0]
+	if len(pending) > 2 {
+		regular = pending[1]
+	}
+
+	monthlyRate := rate / 12 / 100
+	term := float64(len(pending))
+	switch {
+	case scheduleType == models.CreditScheduleDifferentiated:
+		if regular.Principal > 0 {
+			term = balance / regular.Principal
+		}
+	case monthlyRate == 0:
+		term = balance / regular.Amount
+	case balance*monthlyRate < regular.Amount:
+		term = -math.Log(1-balance*monthlyRate/regular.Amount) / math.Log(1+monthlyRate)
+	}
+
+	// Погрешность вычислений не должна добавлять лишний платеж
+	n := int(math.Ceil(term - 1e-9))
+	if n < 1 {
+		n = 1
+	}
+	if n > len(pending) {
+		n = len(pending)
+	}
+	return n
+}
+
+func (s *CreditService) sendScheduleNotification(ctx context.Context, credit *models.Credit, schedule []*models.CreditPayment) {
+	user, err := s.userRepo.GetByID(ctx, credit.UserID)
+	if err != nil || user == nil {
+		log.Printf("Error getting user %d for credit schedule notification: %v", credit.UserID, err)
+		return
+	}
+
+	if err := s.smtpService.SendCreditScheduleNotification(user.Email, credit.ID, schedule); err != nil {
+		log.Printf("Error sending credit schedule notification for credit %d: %v", credit.ID, err)
+	}
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"banksystem/internal/models"
+	"database/sql"
+)
+
+// creditRate рассчитывает годовую ставку по кредиту: ключевая ставка ЦБ keyRate, маржа банка
+// из политики кредитования, надбавка за срок и надбавка за риск по скоринговому баллу.
+// Без балла применяется максимальная надбавка за риск.
+func (s *CreditService) creditRate(keyRate float64, termMonths int, score sql.NullInt64) float64 {
+	return roundKopecks(keyRate + s.policy.Margin + termMargin(termMonths) + riskMargin(score))
+}
+
+// termMargin надбавка за срок кредита, п.п.
+func termMargin(termMonths int) float64 {
+	switch {
+	case termMonths <= 12:
+		return 0
+	case termMonths <= 36:
+		return 1
+	case termMonths <= 60:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// riskMargin надбавка за риск по скоринговому баллу, п.п.
+func riskMargin(score sql.NullInt64) float64 {
+	if !score.Valid {
+		return 5
+	}
+	switch {
+	case score.Int64 >= 85:
+		return 0
+	case score.Int64 >= models.ScoreAutoApprove:
+		return 1
+	case score.Int64 >= 55:
+		return 3
+	default:
+		return 5
+	}
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"banksystem/internal/models"
+	"math"
+	"time"
+)
+
+// buildSchedule рассчитывает график платежей по остатку основного долга balance, годовой ставке rate
+// и числу платежей term. Первый платеж приходится на месяц после start.
+// Суммы округляются до копеек; погрешность округления закрывается последним платежом,
+// поэтому сумма долей основного долга всегда равна balance.
+func buildSchedule(scheduleType string, balance float64, rate float64, term int, start time.Time) []*models.CreditPayment {
+	monthlyRate := rate / 12 / 100
+	balance = roundKopecks(balance)
+
+	annuity := roundKopecks(annuityPayment(balance, rate, term))
+	principalPart := roundKopecks(balance / float64(term))
+
+	payments := make([]*models.CreditPayment, 0, term)
+	for i := 1; i <= term; i++ {
+		interest := roundKopecks(balance * monthlyRate)
+
+		var principal float64
+		switch {
+		case i == term:
+			principal = balance
+		case scheduleType == models.CreditScheduleDifferentiated:
+			principal = principalPart
+		default:
+			principal = roundKopecks(annuity - interest)
+		}
+		if principal > balance {
+			principal = balance
+		}
+		balance = roundKopecks(balance - principal)
+
+		payments = append(payments, &models.CreditPayment{
+			Amount:           roundKopecks(principal + interest),
+			Principal:        principal,
+			Interest:         interest,
+			RemainingBalance: balance,
+			Status:           models.CreditPaymentStatusPending,
+			DueDate:          start.AddDate(0, i, 0),
+		})
+	}
+
+	return payments
+}
+
+// creditScheduleState состояние кредита по сохраненному графику
+type creditScheduleState struct {
+	// Остаток основного долга: доли основного долга в неоплаченных строках графика
+	outstanding float64
+	// Ближайший платеж в статусе pending; nil, если таких платежей нет
+	next *models.CreditPayment
+}
+
+// scheduleStates группирует строки графика по кредитам. Строки должны быть отсортированы по сроку платежа,
+// тогда next — ближайший предстоящий платеж. Учитываются пересчеты графика после досрочного погашения,
+// которые не следуют исходной формуле аннуитета.
+func scheduleStates(payments []*models.CreditPayment) map[int64]*creditScheduleState {
+	states := make(map[int64]*creditScheduleState)
+	for _, payment := range payments {
+		state, ok := states[payment.CreditID]
+		if !ok {
+			state = &creditScheduleState{}
+			states[payment.CreditID] = state
+		}
+		if payment.Status == models.CreditPaymentStatusCompleted {
+			continue
+		}
+		state.outstanding += payment.Principal
+		if state.next == nil && payment.Status == models.CreditPaymentStatusPending {
+			state.next = payment
+		}
+	}
+	return states
+}
+
+// monthlyPayment сумма ближайшего платежа по графику
+func (s *creditScheduleState) monthlyPayment() float64 {
+	if s == nil || s.next == nil {
+		return 0
+	}
+	return s.next.Amount
+}
+
+// roundKopecks округляет сумму до копеек
+func roundKopecks(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"banksystem/internal/models"
+	"context"
+	"fmt"
+	"time"
+)
+
+// Период, за который оценивается история операций заявителя
+const scoringHistoryMonths = 3
+
+// scoreApplication оценивает заявку по правилам: возраст счетов (до 25 баллов), история операций
+// (до 20), кредитная нагрузка с учетом нового платежа (до 35) и история просрочек (до 20).
+// Текущая просрочка по любому кредиту означает отказ независимо от балла.
+func (s *CreditService) scoreApplication(ctx context.Context, application *models.CreditApplication) (*models.CreditScore, error) {
+	score := &models.CreditScore{}
+	now := time.Now()
+
+	accounts, err := s.accountRepo.GetByUserID(ctx, application.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get accounts: %v", err)
+	}
+	oldest := now
+	for _, account := range accounts {
+		if account.CreatedAt.Valid && account.CreatedAt.Time.Before(oldest) {
+			oldest = account.CreatedAt.Time
+		}
+	}
+	ageDays := int(now.Sub(oldest).Hours() / 24)
+	score.Add(fmt.Sprintf("возраст счета %d дн.", ageDays), scoreAccountAge(ageDays))
+
+	operations, err := s.transactionRepo.CountByUserID(ctx, application.UserID, now.AddDate(0, -scoringHistoryMonths, 0))
+	if err != nil {
+		return nil, fmt.Errorf("failed to count transactions: %v", err)
+	}
+	score.Add(fmt.Sprintf("операций за %d мес.: %d", scoringHistoryMonths, operations), scoreOperations(operations))
+
+	income, err := s.transactionRepo.GetIncomeByUserID(ctx, application.UserID, now.AddDate(0, -scoringHistoryMonths, 0))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get income: %v", err)
+	}
+	monthlyIncome := income / scoringHistoryMonths
+
+	credits, err := s.creditRepo.GetByUserID(int(application.UserID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credits: %v", err)
+	}
+	payments, err := s.paymentRepo.GetByUserID(ctx, application.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credit payments: %v", err)
+	}
+	// Текущая нагрузка — ближайшие платежи по сохраненным графикам действующих кредитов
+	states := scheduleStates(payments)
+	var obligations float64
+	for _, credit := range credits {
+		switch credit.Status {
+		case models.CreditStatusOverdue:
+			score.RejectReason = fmt.Sprintf("просрочка по кредиту №%d", credit.ID)
+			obligations += states[credit.ID].monthlyPayment()
+		case models.CreditStatusActive:
+			obligations += states[credit.ID].monthlyPayment()
+		}
+	}
+	schedule := buildSchedule(application.ScheduleType, application.Amount, application.InterestRate.Float64, application.TermMonths, now)
+	obligations += schedule[0].Amount
+
+	if monthlyIncome > 0 {
+		debtToIncome := obligations / monthlyIncome
+		score.Add(fmt.Sprintf("платежи %.0f%% дохода", debtToIncome*100), scoreDebtToIncome(debtToIncome))
+	} else {
+		score.Add("нет подтвержденного дохода", 0)
+	}
+
+	penalties, err := s.paymentRepo.CountPenaltiesByUserID(ctx, application.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count penalties: %v", err)
+	}
+	score.Add(fmt.Sprintf("просроченных платежей в истории: %d", penalties), scoreOverdueHistory(penalties))
+
+	return score, nil
+}
+
+func scoreAccountAge(days int) int {
+	switch {
+	case days >= 365:
+		return 25
+	case days >= 180:
+		return 20
+	case days >= 30:
+		return 10
+	default:
+		return 0
+	}
+}
+
+func scoreOperations(count int) int {
+	switch {
+	case count >= 30:
+		return 20
+	case count >= 10:
+		return 15
+	case count > 0:
+		return 5
+	default:
+		return 0
+	}
+}
+
+func scoreDebtToIncome(ratio float64) int {
+	switch {
+	case ratio <= 0.3:
+		return 35
+	case ratio <= 0.5:
+		return 20
+	case ratio <= 0.7:
+		return 5
+	default:
+		return 0
+	}
+}
+
+func scoreOverdueHistory(penalties int) int {
+	switch {
+	case penalties == 0:
+		return 20
+	case penalties <= 2:
+		return 10
+	default:
+		return 0
+	}
+}
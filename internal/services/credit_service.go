@@ -5,7 +5,7 @@ import (
 	"banksystem/internal/repositories"
 	"context"
 	"database/sql"
-	"errors"
+	"fmt"
 	"math"
 	"time"
 )
@@ -15,7 +15,11 @@ type CreditService struct {
 	accountRepo     *repositories.AccountRepository
 	paymentRepo     *repositories.CreditPaymentRepository
 	transactionRepo *repositories.TransactionRepository
-	db             *sql.DB
+	userRepo        *repositories.UserRepository
+	smtpService     *SMTPService
+	centralBank     *CentralBankService
+	policy          models.CreditPolicy
+	db              *sql.DB
 }
 
 func NewCreditService(
@@ -24,83 +28,82 @@ func NewCreditService(
 	accountRepo *repositories.AccountRepository,
 	paymentRepo *repositories.CreditPaymentRepository,
 	transactionRepo *repositories.TransactionRepository,
+	userRepo *repositories.UserRepository,
+	smtpService *SMTPService,
+	centralBank *CentralBankService,
+	policy models.CreditPolicy,
 ) *CreditService {
 	return &CreditService{
-		db:             db,
-		creditRepo:     creditRepo,
-		accountRepo:    accountRepo,
-		paymentRepo:    paymentRepo,
+		db:              db,
+		creditRepo:      creditRepo,
+		accountRepo:     accountRepo,
+		paymentRepo:     paymentRepo,
 		transactionRepo: transactionRepo,
+		userRepo:        userRepo,
+		smtpService:     smtpService,
+		centralBank:     centralBank,
+		policy:          policy,
 	}
 }
 
-func (s *CreditService) CreateCredit(ctx context.Context, userID int64, accountID int64, amount float64, term int, rate float64) (*models.Credit, error) {
-	// Проверяем существование счета
-	account, err := s.accountRepo.GetByID(ctx, accountID)
+// issueCredit выдает кредит по одобренной заявке в транзакции tx: сохраняет кредит с графиком
+// платежей и зачисляет сумму на счет. Ключевая ставка keyRate сохраняется в кредите для аудита.
+func (s *CreditService) issueCredit(ctx context.Context, tx *sql.Tx, application *models.CreditApplication, keyRate *models.KeyRate) (*models.Credit, error) {
+	account, err := s.accountRepo.GetByIDForUpdate(ctx, tx, application.AccountID)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to get account: %v", err)
 	}
-	if account == nil {
-		return nil, errors.New("счет не найден")
+	if account == nil || !account.IsActive || account.UserID != application.UserID {
+		return nil, models.ErrAccountNotFound
 	}
 
-	// Рассчитываем ежемесячный платеж
-	monthlyPayment := s.calculateMonthlyPayment(amount, rate, term)
-
 	credit := &models.Credit{
-		UserID:         userID,
-		AccountID:      accountID,
-		Amount:         amount,
-		InterestRate:   rate,
-		TermMonths:     term,
-		MonthlyPayment: monthlyPayment,
-		Status:         models.CreditStatusActive,
-		CreatedAt:      time.Now(),
+		UserID:       application.UserID,
+		AccountID:    account.ID,
+		Amount:       application.Amount,
+		InterestRate: application.InterestRate.Float64,
+		TermMonths:   application.TermMonths,
+		ScheduleType: application.ScheduleType,
+		Status:       models.CreditStatusActive,
+		KeyRate:      &keyRate.Rate,
+		KeyRateDate:  &keyRate.Date,
+		CreatedAt:    time.Now(),
 	}
 
-	// Начинаем транзакцию
-	tx, err := s.db.BeginTx(ctx, nil)
-	if err != nil {
-		return nil, err
-	}
-	defer tx.Rollback()
+	// Рассчитываем график; для дифференцированного графика ежемесячным считается первый, наибольший платеж
+	schedule := buildSchedule(credit.ScheduleType, credit.Amount, credit.InterestRate, credit.TermMonths, credit.CreatedAt)
+	credit.MonthlyPayment = schedule[0].Amount
 
 	// Создаем кредит
-	err = s.creditRepo.Create(credit)
-	if err != nil {
-		return nil, err
+	if err := s.creditRepo.Create(ctx, tx, credit); err != nil {
+		return nil, fmt.Errorf("failed to create credit: %v", err)
 	}
 
 	// Создаем график платежей
-	err = s.createPaymentSchedule(ctx, credit)
-	if err != nil {
-		return nil, err
+	for _, payment := range schedule {
+		payment.CreditID = credit.ID
+	}
+	if err := s.paymentRepo.CreateSchedule(ctx, tx, schedule); err != nil {
+		return nil, fmt.Errorf("failed to create payment schedule: %v", err)
 	}
 
 	// Зачисляем сумму кредита на счет
-	account.Balance += amount
-	err = s.accountRepo.Update(ctx, tx, account)
-	if err != nil {
-		return nil, err
+	account.Balance += credit.Amount
+	if err := s.accountRepo.Update(ctx, tx, account); err != nil {
+		return nil, fmt.Errorf("failed to update account: %v", err)
 	}
 
 	// Создаем транзакцию о зачислении кредита
 	transaction := &models.Transaction{
-		AccountID: accountID,
-		Type:      "credit",
-		Amount:    amount,
-		Status:    "completed",
-		CreatedAt: sql.NullTime{Time: time.Now(), Valid: true},
+		AccountID:   account.ID,
+		Type:        "credit",
+		Amount:      credit.Amount,
+		Status:      "completed",
+		Description: sql.NullString{String: fmt.Sprintf("Зачисление кредита №%d", credit.ID), Valid: true},
+		CreatedAt:   sql.NullTime{Time: time.Now(), Valid: true},
 	}
-
-	_, err = s.transactionRepo.Create(ctx, tx, transaction)
-	if err != nil {
-		return nil, err
-	}
-
-	err = tx.Commit()
-	if err != nil {
-		return nil, err
+	if _, err := s.transactionRepo.Create(ctx, tx, transaction); err != nil {
+		return nil, fmt.Errorf("failed to create transaction: %v", err)
 	}
 
 	return credit, nil
@@ -118,25 +121,11 @@ func (s *CreditService) GetPaymentSchedule(ctx context.Context, creditID int64)
 	return s.paymentRepo.GetByCreditID(ctx, creditID)
 }
 
-func (s *CreditService) calculateMonthlyPayment(amount float64, rate float64, term int) float64 {
+// annuityPayment рассчитывает аннуитетный платеж по сумме, годовой ставке и сроку в месяцах
+func annuityPayment(amount float64, rate float64, term int) float64 {
 	monthlyRate := rate / 12 / 100
+	if monthlyRate == 0 {
+		return amount / float64(term)
+	}
 	return amount * monthlyRate * math.Pow(1+monthlyRate, float64(term)) / (math.Pow(1+monthlyRate, float64(term)) - 1)
 }
-
-func (s *CreditService) createPaymentSchedule(ctx context.Context, credit *models.Credit) error {
-	for i := 1; i <= credit.TermMonths; i++ {
-		payment := &models.CreditPayment{
-			CreditID: credit.ID,
-			Amount:   credit.MonthlyPayment,
-			Status:   "pending",
-			DueDate:  credit.CreatedAt.AddDate(0, i, 0),
-		}
-
-		err := s.paymentRepo.Create(ctx, payment)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
-} 
\ No newline at end of file
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"banksystem/internal/models"
+	"banksystem/internal/repositories"
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// Глубина истории для поиска регулярных поступлений и трат
+	recurringLookbackMonths = 3
+	// Минимальное число месяцев, в которых должна встретиться операция, чтобы считаться регулярной
+	recurringMinMonths = 2
+)
+
+// recurringFlow регулярная операция, обнаруженная в истории счета
+type recurringFlow struct {
+	amount     float64 // > 0 поступление, < 0 списание
+	dayOfMonth int
+}
+
+type ForecastService struct {
+	accountRepo     *repositories.AccountRepository
+	paymentRepo     *repositories.CreditPaymentRepository
+	transactionRepo *repositories.TransactionRepository
+	savingsRate     float64
+}
+
+func NewForecastService(
+	accountRepo *repositories.AccountRepository,
+	paymentRepo *repositories.CreditPaymentRepository,
+	transactionRepo *repositories.TransactionRepository,
+	savingsRate float64,
+) *ForecastService {
+	return &ForecastService{
+		accountRepo:     accountRepo,
+		paymentRepo:     paymentRepo,
+		transactionRepo: transactionRepo,
+		savingsRate:     savingsRate,
+	}
+}
+
+// PredictBalance строит прогноз баланса счета по дням на days дней вперед
+func (s *ForecastService) PredictBalance(ctx context.Context, userID, accountID int64, days int, threshold float64, includeRecurring bool) (*models.BalanceForecast, error) {
+	if days <= 0 || days > models.MaxForecastDays {
+		return nil, models.ErrInvalidForecastPeriod
+	}
+
+	account, err := s.accountRepo.GetByID(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account: %v", err)
+	}
+	if account == nil {
+		return nil, models.ErrAccountNotFound
+	}
+	if account.UserID != userID {
+		return nil, models.ErrAccessDenied
+	}
+
+	payments, err := s.paymentRepo.GetPendingByAccountID(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scheduled payments: %v", err)
+	}
+
+	var flows []recurringFlow
+	if includeRecurring {
+		now := time.Now()
+		history, err := s.transactionRepo.GetHistoryByAccountID(ctx, accountID, now.AddDate(0, -recurringLookbackMonths, 0))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get transaction history: %v", err)
+		}
+		flows = detectRecurringFlows(accountID, history)
+	}
+
+	today := truncateToDay(time.Now())
+	outflows := make(map[string]float64)
+	for _, payment := range payments {
+		day := truncateToDay(payment.DueDate.In(today.Location()))
+		// Просроченные платежи будут списаны при ближайшем запуске шедулера
+		if !day.After(today) {
+			day = today.AddDate(0, 0, 1)
+		}
+		outflows[day.Format(time.DateOnly)] += payment.Amount
+	}
+
+	forecast := &models.BalanceForecast{
+		AccountID:    accountID,
+		Days:         days,
+		StartBalance: account.Balance,
+		Threshold:    threshold,
+		Points:       make([]models.ForecastPoint, 0, days),
+	}
+
+	balance := account.Balance
+	var accruedInterest float64
+	for i := 1; i <= days; i++ {
+		day := today.AddDate(0, 0, i)
+		point := models.ForecastPoint{Date: day}
+
+		point.Outflow += outflows[day.Format(time.DateOnly)]
+		for _, flow := range flows {
+			if day.Day() != flowDay(flow.dayOfMonth, day) {
+				continue
+			}
+			if flow.amount > 0 {
+				point.Inflow += flow.amount
+			} else {
+				point.Outflow -= flow.amount
+			}
+		}
+
+		// Проценты по сберегательному счету начисляются ежедневно и капитализируются в первый день месяца
+		if account.Type == "savings" && s.savingsRate > 0 && balance > 0 {
+			accruedInterest += balance * s.savingsRate / 100 / 365
+		}
+		if day.Day() == 1 && accruedInterest > 0 {
+			point.Inflow += math.Round(accruedInterest*100) / 100
+			accruedInterest = 0
+		}
+
+		balance += point.Inflow - point.Outflow
+		point.Balance = math.Round(balance*100) / 100
+		forecast.Points = append(forecast.Points, point)
+
+		if balance < 0 && forecast.FirstNegativeDate == nil {
+			date := day
+			forecast.FirstNegativeDate = &date
+		}
+		if balance < threshold && forecast.FirstBelowThreshold == nil {
+			date := day
+			forecast.FirstBelowThreshold = &date
+		}
+	}
+	forecast.EndBalance = math.Round(balance*100) / 100
+
+	return forecast, nil
+}
+
+// detectRecurringFlows ищет в истории операции с одинаковой суммой и направлением,
+// повторяющиеся в разные месяцы, и считает их ежемесячными
+func detectRecurringFlows(accountID int64, history []*models.Transaction) []recurringFlow {
+	type occurrences struct {
+		months map[string]bool
+		day    int
+	}
+
+	groups := make(map[float64]*occurrences)
+	for _, t := range history {
+		if !t.CreatedAt.Valid || t.Type == "credit" {
+			continue
+		}
+
+		amount := -t.Amount
+		if t.Type == "deposit" || (t.ToAccountID.Valid && t.ToAccountID.Int64 == accountID) {
+			amount = t.Amount
+		}
+		amount = math.Round(amount)
+
+		group, ok := groups[amount]
+		if !ok {
+			group = &occurrences{months: make(map[string]bool)}
+			groups[amount] = group
+		}
+		group.months[t.CreatedAt.Time.Format("2006-01")] = true
+		group.day = t.CreatedAt.Time.Day()
+	}
+
+	var flows []recurringFlow
+	for amount, group := range groups {
+		if len(group.months) >= recurringMinMonths {
+			flows = append(flows, recurringFlow{amount: amount, dayOfMonth: group.day})
+		}
+	}
+
+	return flows
+}
+
+// flowDay возвращает день месяца операции с учетом коротких месяцев
+func flowDay(dayOfMonth int, date time.Time) int {
+	lastDay := time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, date.Location()).Day()
+	if dayOfMonth > lastDay {
+		return lastDay
+	}
+	return dayOfMonth
+}
+
+func truncateToDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -1,20 +1,31 @@
 package services
 
 import (
+	"banksystem/internal/models"
 	"context"
+	"errors"
 	"log"
 	"time"
 )
 
+// cardIntegrityBatchSize количество карт, проверяемых за один запрос при сверке HMAC
+const cardIntegrityBatchSize = 500
+
 type Scheduler struct {
 	creditPaymentService *CreditPaymentService
-	stopChan            chan struct{}
+	budgetService        *BudgetService
+	cardService          *CardService
+	centralBankService   *CentralBankService
+	stopChan             chan struct{}
 }
 
-func NewScheduler(creditPaymentService *CreditPaymentService) *Scheduler {
+func NewScheduler(creditPaymentService *CreditPaymentService, budgetService *BudgetService, cardService *CardService, centralBankService *CentralBankService) *Scheduler {
 	return &Scheduler{
 		creditPaymentService: creditPaymentService,
-		stopChan:            make(chan struct{}),
+		budgetService:        budgetService,
+		cardService:          cardService,
+		centralBankService:   centralBankService,
+		stopChan:             make(chan struct{}),
 	}
 }
 
@@ -25,6 +36,13 @@ func (s *Scheduler) Start() {
 			select {
 			case <-ticker.C:
 				s.processPayments()
+				s.rolloverBudgets()
+				s.checkCardIntegrity()
+				s.expireCards()
+				s.renewCards()
+				s.releaseExpiredHolds()
+				s.expirePendingPayments()
+				s.refreshKeyRate()
 			case <-s.stopChan:
 				ticker.Stop()
 				return
@@ -48,6 +66,10 @@ func (s *Scheduler) processPayments() {
 	for _, payment := range payments {
 		if payment.DueDate.Before(time.Now()) {
 			err := s.creditPaymentService.ProcessPayment(ctx, payment.ID)
+			if errors.Is(err, models.ErrInsufficientFunds) {
+				log.Printf("Payment %d is overdue, collection will be retried", payment.ID)
+				continue
+			}
 			if err != nil {
 				log.Printf("Error processing payment %d: %v", payment.ID, err)
 				continue
@@ -55,4 +77,77 @@ func (s *Scheduler) processPayments() {
 			log.Printf("Successfully processed payment %d", payment.ID)
 		}
 	}
-} 
\ No newline at end of file
+}
+
+func (s *Scheduler) rolloverBudgets() {
+	count, err := s.budgetService.RolloverBudgets(context.Background())
+	if err != nil {
+		log.Printf("Error rolling over budgets: %v", err)
+		return
+	}
+	if count > 0 {
+		log.Printf("Rolled over %d budgets to the new month", count)
+	}
+}
+
+func (s *Scheduler) checkCardIntegrity() {
+	checked, tampered, err := s.cardService.CheckIntegrity(context.Background(), cardIntegrityBatchSize)
+	if err != nil {
+		log.Printf("Error checking card integrity: %v", err)
+		return
+	}
+	if tampered > 0 {
+		log.Printf("Card integrity check: %d of %d cards failed HMAC verification", tampered, checked)
+	}
+}
+
+func (s *Scheduler) expireCards() {
+	count, err := s.cardService.ExpireCards(context.Background())
+	if err != nil {
+		log.Printf("Error expiring cards: %v", err)
+		return
+	}
+	if count > 0 {
+		log.Printf("Expired %d cards", count)
+	}
+}
+
+func (s *Scheduler) renewCards() {
+	count, err := s.cardService.RenewExpiringCards(context.Background())
+	if err != nil {
+		log.Printf("Error renewing cards: %v", err)
+		return
+	}
+	if count > 0 {
+		log.Printf("Renewed %d expiring cards", count)
+	}
+}
+
+func (s *Scheduler) releaseExpiredHolds() {
+	count, err := s.cardService.ReleaseExpiredHolds(context.Background())
+	if err != nil {
+		log.Printf("Error releasing expired card holds: %v", err)
+		return
+	}
+	if count > 0 {
+		log.Printf("Released %d expired card holds", count)
+	}
+}
+
+func (s *Scheduler) expirePendingPayments() {
+	count, err := s.cardService.ExpirePendingPayments(context.Background())
+	if err != nil {
+		log.Printf("Error expiring unconfirmed card payments: %v", err)
+		return
+	}
+	if count > 0 {
+		log.Printf("Expired %d unconfirmed card payments", count)
+	}
+}
+
+// refreshKeyRate обновляет ключевую ставку ЦБ по истечении срока кеша, чтобы история ставок пополнялась ежедневно
+func (s *Scheduler) refreshKeyRate() {
+	if _, err := s.centralBankService.GetKeyRate(context.Background()); err != nil {
+		log.Printf("Error refreshing key rate: %v", err)
+	}
+}
@@ -2,8 +2,11 @@ package services
 
 import (
 	"banksystem/internal/config"
+	"banksystem/internal/models"
 	"fmt"
+	"html"
 	"strconv"
+	"strings"
 
 	"gopkg.in/mail.v2"
 )
@@ -12,13 +15,18 @@ type SMTPService struct {
 	config *config.Config
 }
 
-func NewSMTPService(host, port, username, password string) *SMTPService {
+// NewSMTPService создает отправителя писем; если адрес отправителя from не задан, используется username
+func NewSMTPService(host, port, username, password, from string) *SMTPService {
+	if from == "" {
+		from = username
+	}
 	return &SMTPService{
 		config: &config.Config{
 			SMTPHost:     host,
 			SMTPPort:     port,
 			SMTPUsername: username,
 			SMTPPassword: password,
+			SMTPFrom:     from,
 		},
 	}
 }
@@ -30,7 +38,7 @@ func (s *SMTPService) SendEmail(to, subject, body string) error {
 	}
 
 	m := mail.NewMessage()
-	m.SetHeader("From", s.config.SMTPUsername)
+	m.SetHeader("From", s.config.SMTPFrom)
 	m.SetHeader("To", to)
 	m.SetHeader("Subject", subject)
 	m.SetBody("text/html", body)
@@ -75,6 +83,17 @@ func (s *SMTPService) SendCreditApprovalNotification(email string, amount float6
 	return s.SendEmail(email, subject, body)
 }
 
+func (s *SMTPService) SendCreditRejectionNotification(email string, amount float64, term int, reason string) error {
+	subject := "Credit Application Rejected"
+	body := fmt.Sprintf(`
+		<h1>Credit Application Rejected</h1>
+		<p>Your credit application for %.2f with a term of %d months has been rejected.</p>
+		<p>Reason: %s</p>
+	`, amount, term, html.EscapeString(reason))
+
+	return s.SendEmail(email, subject, body)
+}
+
 func (s *SMTPService) SendPaymentReminderNotification(email string, amount float64, dueDate string) error {
 	subject := "Payment Reminder"
 	body := fmt.Sprintf(`
@@ -84,4 +103,91 @@ func (s *SMTPService) SendPaymentReminderNotification(email string, amount float
 	`, amount, dueDate)
 
 	return s.SendEmail(email, subject, body)
-} 
\ No newline at end of file
+}
+
+func (s *SMTPService) SendCreditPenaltyNotification(email string, creditID int64, amount, penalty float64, dueDate string) error {
+	subject := "Overdue Credit Payment"
+	body := fmt.Sprintf(`
+		<h1>Overdue Credit Payment</h1>
+		<p>The payment of %.2f on credit #%d due on %s could not be collected.</p>
+		<p>A penalty of %.2f has been charged. It will be collected together with the payment.</p>
+		<p>Please top up your account to avoid further overdue payments.</p>
+	`, amount, creditID, dueDate, penalty)
+
+	return s.SendEmail(email, subject, body)
+}
+
+func (s *SMTPService) SendCreditScheduleNotification(email string, creditID int64, schedule []*models.CreditPayment) error {
+	subject := "Credit Payment Schedule Updated"
+	if len(schedule) == 0 {
+		body := fmt.Sprintf(`
+		<h1>Credit Repaid</h1>
+		<p>Credit #%d has been fully repaid and closed.</p>
+	`, creditID)
+		return s.SendEmail(email, "Credit Repaid", body)
+	}
+
+	var rows strings.Builder
+	for _, payment := range schedule {
+		fmt.Fprintf(&rows, "<tr><td>%s</td><td>%.2f</td><td>%.2f</td><td>%.2f</td><td>%.2f</td></tr>",
+			payment.DueDate.Format("02.01.2006"), payment.Amount, payment.Principal, payment.Interest, payment.RemainingBalance)
+	}
+
+	body := fmt.Sprintf(`
+		<h1>Credit Payment Schedule Updated</h1>
+		<p>After early repayment the schedule of credit #%d has been recalculated:</p>
+		<table>
+			<tr><th>Due date</th><th>Amount</th><th>Principal</th><th>Interest</th><th>Remaining</th></tr>
+			%s
+		</table>
+	`, creditID, rows.String())
+
+	return s.SendEmail(email, subject, body)
+}
+
+func (s *SMTPService) SendBudgetAlertNotification(email, budgetName string, percent int, spent, limit float64) error {
+	subject := "Budget Alert"
+	body := fmt.Sprintf(`
+		<h1>Budget Alert</h1>
+		<p>You have used %d%% of your monthly budget "%s".</p>
+		<p>Spent: %.2f of %.2f</p>
+	`, percent, budgetName, spent, limit)
+
+	return s.SendEmail(email, subject, body)
+}
+
+func (s *SMTPService) SendCardRenewalNotification(email, oldLast4, newLast4, expiryDate string) error {
+	subject := "Card Renewal"
+	body := fmt.Sprintf(`
+		<h1>Card Renewal</h1>
+		<p>Your card ending in %s expires soon.</p>
+		<p>A new card ending in %s, valid until %s, has been issued to the same account.</p>
+		<p>The current card will keep working until its expiry date.</p>
+	`, oldLast4, newLast4, expiryDate)
+
+	return s.SendEmail(email, subject, body)
+}
+
+func (s *SMTPService) SendPaymentConfirmationCode(email, code string, amount float64, merchantName, last4 string, validMinutes int) error {
+	subject := "Payment Confirmation Code"
+	body := fmt.Sprintf(`
+		<h1>Payment Confirmation</h1>
+		<p>A payment of %.2f to %s was made with your card ending in %s.</p>
+		<p>Your confirmation code is <b>%s</b>. It is valid for %d minutes.</p>
+		<p>If you did not make this payment, do not share the code and block the card.</p>
+	`, amount, html.EscapeString(merchantName), last4, code, validMinutes)
+
+	return s.SendEmail(email, subject, body)
+}
+
+func (s *SMTPService) SendMandateConfirmationCode(email, code string, amountCap float64, frequency, merchantName, last4 string, validMinutes int) error {
+	subject := "Recurring Payment Confirmation Code"
+	body := fmt.Sprintf(`
+		<h1>Recurring Payment Confirmation</h1>
+		<p>%s requested permission to charge up to %.2f (%s) to your card ending in %s.</p>
+		<p>Your confirmation code is <b>%s</b>. It is valid for %d minutes.</p>
+		<p>If you did not subscribe, do not share the code and block the card.</p>
+	`, html.EscapeString(merchantName), amountCap, frequency, last4, code, validMinutes)
+
+	return s.SendEmail(email, subject, body)
+}
@@ -0,0 +1,314 @@
+package services
+
+import (
+	"banksystem/internal/models"
+	"banksystem/internal/repositories"
+	"banksystem/internal/storage"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type TransactionService struct {
+	transactionRepo   *repositories.TransactionRepository
+	attachmentRepo    *repositories.AttachmentRepository
+	accountRepo       *repositories.AccountRepository
+	blobStore         storage.BlobStore
+	maxAttachmentSize int64
+	db                *sql.DB
+}
+
+func NewTransactionService(
+	db *sql.DB,
+	transactionRepo *repositories.TransactionRepository,
+	attachmentRepo *repositories.AttachmentRepository,
+	accountRepo *repositories.AccountRepository,
+	blobStore storage.BlobStore,
+	maxAttachmentSize int64,
+) *TransactionService {
+	return &TransactionService{
+		db:                db,
+		transactionRepo:   transactionRepo,
+		attachmentRepo:    attachmentRepo,
+		accountRepo:       accountRepo,
+		blobStore:         blobStore,
+		maxAttachmentSize: maxAttachmentSize,
+	}
+}
+
+// GetHistory возвращает историю операций по счету пользователя, при необходимости отфильтрованную по тегу
+func (s *TransactionService) GetHistory(ctx context.Context, userID, accountID int64, tag string) ([]*models.Transaction, error) {
+	if err := s.checkAccountOwner(ctx, userID, accountID); err != nil {
+		return nil, err
+	}
+
+	var transactions []*models.Transaction
+	var err error
+	if tag != "" {
+		transactions, err = s.transactionRepo.GetByAccountIDAndTag(ctx, accountID, models.NormalizeTag(tag))
+	} else {
+		transactions, err = s.transactionRepo.GetByAccountID(ctx, accountID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transactions: %v", err)
+	}
+
+	if err := s.loadTags(ctx, transactions); err != nil {
+		return nil, err
+	}
+
+	return transactions, nil
+}
+
+// Search ищет операции по счетам пользователя: полнотекстово по описанию, заметке и контрагенту,
+// а также по сумме или номеру операции, если запрос является числом
+func (s *TransactionService) Search(ctx context.Context, userID int64, text string, page, pageSize int) (*models.TransactionSearchResponse, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, models.ErrInvalidSearchQuery
+	}
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > models.MaxSearchPageSize {
+		pageSize = models.DefaultSearchPageSize
+	}
+
+	var amount sql.NullFloat64
+	var reference sql.NullInt64
+	number := strings.ReplaceAll(strings.TrimPrefix(text, "#"), ",", ".")
+	if value, err := strconv.ParseFloat(number, 64); err == nil {
+		amount = sql.NullFloat64{Float64: value, Valid: true}
+	}
+	if value, err := strconv.ParseInt(number, 10, 64); err == nil {
+		reference = sql.NullInt64{Int64: value, Valid: true}
+	}
+
+	transactions, total, err := s.transactionRepo.Search(ctx, userID, text, amount, reference, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search transactions: %v", err)
+	}
+
+	if err := s.loadTags(ctx, transactions); err != nil {
+		return nil, err
+	}
+
+	response := &models.TransactionSearchResponse{
+		Items:    make([]models.TransactionResponse, 0, len(transactions)),
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}
+	for _, t := range transactions {
+		response.Items = append(response.Items, t.ToResponse())
+	}
+
+	return response, nil
+}
+
+func (s *TransactionService) SetNote(ctx context.Context, userID int64, req *models.TransactionNoteRequest) error {
+	if _, err := s.getOwnTransaction(ctx, userID, req.TransactionID); err != nil {
+		return err
+	}
+
+	if err := s.transactionRepo.UpdateNote(ctx, req.TransactionID, strings.TrimSpace(req.Note)); err != nil {
+		return fmt.Errorf("failed to update note: %v", err)
+	}
+
+	return nil
+}
+
+func (s *TransactionService) SetTags(ctx context.Context, userID int64, req *models.TransactionTagsRequest) error {
+	if _, err := s.getOwnTransaction(ctx, userID, req.TransactionID); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := s.transactionRepo.SetTags(ctx, tx, req.TransactionID, req.Tags); err != nil {
+		return fmt.Errorf("failed to update tags: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+// AddAttachment сохраняет файл в хранилище и привязывает его к операции.
+// Тип файла определяется по содержимому, а не по заголовкам запроса.
+func (s *TransactionService) AddAttachment(ctx context.Context, userID, transactionID int64, fileName string, r io.Reader) (*models.TransactionAttachment, error) {
+	if _, err := s.getOwnTransaction(ctx, userID, transactionID); err != nil {
+		return nil, err
+	}
+
+	// Читаем на байт больше лимита, чтобы обнаружить превышение размера
+	data, err := io.ReadAll(io.LimitReader(r, s.maxAttachmentSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachment: %v", err)
+	}
+	if int64(len(data)) > s.maxAttachmentSize {
+		return nil, models.ErrAttachmentTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	if !models.AllowedAttachmentTypes[contentType] {
+		return nil, models.ErrInvalidAttachmentType
+	}
+
+	key, err := newStorageKey(transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.blobStore.Put(ctx, key, bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("failed to store attachment: %v", err)
+	}
+
+	attachment := &models.TransactionAttachment{
+		TransactionID: transactionID,
+		FileName:      filepath.Base(fileName),
+		ContentType:   contentType,
+		Size:          int64(len(data)),
+		StorageKey:    key,
+	}
+
+	if err := s.attachmentRepo.Create(ctx, attachment); err != nil {
+		s.blobStore.Delete(ctx, key)
+		return nil, fmt.Errorf("failed to save attachment: %v", err)
+	}
+
+	return attachment, nil
+}
+
+func (s *TransactionService) GetAttachments(ctx context.Context, userID, transactionID int64) ([]*models.TransactionAttachment, error) {
+	if _, err := s.getOwnTransaction(ctx, userID, transactionID); err != nil {
+		return nil, err
+	}
+
+	attachments, err := s.attachmentRepo.GetByTransactionID(ctx, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachments: %v", err)
+	}
+
+	return attachments, nil
+}
+
+// OpenAttachment возвращает метаданные и содержимое вложения владельцу счета
+func (s *TransactionService) OpenAttachment(ctx context.Context, userID, attachmentID int64) (*models.TransactionAttachment, io.ReadCloser, error) {
+	attachment, err := s.attachmentRepo.GetByID(ctx, attachmentID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get attachment: %v", err)
+	}
+	if attachment == nil {
+		return nil, nil, models.ErrAttachmentNotFound
+	}
+
+	if _, err := s.getOwnTransaction(ctx, userID, attachment.TransactionID); err != nil {
+		return nil, nil, err
+	}
+
+	content, err := s.blobStore.Get(ctx, attachment.StorageKey)
+	if err == storage.ErrBlobNotFound {
+		return nil, nil, models.ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read attachment: %v", err)
+	}
+
+	return attachment, content, nil
+}
+
+func (s *TransactionService) DeleteAttachment(ctx context.Context, userID, attachmentID int64) error {
+	attachment, err := s.attachmentRepo.GetByID(ctx, attachmentID)
+	if err != nil {
+		return fmt.Errorf("failed to get attachment: %v", err)
+	}
+	if attachment == nil {
+		return models.ErrAttachmentNotFound
+	}
+
+	if _, err := s.getOwnTransaction(ctx, userID, attachment.TransactionID); err != nil {
+		return err
+	}
+
+	if err := s.attachmentRepo.Delete(ctx, attachmentID); err != nil {
+		return fmt.Errorf("failed to delete attachment: %v", err)
+	}
+
+	return s.blobStore.Delete(ctx, attachment.StorageKey)
+}
+
+// getOwnTransaction возвращает операцию, если она проведена по счету пользователя
+func (s *TransactionService) getOwnTransaction(ctx context.Context, userID, transactionID int64) (*models.Transaction, error) {
+	transaction, err := s.transactionRepo.GetByID(ctx, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction: %v", err)
+	}
+	if transaction == nil {
+		return nil, models.ErrTransactionNotFound
+	}
+
+	if err := s.checkAccountOwner(ctx, userID, transaction.AccountID); err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+func (s *TransactionService) checkAccountOwner(ctx context.Context, userID, accountID int64) error {
+	account, err := s.accountRepo.GetByID(ctx, accountID)
+	if err != nil {
+		return fmt.Errorf("failed to get account: %v", err)
+	}
+	if account == nil {
+		return models.ErrAccountNotFound
+	}
+	if account.UserID != userID {
+		return models.ErrAccessDenied
+	}
+	return nil
+}
+
+func (s *TransactionService) loadTags(ctx context.Context, transactions []*models.Transaction) error {
+	if len(transactions) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(transactions))
+	for i, t := range transactions {
+		ids[i] = t.ID
+	}
+
+	tags, err := s.transactionRepo.GetTags(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("failed to get tags: %v", err)
+	}
+
+	for _, t := range transactions {
+		t.Tags = tags[t.ID]
+	}
+	return nil
+}
+
+// newStorageKey генерирует случайный ключ файла, не зависящий от имени, переданного пользователем
+func newStorageKey(transactionID int64) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("transactions/%d/%s", transactionID, hex.EncodeToString(buf)), nil
+}
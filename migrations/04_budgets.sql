@@ -0,0 +1,20 @@
+-- Категория расхода у операций
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS category VARCHAR(50);
+
+-- Таблица бюджетов
+CREATE TABLE IF NOT EXISTS budgets (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    account_id INTEGER REFERENCES accounts(id) ON DELETE CASCADE, -- NULL: все счета пользователя
+    category VARCHAR(50), -- NULL: все категории
+    monthly_limit DECIMAL(15,2) NOT NULL,
+    carry_over BOOLEAN NOT NULL DEFAULT FALSE,
+    carried_amount DECIMAL(15,2) NOT NULL DEFAULT 0.00, -- Остаток, перенесенный с прошлого месяца
+    spent DECIMAL(15,2) NOT NULL DEFAULT 0.00,
+    last_alert_percent INTEGER NOT NULL DEFAULT 0, -- Последний порог, по которому отправлено уведомление
+    period_start DATE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_budgets_user_id ON budgets(user_id);
@@ -0,0 +1,15 @@
+-- Контрагент операции (получатель перевода, продавец)
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS counterparty VARCHAR(255);
+
+-- Поисковый вектор по описанию, заметке и контрагенту с русским и английским словарями
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS search_vector TSVECTOR
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('russian', COALESCE(counterparty, '')), 'A') ||
+        setweight(to_tsvector('english', COALESCE(counterparty, '')), 'A') ||
+        setweight(to_tsvector('russian', COALESCE(description, '')), 'B') ||
+        setweight(to_tsvector('english', COALESCE(description, '')), 'B') ||
+        setweight(to_tsvector('russian', COALESCE(note, '')), 'C') ||
+        setweight(to_tsvector('english', COALESCE(note, '')), 'C')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_search_vector ON transactions USING GIN (search_vector);
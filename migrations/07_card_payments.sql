@@ -0,0 +1,17 @@
+-- Приводим таблицу карт к модели: номер карты хранится PGP-сообщением,
+-- срок действия открыто, чтобы проверять его без расшифровки
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS encrypted_data TEXT;
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS hashed_cvv VARCHAR(255);
+ALTER TABLE cards ALTER COLUMN card_number DROP NOT NULL;
+ALTER TABLE cards ALTER COLUMN expiry_date TYPE DATE USING (created_at + INTERVAL '4 years')::DATE;
+ALTER TABLE cards ALTER COLUMN cvv_hash DROP NOT NULL;
+ALTER TABLE cards ALTER COLUMN hmac TYPE TEXT USING encode(hmac, 'hex');
+
+-- Статус карты
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'ACTIVE';
+
+-- Данные карточных платежей
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS card_id INTEGER REFERENCES cards(id);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS merchant_mcc VARCHAR(4);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_card_id ON transactions(card_id, created_at);
@@ -0,0 +1,18 @@
+-- Последние цифры номера и платежная система храним открыто для маскированного отображения карт
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS pan_last4 VARCHAR(4);
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS brand VARCHAR(20);
+
+-- Журнал аудита действий с чувствительными данными
+CREATE TABLE IF NOT EXISTS audit_log (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id),
+    action VARCHAR(50) NOT NULL,
+    entity_type VARCHAR(30) NOT NULL,
+    entity_id INTEGER,
+    details TEXT,
+    ip_address VARCHAR(45),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(entity_type, entity_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_user_id ON audit_log(user_id, created_at);
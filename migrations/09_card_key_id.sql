@@ -0,0 +1,4 @@
+-- Идентификатор PGP-ключа, которым зашифрованы данные карты (для ротации ключей)
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS key_id VARCHAR(16);
+
+CREATE INDEX IF NOT EXISTS idx_cards_key_id ON cards(key_id);
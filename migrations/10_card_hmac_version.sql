@@ -0,0 +1,3 @@
+-- Версия ключа HMAC, которым подписаны данные карты.
+-- Существующие карты подписаны ключом версии 1 (см. CARD_HMAC_KEYS).
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS hmac_version INTEGER NOT NULL DEFAULT 1;
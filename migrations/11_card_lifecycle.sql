@@ -0,0 +1,9 @@
+-- Роли пользователей: администраторы управляют блокировками карт
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'user';
+
+-- Жизненный цикл карты: ACTIVE, BLOCKED (временно), PERM_BLOCKED, EXPIRED
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS block_reason VARCHAR(255);
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS blocked_by VARCHAR(20);
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS replaced_by INTEGER REFERENCES cards(id);
+
+CREATE INDEX IF NOT EXISTS idx_cards_status_expiry ON cards(status, expiry_date);
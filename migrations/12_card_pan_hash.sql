@@ -0,0 +1,4 @@
+-- Ключевой хеш номера карты: поиск по реквизитам без расшифровки и гарантия уникальности номеров
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS pan_hash VARCHAR(64);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cards_pan_hash ON cards(pan_hash);
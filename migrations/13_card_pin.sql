@@ -0,0 +1,3 @@
+-- PIN-код карты: хранится только bcrypt-хеш, счетчик неверных попыток обновляется атомарно
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS pin_hash TEXT;
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS pin_attempts INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,4 @@
+-- Виртуальные карты: одноразовые и привязанные к продавцу, с лимитом трат и коротким сроком действия
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS card_type VARCHAR(20) NOT NULL DEFAULT 'STANDARD';
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS spend_cap NUMERIC(15,2);
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS locked_merchant VARCHAR(100);
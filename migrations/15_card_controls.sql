@@ -0,0 +1,11 @@
+-- Пользовательские ограничения карты: каналы оплаты, собственные лимиты и запрещенные MCC
+CREATE TABLE IF NOT EXISTS card_controls (
+    card_id INTEGER PRIMARY KEY REFERENCES cards(id) ON DELETE CASCADE,
+    ecom_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    contactless_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    foreign_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    daily_limit NUMERIC(15,2),
+    monthly_limit NUMERIC(15,2),
+    blocked_mccs TEXT[] NOT NULL DEFAULT '{}',
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,34 @@
+-- Терминалы продавцов, подключенные по ISO 8583
+CREATE TABLE IF NOT EXISTS terminals (
+    id VARCHAR(8) PRIMARY KEY,
+    merchant_account_id INTEGER NOT NULL REFERENCES accounts(id),
+    merchant_name VARCHAR(100) NOT NULL,
+    merchant_mcc VARCHAR(4),
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Авторизации по картам с терминалов: блокировки средств (0100), списания (0200) и их отмены (0400)
+CREATE TABLE IF NOT EXISTS card_holds (
+    id SERIAL PRIMARY KEY,
+    card_id INTEGER NOT NULL REFERENCES cards(id),
+    account_id INTEGER NOT NULL REFERENCES accounts(id),
+    merchant_account_id INTEGER NOT NULL REFERENCES accounts(id),
+    terminal_id VARCHAR(8) NOT NULL REFERENCES terminals(id),
+    rrn VARCHAR(12) NOT NULL,
+    stan VARCHAR(6),
+    amount NUMERIC(15,2) NOT NULL,
+    auth_code VARCHAR(6) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    transaction_id INTEGER REFERENCES transactions(id),
+    merchant_name VARCHAR(100) NOT NULL,
+    merchant_mcc VARCHAR(4),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (terminal_id, rrn)
+);
+
+CREATE INDEX IF NOT EXISTS idx_card_holds_account_status ON card_holds(account_id, status);
+CREATE INDEX IF NOT EXISTS idx_card_holds_card_status ON card_holds(card_id, status);
+CREATE INDEX IF NOT EXISTS idx_card_holds_status_expires ON card_holds(status, expires_at);
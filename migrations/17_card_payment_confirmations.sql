@@ -0,0 +1,23 @@
+-- Онлайн-платежи картой, ожидающие подтверждения одноразовым кодом
+CREATE TABLE IF NOT EXISTS card_payment_confirmations (
+    id SERIAL PRIMARY KEY,
+    card_id INTEGER NOT NULL REFERENCES cards(id),
+    account_id INTEGER NOT NULL REFERENCES accounts(id),
+    merchant_account_id INTEGER REFERENCES accounts(id),
+    initiator_id INTEGER NOT NULL REFERENCES users(id),
+    amount NUMERIC(15,2) NOT NULL,
+    merchant_name VARCHAR(100) NOT NULL,
+    merchant_mcc VARCHAR(4),
+    category VARCHAR(50),
+    channel VARCHAR(20) NOT NULL,
+    country VARCHAR(2) NOT NULL,
+    code_hash TEXT NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    status VARCHAR(30) NOT NULL,
+    transaction_id INTEGER REFERENCES transactions(id),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_card_payment_confirmations_status_expires ON card_payment_confirmations(status, expires_at);
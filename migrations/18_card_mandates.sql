@@ -0,0 +1,19 @@
+-- Разрешения продавцов на регулярные списания с карты (подписки)
+CREATE TABLE IF NOT EXISTS card_mandates (
+    id SERIAL PRIMARY KEY,
+    card_id INTEGER NOT NULL REFERENCES cards(id),
+    merchant_account_id INTEGER NOT NULL REFERENCES accounts(id),
+    merchant_name VARCHAR(100) NOT NULL,
+    merchant_mcc VARCHAR(4),
+    merchant_country VARCHAR(2) NOT NULL DEFAULT 'RU',
+    amount_cap NUMERIC(15,2) NOT NULL,
+    frequency VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'ACTIVE',
+    last_charged_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_card_mandates_card_id ON card_mandates(card_id);
+CREATE INDEX IF NOT EXISTS idx_card_mandates_merchant_account_id ON card_mandates(merchant_account_id);
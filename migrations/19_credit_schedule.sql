@@ -0,0 +1,8 @@
+-- Тип графика погашения кредита и разбивка платежей на основной долг и проценты
+ALTER TABLE credits ADD COLUMN IF NOT EXISTS schedule_type VARCHAR(20) NOT NULL DEFAULT 'annuity';
+
+ALTER TABLE credit_payments ADD COLUMN IF NOT EXISTS principal NUMERIC(15,2) NOT NULL DEFAULT 0;
+ALTER TABLE credit_payments ADD COLUMN IF NOT EXISTS interest NUMERIC(15,2) NOT NULL DEFAULT 0;
+ALTER TABLE credit_payments ADD COLUMN IF NOT EXISTS remaining_balance NUMERIC(15,2) NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_credit_payments_credit_id ON credit_payments(credit_id);
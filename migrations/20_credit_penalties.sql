@@ -0,0 +1,13 @@
+-- Штрафы за просроченные платежи по кредиту: не больше одного на каждый пропущенный платеж
+CREATE TABLE IF NOT EXISTS credit_penalties (
+    id SERIAL PRIMARY KEY,
+    credit_id INTEGER NOT NULL REFERENCES credits(id),
+    payment_id INTEGER NOT NULL UNIQUE REFERENCES credit_payments(id),
+    amount NUMERIC(15,2) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    transaction_id INTEGER REFERENCES transactions(id),
+    paid_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_credit_penalties_credit_id ON credit_penalties(credit_id);
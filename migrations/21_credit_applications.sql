@@ -0,0 +1,22 @@
+-- Заявки на кредит: автоматический скоринг, ручное рассмотрение и выдача
+CREATE TABLE IF NOT EXISTS credit_applications (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    account_id INTEGER NOT NULL REFERENCES accounts(id),
+    amount NUMERIC(15,2) NOT NULL,
+    term_months INTEGER NOT NULL,
+    schedule_type VARCHAR(20) NOT NULL DEFAULT 'annuity',
+    interest_rate NUMERIC(5,2),
+    status VARCHAR(20) NOT NULL DEFAULT 'submitted',
+    score INTEGER,
+    score_details TEXT,
+    decision_reason TEXT,
+    reviewed_by INTEGER REFERENCES users(id),
+    credit_id INTEGER REFERENCES credits(id),
+    decided_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_credit_applications_user_id ON credit_applications(user_id);
+CREATE INDEX IF NOT EXISTS idx_credit_applications_status ON credit_applications(status);
@@ -0,0 +1,5 @@
+-- Ключевая ставка ЦБ, от которой рассчитана ставка по кредиту и по заявке
+ALTER TABLE credits ADD COLUMN IF NOT EXISTS key_rate NUMERIC(5,2);
+ALTER TABLE credits ADD COLUMN IF NOT EXISTS key_rate_date DATE;
+
+ALTER TABLE credit_applications ADD COLUMN IF NOT EXISTS key_rate NUMERIC(5,2);
@@ -0,0 +1,6 @@
+-- История ключевой ставки ЦБ: ставка, действующая с даты date
+CREATE TABLE IF NOT EXISTS key_rates (
+    date DATE PRIMARY KEY,
+    rate NUMERIC(5,2) NOT NULL,
+    fetched_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
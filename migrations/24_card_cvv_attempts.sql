@@ -0,0 +1,2 @@
+-- Счетчик неверных попыток ввода CVV: после MaxCVVAttempts подряд карта блокируется системой
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS cvv_attempts INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+-- Карта, привязанная к продавцу, принимается только при зачислении на счет продавца, указанный при выпуске.
+-- Название продавца (locked_merchant) остается подписью; карты, выпущенные без счета продавца, больше не принимаются.
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS locked_merchant_account_id INTEGER REFERENCES accounts(id);
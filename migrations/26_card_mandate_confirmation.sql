@@ -0,0 +1,5 @@
+-- Разрешение с лимитом выше порога подтверждения действует только после ввода кода владельцем карты.
+-- В базе хранится только bcrypt-хеш кода.
+ALTER TABLE card_mandates ADD COLUMN IF NOT EXISTS code_hash VARCHAR(100);
+ALTER TABLE card_mandates ADD COLUMN IF NOT EXISTS otp_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE card_mandates ADD COLUMN IF NOT EXISTS otp_expires_at TIMESTAMP WITH TIME ZONE;
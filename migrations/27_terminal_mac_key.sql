@@ -0,0 +1,2 @@
+-- Общий с терминалом ключ MAC (hex, не короче 16 байт). Сообщения терминала без ключа не принимаются.
+ALTER TABLE terminals ADD COLUMN IF NOT EXISTS mac_key VARCHAR(128);